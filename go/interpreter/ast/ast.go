@@ -3,6 +3,7 @@ package ast
 import (
 	"bytes"
 	"interpreter/token"
+	"strconv"
 	"strings"
 )
 
@@ -42,9 +43,24 @@ func (p *Program) String() string {
 	return out.String()
 }
 
+// Identifier is a name. The resolve pass sets Resolved, Depth and Index:
+// Depth counts the function scopes between the identifier and the scope that
+// binds it, Index is the slot of the binding in that scope. The evaluator
+// uses them to find a binding without searching the scopes. Type is the
+// optional annotation of a let name or parameter.
 type Identifier struct {
-	Value string
+	Value    string
+	Token    token.Token
+	Type     *TypeAnnotation
+	Resolved bool
+	Depth    int
+	Index    int
+}
+
+// TypeAnnotation is an optional static type like the int in let x: int = 5.
+type TypeAnnotation struct {
 	Token token.Token
+	Name  string
 }
 
 type IntegerLiteral struct {
@@ -57,15 +73,50 @@ type FloatLiteral struct {
 	Value float64
 }
 
+// StringLiteral holds the unescaped content of a double quoted string.
+type StringLiteral struct {
+	Token token.Token
+	Value string
+}
+
+// InterpolatedString is "text ${expression} text". Parts holds the string
+// literals of the text and the embedded expressions in source order.
+type InterpolatedString struct {
+	Token token.Token
+	Parts []Expression
+}
+
 type Boolean struct {
 	Token token.Token
 	Value bool
 }
 
+// LetStatement binds Name to Value. Exported lets of a module are visible
+// to the files that import it. A destructuring let has a Pattern instead of
+// a Name, an array or hash pattern the value must have the shape of. A
+// const statement is a let whose names cannot be bound again in its scope.
 type LetStatement struct {
-	Value Expression
-	Name  *Identifier
-	Token token.Token
+	Value    Expression
+	Name     *Identifier
+	Pattern  Expression
+	Token    token.Token
+	Exported bool
+}
+
+// Const reports whether the statement is a const.
+func (ls *LetStatement) Const() bool {
+	return ls.Token.Type == token.CONST
+}
+
+// Names returns the identifiers the let binds.
+func (ls *LetStatement) Names() []*Identifier {
+	if ls.Pattern != nil {
+		return PatternNames(ls.Pattern)
+	}
+	if ls.Name == nil {
+		return nil
+	}
+	return []*Identifier{ls.Name}
 }
 
 type ReturnStatement struct {
@@ -103,10 +154,242 @@ type BlockStatement struct {
 	Statements []Statement
 }
 
+// FunctionLiteral is fn(params) { body }. Name is set by the parser if the
+// literal is the value of a let. Defaults is parallel to Parameters and
+// holds the default values, nil for parameters without one. It is empty if
+// no parameter has a default. Rest is the ...rest parameter, if any.
+// Generator is set by the parser if the body contains a yield.
 type FunctionLiteral struct {
 	Token      token.Token
+	Name       string
 	Parameters []*Identifier
+	Defaults   []Expression
+	Rest       *Identifier
+	ReturnType *TypeAnnotation
 	Body       *BlockStatement
+	Generator  bool
+}
+
+// Default returns the default value of the i-th parameter or nil.
+func (fl *FunctionLiteral) Default(i int) Expression {
+	if i < len(fl.Defaults) {
+		return fl.Defaults[i]
+	}
+	return nil
+}
+
+// MacroLiteral is macro(params) { body }. The macro pass removes it from the
+// program before evaluation.
+type MacroLiteral struct {
+	Token      token.Token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+type ArrayLiteral struct {
+	Token    token.Token
+	Elements []Expression
+}
+
+// HashLiteral is {key: value, ...}. Keys and Values are parallel, so the
+// pairs keep the order of the source.
+type HashLiteral struct {
+	Token  token.Token
+	Keys   []Expression
+	Values []Expression
+}
+
+// IndexExpression is Left[Index].
+type IndexExpression struct {
+	Token token.Token
+	Left  Expression
+	Index Expression
+}
+
+// ImportStatement is import "path/to/lib". It binds the module to the last
+// element of the path, here lib.
+type ImportStatement struct {
+	Token token.Token
+	Path  string
+	Name  *Identifier
+}
+
+// StructStatement is struct Name { field, ... }. It binds Name to the
+// constructor of a record type with these fields.
+type StructStatement struct {
+	Token    token.Token
+	Name     *Identifier
+	Fields   []*Identifier
+	Exported bool
+}
+
+// AssignExpression is Target = Value. Target is a SelectorExpression for
+// the field of a record or an IndexExpression for an element of an array or
+// hash. Names are bound by let.
+type AssignExpression struct {
+	Token  token.Token
+	Target Expression
+	Value  Expression
+}
+
+// MethodDeclaration is fn (receiver Struct) name(params) { body }. It adds
+// the method to the struct, Function holds the parameters without the
+// receiver.
+type MethodDeclaration struct {
+	Token    token.Token
+	Receiver *Identifier
+	Struct   *Identifier
+	Name     *Identifier
+	Function *FunctionLiteral
+}
+
+// SelectorExpression is Left.Name, the access to a member of a module or to
+// a field of a record.
+type SelectorExpression struct {
+	Token token.Token
+	Left  Expression
+	Name  *Identifier
+}
+
+// ThrowExpression is throw value. It unwinds the evaluation up to the next
+// enclosing try.
+type ThrowExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// YieldExpression is yield value. It hands value to the consumer of the
+// generator and evaluates to null once the generator is resumed.
+type YieldExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// SpawnExpression is spawn call. The function and the arguments of the call
+// are evaluated right away and the call runs in a task of its own. Value may
+// also be a function, which is then called without arguments.
+type SpawnExpression struct {
+	Token token.Token
+	Value Expression
+}
+
+// SelectExpression is select { case, ... }. It waits until the channel
+// operation of one of its cases can proceed and evaluates that case.
+type SelectExpression struct {
+	Token token.Token
+	Cases []*SelectCase
+}
+
+// SelectCase is name = recv(channel) => body, send(channel, value) => body
+// or the default case _ => body, which is taken if no operation can proceed
+// right away. Name is optional and Op is nil for the default case.
+type SelectCase struct {
+	Token token.Token
+	Name  *Identifier
+	Op    *CallExpression
+	Body  Expression
+}
+
+// TryExpression is try { body } catch (param) { handler }. The parameter is
+// optional.
+type TryExpression struct {
+	Token   token.Token
+	Body    *BlockStatement
+	Param   *Identifier
+	Handler *BlockStatement
+}
+
+// PipeExpression is Left |> Right. It calls Right with Left as argument, or,
+// if Right is a call f(a), f with Left as first argument: f(Left, a).
+type PipeExpression struct {
+	Token token.Token
+	Left  Expression
+	Right Expression
+}
+
+// MatchExpression is match (Value) { arm, ... }. The first arm whose
+// pattern matches Value and whose guard holds is evaluated.
+type MatchExpression struct {
+	Token token.Token
+	Value Expression
+	Arms  []*MatchArm
+}
+
+// MatchArm is pattern if guard => body, the guard is optional. A pattern is
+// a literal, a name that binds the value, the wildcard _ or an array or hash
+// literal of patterns. Hash patterns have literal keys and match hashes with
+// at least these keys. A call of a struct constructor with patterns as
+// arguments, e.g. Point(0, y: y), matches records of that struct.
+type MatchArm struct {
+	Token   token.Token
+	Pattern Expression
+	Guard   Expression
+	Body    Expression
+}
+
+// RangeExpression is Start..End, or Start..=End if Inclusive. It evaluates
+// to a range of integers, not to an array.
+type RangeExpression struct {
+	Token     token.Token
+	Start     Expression
+	End       Expression
+	Inclusive bool
+}
+
+// ForExpression is for (Pattern in Iterable) { Body }. Body runs once for
+// every element, with the names of the pattern bound to it.
+type ForExpression struct {
+	Token    token.Token
+	Pattern  Expression
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+// RestPattern is ...Name as the last element of an array pattern. It binds
+// the elements that are left over as an array.
+type RestPattern struct {
+	Token token.Token
+	Name  *Identifier
+}
+
+// NamedArgument is name: value in the arguments of a call. Named arguments
+// follow the positional ones.
+type NamedArgument struct {
+	Token token.Token
+	Name  *Identifier
+	Value Expression
+}
+
+// Wildcard is the pattern that matches any value without binding it.
+const Wildcard = "_"
+
+// PatternNames returns the identifiers a pattern binds, in source order.
+func PatternNames(pattern Expression) []*Identifier {
+	names := []*Identifier{}
+	switch pattern := pattern.(type) {
+	case *Identifier:
+		if pattern.Value != Wildcard {
+			names = append(names, pattern)
+		}
+	case *RestPattern:
+		names = append(names, PatternNames(pattern.Name)...)
+	case *ArrayLiteral:
+		for _, el := range pattern.Elements {
+			names = append(names, PatternNames(el)...)
+		}
+	case *HashLiteral:
+		for _, value := range pattern.Values {
+			names = append(names, PatternNames(value)...)
+		}
+	case *CallExpression:
+		for _, arg := range pattern.Arguments {
+			if named, ok := arg.(*NamedArgument); ok {
+				arg = named.Value
+			}
+			names = append(names, PatternNames(arg)...)
+		}
+	}
+	return names
 }
 
 type CallExpression struct {
@@ -119,8 +402,18 @@ func (ls *LetStatement) statementNode()       {}
 func (ls *LetStatement) TokenLiteral() string { return ls.Token.Literal }
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
+	if ls.Exported {
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+		if ls.Name.Type != nil {
+			out.WriteString(": " + ls.Name.Type.String())
+		}
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -154,6 +447,9 @@ func (es *ExpressionStatement) String() string {
 	return ""
 }
 
+func (ta *TypeAnnotation) TokenLiteral() string { return ta.Token.Literal }
+func (ta *TypeAnnotation) String() string       { return ta.Name }
+
 func (il *IntegerLiteral) expressionNode()      {}
 func (il *IntegerLiteral) TokenLiteral() string { return il.Token.Literal }
 func (il *IntegerLiteral) String() string       { return il.Token.Literal }
@@ -162,6 +458,27 @@ func (fl *FloatLiteral) expressionNode()      {}
 func (fl *FloatLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FloatLiteral) String() string       { return fl.Token.Literal }
 
+func (sl *StringLiteral) expressionNode()      {}
+func (sl *StringLiteral) TokenLiteral() string { return sl.Token.Literal }
+func (sl *StringLiteral) String() string       { return strconv.Quote(sl.Value) }
+
+func (is *InterpolatedString) expressionNode()      {}
+func (is *InterpolatedString) TokenLiteral() string { return is.Token.Literal }
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+	out.WriteString(`"`)
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			quoted := strconv.Quote(text.Value)
+			out.WriteString(strings.ReplaceAll(quoted[1:len(quoted)-1], "${", `\${`))
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+	out.WriteString(`"`)
+	return out.String()
+}
+
 func (b *Boolean) expressionNode()      {}
 func (b *Boolean) TokenLiteral() string { return b.Token.Literal }
 func (b *Boolean) String() string       { return b.Token.Literal }
@@ -219,13 +536,30 @@ func (fl *FunctionLiteral) TokenLiteral() string { return fl.Token.Literal }
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		param := p.String()
+		if p.Type != nil {
+			param += ": " + p.Type.String()
+		}
+		if def := fl.Default(i); def != nil {
+			param += " = " + def.String()
+		}
+		params = append(params, param)
+	}
+	if fl.Rest != nil {
+		rest := "..." + fl.Rest.String()
+		if fl.Rest.Type != nil {
+			rest += ": " + fl.Rest.Type.String()
+		}
+		params = append(params, rest)
 	}
 	out.WriteString(fl.TokenLiteral())
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") ")
+	if fl.ReturnType != nil {
+		out.WriteString("-> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteString(fl.Body.String())
 	return out.String()
 }
@@ -244,3 +578,201 @@ func (ce *CallExpression) String() string {
 	out.WriteString(")")
 	return out.String()
 }
+
+func (na *NamedArgument) expressionNode()      {}
+func (na *NamedArgument) TokenLiteral() string { return na.Token.Literal }
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
+
+func (pe *PipeExpression) expressionNode()      {}
+func (pe *PipeExpression) TokenLiteral() string { return pe.Token.Literal }
+func (pe *PipeExpression) String() string {
+	return "(" + pe.Left.String() + " |> " + pe.Right.String() + ")"
+}
+
+// Call returns the call the pipe stands for.
+func (pe *PipeExpression) Call() *CallExpression {
+	call, ok := pe.Right.(*CallExpression)
+	if !ok {
+		return &CallExpression{Token: pe.Token, Function: pe.Right, Arguments: []Expression{pe.Left}}
+	}
+	args := append([]Expression{pe.Left}, call.Arguments...)
+	return &CallExpression{Token: call.Token, Function: call.Function, Arguments: args}
+}
+
+func (me *MatchExpression) expressionNode()      {}
+func (me *MatchExpression) TokenLiteral() string { return me.Token.Literal }
+func (me *MatchExpression) String() string {
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+	return "match (" + me.Value.String() + ") { " + strings.Join(arms, ", ") + " }"
+}
+
+func (re *RangeExpression) expressionNode()      {}
+func (re *RangeExpression) TokenLiteral() string { return re.Token.Literal }
+func (re *RangeExpression) String() string {
+	return "(" + re.Start.String() + re.Token.Literal + re.End.String() + ")"
+}
+
+func (fe *ForExpression) expressionNode()      {}
+func (fe *ForExpression) TokenLiteral() string { return fe.Token.Literal }
+func (fe *ForExpression) String() string {
+	return "for (" + fe.Pattern.String() + " in " + fe.Iterable.String() + ") " + fe.Body.String()
+}
+
+func (ma *MatchArm) TokenLiteral() string { return ma.Token.Literal }
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if " + ma.Guard.String())
+	}
+	out.WriteString(" => " + ma.Body.String())
+	return out.String()
+}
+
+func (ss *StructStatement) statementNode()       {}
+func (ss *StructStatement) TokenLiteral() string { return ss.Token.Literal }
+func (ss *StructStatement) String() string {
+	fields := []string{}
+	for _, f := range ss.Fields {
+		fields = append(fields, f.String())
+	}
+	var out bytes.Buffer
+	if ss.Exported {
+		out.WriteString("export ")
+	}
+	out.WriteString("struct " + ss.Name.String() + " { " + strings.Join(fields, ", ") + " }")
+	return out.String()
+}
+
+func (ae *AssignExpression) expressionNode()      {}
+func (ae *AssignExpression) TokenLiteral() string { return ae.Token.Literal }
+func (ae *AssignExpression) String() string {
+	return "(" + ae.Target.String() + " = " + ae.Value.String() + ")"
+}
+
+func (md *MethodDeclaration) expressionNode()      {}
+func (md *MethodDeclaration) TokenLiteral() string { return md.Token.Literal }
+func (md *MethodDeclaration) String() string {
+	fn := strings.TrimPrefix(md.Function.String(), md.Function.TokenLiteral())
+	return md.TokenLiteral() + " (" + md.Receiver.String() + " " + md.Struct.String() + ") " + md.Name.String() + fn
+}
+
+func (rp *RestPattern) expressionNode()      {}
+func (rp *RestPattern) TokenLiteral() string { return rp.Token.Literal }
+func (rp *RestPattern) String() string       { return "..." + rp.Name.String() }
+
+func (al *ArrayLiteral) expressionNode()      {}
+func (al *ArrayLiteral) TokenLiteral() string { return al.Token.Literal }
+func (al *ArrayLiteral) String() string {
+	elements := []string{}
+	for _, el := range al.Elements {
+		elements = append(elements, el.String())
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (hl *HashLiteral) expressionNode()      {}
+func (hl *HashLiteral) TokenLiteral() string { return hl.Token.Literal }
+func (hl *HashLiteral) String() string {
+	pairs := []string{}
+	for i, key := range hl.Keys {
+		pairs = append(pairs, key.String()+": "+hl.Values[i].String())
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+func (ie *IndexExpression) expressionNode()      {}
+func (ie *IndexExpression) TokenLiteral() string { return ie.Token.Literal }
+func (ie *IndexExpression) String() string {
+	return "(" + ie.Left.String() + "[" + ie.Index.String() + "])"
+}
+
+func (is *ImportStatement) statementNode()       {}
+func (is *ImportStatement) TokenLiteral() string { return is.Token.Literal }
+func (is *ImportStatement) String() string {
+	return is.TokenLiteral() + " " + strconv.Quote(is.Path) + ";"
+}
+
+func (se *SelectorExpression) expressionNode()      {}
+func (se *SelectorExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectorExpression) String() string {
+	return se.Left.String() + "." + se.Name.String()
+}
+
+func (te *ThrowExpression) expressionNode()      {}
+func (te *ThrowExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *ThrowExpression) String() string {
+	return te.TokenLiteral() + " " + te.Value.String()
+}
+
+func (ye *YieldExpression) expressionNode()      {}
+func (ye *YieldExpression) TokenLiteral() string { return ye.Token.Literal }
+func (ye *YieldExpression) String() string {
+	return ye.TokenLiteral() + " " + ye.Value.String()
+}
+
+func (se *SpawnExpression) expressionNode()      {}
+func (se *SpawnExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SpawnExpression) String() string {
+	return se.TokenLiteral() + " " + se.Value.String()
+}
+
+func (se *SelectExpression) expressionNode()      {}
+func (se *SelectExpression) TokenLiteral() string { return se.Token.Literal }
+func (se *SelectExpression) String() string {
+	cases := []string{}
+	for _, c := range se.Cases {
+		cases = append(cases, c.String())
+	}
+	return "select { " + strings.Join(cases, ", ") + " }"
+}
+
+func (sc *SelectCase) TokenLiteral() string { return sc.Token.Literal }
+func (sc *SelectCase) String() string {
+	var out bytes.Buffer
+	switch {
+	case sc.Op == nil:
+		out.WriteString(Wildcard)
+	case sc.Name != nil:
+		out.WriteString(sc.Name.String() + " = " + sc.Op.String())
+	default:
+		out.WriteString(sc.Op.String())
+	}
+	out.WriteString(" => " + sc.Body.String())
+	return out.String()
+}
+
+func (te *TryExpression) expressionNode()      {}
+func (te *TryExpression) TokenLiteral() string { return te.Token.Literal }
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("try ")
+	out.WriteString(te.Body.String())
+	out.WriteString(" catch ")
+	if te.Param != nil {
+		out.WriteString("(" + te.Param.String() + ") ")
+	}
+	out.WriteString(te.Handler.String())
+	return out.String()
+}
+
+func (ml *MacroLiteral) expressionNode()      {}
+func (ml *MacroLiteral) TokenLiteral() string { return ml.Token.Literal }
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range ml.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString(ml.TokenLiteral())
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") ")
+	out.WriteString(ml.Body.String())
+	return out.String()
+}
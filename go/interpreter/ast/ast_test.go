@@ -25,3 +25,38 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestNodeSpan(t *testing.T) {
+	// "let x =\n  a + 10"
+	program := &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Token: token.Token{Type: token.LET, Literal: "let", Line: 1, Column: 1},
+				Name: &Identifier{
+					Token: token.Token{Type: token.IDENT, Literal: "x", Line: 1, Column: 5},
+					Value: "x",
+				},
+				Value: &InfixExpression{
+					Token:    token.Token{Type: token.PLUS, Literal: "+", Line: 2, Column: 5},
+					Operator: "+",
+					Left: &Identifier{
+						Token: token.Token{Type: token.IDENT, Literal: "a", Line: 2, Column: 3},
+						Value: "a",
+					},
+					Right: &IntegerLiteral{
+						Token: token.Token{Type: token.INT, Literal: "10", Line: 2, Column: 7},
+						Value: 10,
+					},
+				},
+			},
+		},
+	}
+
+	if span := NodeSpan(program).String(); span != "1:1-2:8" {
+		t.Errorf("span of program wrong. got=%q", span)
+	}
+	value := program.Statements[0].(*LetStatement).Value
+	if span := NodeSpan(value).String(); span != "2:3-2:8" {
+		t.Errorf("span of value wrong. got=%q", span)
+	}
+}
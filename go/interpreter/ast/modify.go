@@ -0,0 +1,364 @@
+package ast
+
+// ModifierFunc returns the node that replaces node.
+type ModifierFunc func(node Node) Node
+
+// Modify rewrites the tree bottom-up: the children of a node are modified
+// before modifier is called with the node itself.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+	case *Program:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence, _ = Modify(node.Consequence, modifier).(*BlockStatement)
+		if node.Alternative != nil {
+			node.Alternative, _ = Modify(node.Alternative, modifier).(*BlockStatement)
+		}
+	case *BlockStatement:
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+	case *LetStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			node.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+		}
+		for i, def := range node.Defaults {
+			node.Defaults[i] = modifyExpression(def, modifier)
+		}
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *SelectorExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+	case *ArrayLiteral:
+		for i, el := range node.Elements {
+			node.Elements[i] = modifyExpression(el, modifier)
+		}
+	case *InterpolatedString:
+		for i, part := range node.Parts {
+			node.Parts[i] = modifyExpression(part, modifier)
+		}
+	case *HashLiteral:
+		for i, key := range node.Keys {
+			node.Keys[i] = modifyExpression(key, modifier)
+			node.Values[i] = modifyExpression(node.Values[i], modifier)
+		}
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+	case *ThrowExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *YieldExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *SpawnExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *SelectExpression:
+		for _, c := range node.Cases {
+			if c.Op != nil {
+				for i, arg := range c.Op.Arguments {
+					c.Op.Arguments[i] = modifyExpression(arg, modifier)
+				}
+			}
+			c.Body = modifyExpression(c.Body, modifier)
+		}
+	case *TryExpression:
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+		node.Handler, _ = Modify(node.Handler, modifier).(*BlockStatement)
+	case *MatchExpression:
+		node.Value = modifyExpression(node.Value, modifier)
+		for _, arm := range node.Arms {
+			arm.Pattern = modifyExpression(arm.Pattern, modifier)
+			arm.Guard = modifyExpression(arm.Guard, modifier)
+			arm.Body = modifyExpression(arm.Body, modifier)
+		}
+	case *RangeExpression:
+		node.Start = modifyExpression(node.Start, modifier)
+		node.End = modifyExpression(node.End, modifier)
+	case *ForExpression:
+		node.Iterable = modifyExpression(node.Iterable, modifier)
+		node.Body, _ = Modify(node.Body, modifier).(*BlockStatement)
+	case *NamedArgument:
+		node.Value = modifyExpression(node.Value, modifier)
+	case *MethodDeclaration:
+		node.Function, _ = Modify(node.Function, modifier).(*FunctionLiteral)
+	case *AssignExpression:
+		// the target stays assignable, only its operands are modified
+		switch target := node.Target.(type) {
+		case *SelectorExpression:
+			target.Left = modifyExpression(target.Left, modifier)
+		case *IndexExpression:
+			target.Left = modifyExpression(target.Left, modifier)
+			target.Index = modifyExpression(target.Index, modifier)
+		}
+		node.Value = modifyExpression(node.Value, modifier)
+	case *PipeExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		for i, arg := range node.Arguments {
+			node.Arguments[i] = modifyExpression(arg, modifier)
+		}
+	}
+
+	return modifier(node)
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified, _ := Modify(exp, modifier).(Expression)
+	return modified
+}
+
+// Copy returns a deep copy of node, so the copy can be modified without
+// changing the original tree. Type annotations are shared.
+func Copy(node Node) Node {
+	switch node := node.(type) {
+	case *Program:
+		c := *node
+		c.Statements = copyStatements(node.Statements)
+		return &c
+	case *Identifier:
+		c := *node
+		return &c
+	case *IntegerLiteral:
+		c := *node
+		return &c
+	case *FloatLiteral:
+		c := *node
+		return &c
+	case *StringLiteral:
+		c := *node
+		return &c
+	case *Boolean:
+		c := *node
+		return &c
+	case *LetStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Pattern = copyExpression(node.Pattern)
+		c.Value = copyExpression(node.Value)
+		return &c
+	case *ReturnStatement:
+		c := *node
+		c.ReturnValue = copyExpression(node.ReturnValue)
+		return &c
+	case *ExpressionStatement:
+		c := *node
+		c.Expression = copyExpression(node.Expression)
+		return &c
+	case *PrefixExpression:
+		c := *node
+		c.Right = copyExpression(node.Right)
+		return &c
+	case *InfixExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Right = copyExpression(node.Right)
+		return &c
+	case *IfExpression:
+		c := *node
+		c.Condition = copyExpression(node.Condition)
+		c.Consequence = copyBlock(node.Consequence)
+		c.Alternative = copyBlock(node.Alternative)
+		return &c
+	case *BlockStatement:
+		return copyBlock(node)
+	case *FunctionLiteral:
+		c := *node
+		c.Parameters = copyIdentifiers(node.Parameters)
+		c.Defaults = copyExpressions(node.Defaults)
+		c.Rest = copyIdentifier(node.Rest)
+		c.Body = copyBlock(node.Body)
+		return &c
+	case *MacroLiteral:
+		c := *node
+		c.Parameters = copyIdentifiers(node.Parameters)
+		c.Body = copyBlock(node.Body)
+		return &c
+	case *ArrayLiteral:
+		c := *node
+		c.Elements = copyExpressions(node.Elements)
+		return &c
+	case *InterpolatedString:
+		c := *node
+		c.Parts = copyExpressions(node.Parts)
+		return &c
+	case *HashLiteral:
+		c := *node
+		c.Keys = copyExpressions(node.Keys)
+		c.Values = copyExpressions(node.Values)
+		return &c
+	case *IndexExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Index = copyExpression(node.Index)
+		return &c
+	case *ThrowExpression:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+	case *YieldExpression:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+	case *SpawnExpression:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		return &c
+	case *SelectExpression:
+		c := *node
+		c.Cases = make([]*SelectCase, len(node.Cases))
+		for i, sc := range node.Cases {
+			caseCopy := *sc
+			caseCopy.Name = copyIdentifier(sc.Name)
+			if sc.Op != nil {
+				caseCopy.Op = copyExpression(sc.Op).(*CallExpression)
+			}
+			caseCopy.Body = copyExpression(sc.Body)
+			c.Cases[i] = &caseCopy
+		}
+		return &c
+	case *TryExpression:
+		c := *node
+		c.Body = copyBlock(node.Body)
+		c.Param = copyIdentifier(node.Param)
+		c.Handler = copyBlock(node.Handler)
+		return &c
+	case *ImportStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		return &c
+	case *SelectorExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Name = copyIdentifier(node.Name)
+		return &c
+	case *MatchExpression:
+		c := *node
+		c.Value = copyExpression(node.Value)
+		c.Arms = make([]*MatchArm, len(node.Arms))
+		for i, arm := range node.Arms {
+			armCopy := *arm
+			armCopy.Pattern = copyExpression(arm.Pattern)
+			armCopy.Guard = copyExpression(arm.Guard)
+			armCopy.Body = copyExpression(arm.Body)
+			c.Arms[i] = &armCopy
+		}
+		return &c
+	case *RangeExpression:
+		c := *node
+		c.Start = copyExpression(node.Start)
+		c.End = copyExpression(node.End)
+		return &c
+	case *ForExpression:
+		c := *node
+		c.Pattern = copyExpression(node.Pattern)
+		c.Iterable = copyExpression(node.Iterable)
+		c.Body = copyBlock(node.Body)
+		return &c
+	case *RestPattern:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		return &c
+	case *NamedArgument:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Value = copyExpression(node.Value)
+		return &c
+	case *StructStatement:
+		c := *node
+		c.Name = copyIdentifier(node.Name)
+		c.Fields = copyIdentifiers(node.Fields)
+		return &c
+	case *MethodDeclaration:
+		c := *node
+		c.Receiver = copyIdentifier(node.Receiver)
+		c.Struct = copyIdentifier(node.Struct)
+		c.Name = copyIdentifier(node.Name)
+		c.Function, _ = Copy(node.Function).(*FunctionLiteral)
+		return &c
+	case *AssignExpression:
+		c := *node
+		c.Target = copyExpression(node.Target)
+		c.Value = copyExpression(node.Value)
+		return &c
+	case *PipeExpression:
+		c := *node
+		c.Left = copyExpression(node.Left)
+		c.Right = copyExpression(node.Right)
+		return &c
+	case *CallExpression:
+		c := *node
+		c.Function = copyExpression(node.Function)
+		c.Arguments = copyExpressions(node.Arguments)
+		return &c
+	default:
+		return node
+	}
+}
+
+func copyExpression(exp Expression) Expression {
+	if exp == nil {
+		return nil
+	}
+	return Copy(exp).(Expression)
+}
+
+func copyExpressions(exps []Expression) []Expression {
+	if exps == nil {
+		return nil
+	}
+	c := make([]Expression, len(exps))
+	for i, exp := range exps {
+		c[i] = copyExpression(exp)
+	}
+	return c
+}
+
+func copyStatements(statements []Statement) []Statement {
+	c := make([]Statement, len(statements))
+	for i, s := range statements {
+		c[i] = Copy(s).(Statement)
+	}
+	return c
+}
+
+func copyBlock(block *BlockStatement) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	c := *block
+	c.Statements = copyStatements(block.Statements)
+	return &c
+}
+
+func copyIdentifier(ident *Identifier) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	c := *ident
+	return &c
+}
+
+func copyIdentifiers(idents []*Identifier) []*Identifier {
+	c := make([]*Identifier, len(idents))
+	for i, ident := range idents {
+		c[i] = copyIdentifier(ident)
+	}
+	return c
+}
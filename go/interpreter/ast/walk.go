@@ -0,0 +1,286 @@
+package ast
+
+import (
+	"fmt"
+	"interpreter/token"
+)
+
+// Walk traverses the tree depth-first and calls fn for every node. If fn
+// returns false the children of that node are skipped.
+func Walk(node Node, fn func(Node) bool) {
+	if node == nil || !fn(node) {
+		return
+	}
+
+	switch node := node.(type) {
+	case *Program:
+		for _, s := range node.Statements {
+			Walk(s, fn)
+		}
+	case *BlockStatement:
+		for _, s := range node.Statements {
+			Walk(s, fn)
+		}
+	case *LetStatement:
+		if node.Name != nil {
+			Walk(node.Name, fn)
+		}
+		walkExpression(node.Pattern, fn)
+		walkExpression(node.Value, fn)
+	case *ReturnStatement:
+		walkExpression(node.ReturnValue, fn)
+	case *ExpressionStatement:
+		walkExpression(node.Expression, fn)
+	case *PrefixExpression:
+		walkExpression(node.Right, fn)
+	case *InfixExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Right, fn)
+	case *IfExpression:
+		walkExpression(node.Condition, fn)
+		Walk(node.Consequence, fn)
+		if node.Alternative != nil {
+			Walk(node.Alternative, fn)
+		}
+	case *FunctionLiteral:
+		for i, p := range node.Parameters {
+			Walk(p, fn)
+			walkExpression(node.Default(i), fn)
+		}
+		if node.Rest != nil {
+			Walk(node.Rest, fn)
+		}
+		Walk(node.Body, fn)
+	case *MacroLiteral:
+		for _, p := range node.Parameters {
+			Walk(p, fn)
+		}
+		Walk(node.Body, fn)
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			walkExpression(el, fn)
+		}
+	case *HashLiteral:
+		for i, key := range node.Keys {
+			walkExpression(key, fn)
+			walkExpression(node.Values[i], fn)
+		}
+	case *InterpolatedString:
+		for _, part := range node.Parts {
+			walkExpression(part, fn)
+		}
+	case *IndexExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Index, fn)
+	case *ThrowExpression:
+		walkExpression(node.Value, fn)
+	case *YieldExpression:
+		walkExpression(node.Value, fn)
+	case *SpawnExpression:
+		walkExpression(node.Value, fn)
+	case *SelectExpression:
+		for _, c := range node.Cases {
+			Walk(c, fn)
+		}
+	case *SelectCase:
+		if node.Name != nil {
+			Walk(node.Name, fn)
+		}
+		if node.Op != nil {
+			Walk(node.Op, fn)
+		}
+		walkExpression(node.Body, fn)
+	case *TryExpression:
+		Walk(node.Body, fn)
+		if node.Param != nil {
+			Walk(node.Param, fn)
+		}
+		Walk(node.Handler, fn)
+	case *ImportStatement:
+		Walk(node.Name, fn)
+	case *SelectorExpression:
+		walkExpression(node.Left, fn)
+		Walk(node.Name, fn)
+	case *MatchExpression:
+		walkExpression(node.Value, fn)
+		for _, arm := range node.Arms {
+			Walk(arm, fn)
+		}
+	case *RangeExpression:
+		walkExpression(node.Start, fn)
+		walkExpression(node.End, fn)
+	case *ForExpression:
+		walkExpression(node.Pattern, fn)
+		walkExpression(node.Iterable, fn)
+		Walk(node.Body, fn)
+	case *StructStatement:
+		Walk(node.Name, fn)
+		for _, f := range node.Fields {
+			Walk(f, fn)
+		}
+	case *AssignExpression:
+		Walk(node.Target, fn)
+		walkExpression(node.Value, fn)
+	case *MethodDeclaration:
+		Walk(node.Receiver, fn)
+		Walk(node.Struct, fn)
+		Walk(node.Name, fn)
+		Walk(node.Function, fn)
+	case *RestPattern:
+		Walk(node.Name, fn)
+	case *NamedArgument:
+		Walk(node.Name, fn)
+		walkExpression(node.Value, fn)
+	case *MatchArm:
+		walkExpression(node.Pattern, fn)
+		walkExpression(node.Guard, fn)
+		walkExpression(node.Body, fn)
+	case *PipeExpression:
+		walkExpression(node.Left, fn)
+		walkExpression(node.Right, fn)
+	case *CallExpression:
+		walkExpression(node.Function, fn)
+		for _, a := range node.Arguments {
+			walkExpression(a, fn)
+		}
+	}
+}
+
+// walkExpression skips expressions the parser could not build.
+func walkExpression(exp Expression, fn func(Node) bool) {
+	if exp != nil {
+		Walk(exp, fn)
+	}
+}
+
+// Span is the source range of a node: from the first character of its first
+// token up to and including the last character of its last token.
+type Span struct {
+	StartLine, StartColumn int
+	EndLine, EndColumn     int
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("%d:%d-%d:%d", s.StartLine, s.StartColumn, s.EndLine, s.EndColumn)
+}
+
+// NodeSpan computes the span of node from the positions of all tokens below it.
+func NodeSpan(node Node) Span {
+	var span Span
+	first := true
+	Walk(node, func(n Node) bool {
+		tok, ok := nodeToken(n)
+		if !ok || tok.Line == 0 {
+			return true
+		}
+		endColumn := tok.Column + len(tok.Literal) - 1
+		if len(tok.Literal) == 0 {
+			endColumn = tok.Column
+		}
+		if first || before(tok.Line, tok.Column, span.StartLine, span.StartColumn) {
+			span.StartLine, span.StartColumn = tok.Line, tok.Column
+		}
+		if first || before(span.EndLine, span.EndColumn, tok.Line, endColumn) {
+			span.EndLine, span.EndColumn = tok.Line, endColumn
+		}
+		first = false
+		return true
+	})
+	return span
+}
+
+// Pos returns the position of the token a node was created from.
+func Pos(node Node) (line, column int) {
+	tok, _ := nodeToken(node)
+	return tok.Line, tok.Column
+}
+
+func before(line, column, otherLine, otherColumn int) bool {
+	return line < otherLine || line == otherLine && column < otherColumn
+}
+
+func nodeToken(node Node) (token.Token, bool) {
+	switch node := node.(type) {
+	case *Identifier:
+		return node.Token, true
+	case *IntegerLiteral:
+		return node.Token, true
+	case *FloatLiteral:
+		return node.Token, true
+	case *StringLiteral:
+		return node.Token, true
+	case *InterpolatedString:
+		return node.Token, true
+	case *Boolean:
+		return node.Token, true
+	case *LetStatement:
+		return node.Token, true
+	case *ReturnStatement:
+		return node.Token, true
+	case *ExpressionStatement:
+		return node.Token, true
+	case *PrefixExpression:
+		return node.Token, true
+	case *InfixExpression:
+		return node.Token, true
+	case *IfExpression:
+		return node.Token, true
+	case *BlockStatement:
+		return node.Token, true
+	case *FunctionLiteral:
+		return node.Token, true
+	case *CallExpression:
+		return node.Token, true
+	case *PipeExpression:
+		return node.Token, true
+	case *MatchExpression:
+		return node.Token, true
+	case *MatchArm:
+		return node.Token, true
+	case *RestPattern:
+		return node.Token, true
+	case *RangeExpression:
+		return node.Token, true
+	case *ForExpression:
+		return node.Token, true
+	case *StructStatement:
+		return node.Token, true
+	case *AssignExpression:
+		return node.Token, true
+	case *NamedArgument:
+		return node.Token, true
+	case *MethodDeclaration:
+		return node.Token, true
+	case *TypeAnnotation:
+		return node.Token, true
+	case *MacroLiteral:
+		return node.Token, true
+	case *ImportStatement:
+		return node.Token, true
+	case *ArrayLiteral:
+		return node.Token, true
+	case *HashLiteral:
+		return node.Token, true
+	case *IndexExpression:
+		return node.Token, true
+	case *ThrowExpression:
+		return node.Token, true
+	case *YieldExpression:
+		return node.Token, true
+	case *SpawnExpression:
+		return node.Token, true
+	case *SelectExpression:
+		return node.Token, true
+	case *SelectCase:
+		return node.Token, true
+	case *TryExpression:
+		return node.Token, true
+	case *SelectorExpression:
+		return node.Token, true
+	case *Program:
+		if len(node.Statements) > 0 {
+			return nodeToken(node.Statements[0])
+		}
+	}
+	return token.Token{}, false
+}
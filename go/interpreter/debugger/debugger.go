@@ -0,0 +1,330 @@
+package debugger
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"interpreter/ast"
+	"interpreter/diag"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"interpreter/pipeline"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const PROMPT = "(debug) "
+
+const HELP = `commands:
+  break N, b N      set a breakpoint on line N
+  clear N           remove the breakpoint on line N
+  step, s           step into the next node
+  next, n           step over the current node
+  out, o            run until the current function call returns
+  continue, c       run until the next breakpoint
+  print EXPR, p     evaluate EXPR in the current environment
+  env               list the bindings of the current environment
+  where, w          show the current position
+  quit, q           abort the evaluation
+`
+
+type mode int
+
+const (
+	stepInto mode = iota
+	stepOver
+	stepOut
+	run
+)
+
+// errQuit unwinds the evaluation when the user quits the session.
+var errQuit = errors.New("debugger: quit")
+
+// Debugger pauses the evaluation between node evaluations and reads commands
+// from its input. It implements eval.Tracer.
+type Debugger struct {
+	lines       []string
+	scanner     *bufio.Scanner
+	out         io.Writer
+	breakpoints map[int]bool
+
+	mode        mode
+	targetDepth int
+	depth       int
+	stack       []ast.Node
+	frames      []int
+	lastLine    int
+
+	current ast.Node
+	env     *object.Environment
+	// quitting is set once the user quit. A task turns the panic that
+	// unwinds it into its error, so every node entered afterwards panics
+	// again until the whole evaluation is unwound.
+	quitting bool
+	// context is the context of the session, print borrows its loader and
+	// output.
+	context *eval.Context
+}
+
+func New(source string, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		lines:       strings.Split(source, "\n"),
+		scanner:     bufio.NewScanner(in),
+		out:         out,
+		breakpoints: make(map[int]bool),
+		mode:        stepInto,
+	}
+}
+
+// Start parses source and evaluates it with c under the control of the
+// debugger. The output of the program goes to out. The session pauses
+// before the first node so breakpoints can be set.
+func Start(c *eval.Context, source string, in io.Reader, out io.Writer) {
+	l := lexer.New(source)
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			io.WriteString(out, "\t"+msg+"\n")
+		}
+		return
+	}
+
+	c.Output = out
+	program, diagnostics := pipeline.Prepare(context.Background(), c, program, object.NewEnvironment(), eval.BuiltinNames())
+	for _, d := range diagnostics {
+		io.WriteString(out, "\t"+d.String()+"\n")
+	}
+	if diag.HasErrors(diagnostics) {
+		return
+	}
+
+	d := New(source, in, out)
+	result, ok := d.Run(c, program, object.NewEnvironment())
+	if !ok {
+		io.WriteString(out, "Evaluation aborted\n")
+		return
+	}
+	if result != nil {
+		fmt.Fprintf(out, "Program finished: %s\n", result.Inspect())
+	}
+}
+
+// Run evaluates program in env with c, which it installs itself as tracer
+// of. Other evaluations are not affected. ok is false if the user quit the
+// session.
+func (d *Debugger) Run(c *eval.Context, program *ast.Program, env *object.Environment) (result object.Object, ok bool) {
+	previous := c.Tracer
+	c.Tracer, d.context = d, c
+	defer func() { c.Tracer = previous }()
+	defer func() {
+		if r := recover(); r != nil {
+			if r != errQuit {
+				panic(r)
+			}
+			result, ok = nil, false
+		}
+	}()
+
+	d.quitting = false
+	result = c.Run(context.Background(), program, env)
+	// the user may have quit in a task whose error ended the program
+	return result, !d.quitting
+}
+
+func (d *Debugger) Enter(node ast.Node, env *object.Environment) {
+	if d.quitting {
+		panic(errQuit)
+	}
+	d.depth++
+	d.stack = append(d.stack, node)
+	if _, ok := node.(*ast.BlockStatement); ok && d.parentIsCall() {
+		d.frames = append(d.frames, d.depth)
+	}
+
+	if !pausable(node) {
+		return
+	}
+
+	line, _ := ast.Pos(node)
+	hitBreakpoint := d.breakpoints[line] && line != d.lastLine
+	d.lastLine = line
+
+	if hitBreakpoint || d.shouldStep() {
+		d.current, d.env = node, env
+		d.pause(hitBreakpoint)
+	}
+}
+
+func (d *Debugger) Leave(node ast.Node, result object.Object) {
+	if len(d.frames) > 0 && d.frames[len(d.frames)-1] == d.depth {
+		d.frames = d.frames[:len(d.frames)-1]
+	}
+	d.stack = d.stack[:len(d.stack)-1]
+	d.depth--
+}
+
+func (d *Debugger) shouldStep() bool {
+	switch d.mode {
+	case stepInto:
+		return true
+	case stepOver:
+		return d.depth <= d.targetDepth
+	case stepOut:
+		return d.depth < d.targetDepth
+	default:
+		return false
+	}
+}
+
+// parentIsCall reports whether the node on top of the stack was entered
+// directly from a call expression, i.e. it is the body of the called function.
+func (d *Debugger) parentIsCall() bool {
+	if len(d.stack) < 2 {
+		return false
+	}
+	_, ok := d.stack[len(d.stack)-2].(*ast.CallExpression)
+	return ok
+}
+
+// pause shows the current node and handles commands until one of them
+// resumes the evaluation.
+func (d *Debugger) pause(hitBreakpoint bool) {
+	if hitBreakpoint {
+		line, _ := ast.Pos(d.current)
+		fmt.Fprintf(d.out, "Breakpoint on line %d\n", line)
+	}
+	d.where()
+
+	for {
+		fmt.Fprint(d.out, PROMPT)
+		if !d.scanner.Scan() {
+			d.quit()
+		}
+
+		command, arg, _ := strings.Cut(strings.TrimSpace(d.scanner.Text()), " ")
+		arg = strings.TrimSpace(arg)
+
+		switch command {
+		case "step", "s":
+			d.mode = stepInto
+			return
+		case "next", "n":
+			d.mode, d.targetDepth = stepOver, d.depth
+			return
+		case "out", "o":
+			if len(d.frames) == 0 {
+				d.mode = run
+			} else {
+				d.mode, d.targetDepth = stepOut, d.frames[len(d.frames)-1]
+			}
+			return
+		case "continue", "c":
+			d.mode = run
+			return
+		case "break", "b":
+			if line, ok := d.parseLine(arg); ok {
+				d.breakpoints[line] = true
+				fmt.Fprintf(d.out, "Breakpoint set on line %d\n", line)
+			}
+		case "clear":
+			if line, ok := d.parseLine(arg); ok {
+				delete(d.breakpoints, line)
+				fmt.Fprintf(d.out, "Breakpoint on line %d removed\n", line)
+			}
+		case "print", "p":
+			d.print(arg)
+		case "env":
+			d.printEnv()
+		case "where", "w":
+			d.where()
+		case "help", "h":
+			io.WriteString(d.out, HELP)
+		case "quit", "q":
+			d.quit()
+		case "":
+		default:
+			fmt.Fprintf(d.out, "unknown command: %s (try help)\n", command)
+		}
+	}
+}
+
+func (d *Debugger) quit() {
+	d.quitting = true
+	panic(errQuit)
+}
+
+func (d *Debugger) parseLine(arg string) (int, bool) {
+	line, err := strconv.Atoi(arg)
+	if err != nil || line < 1 || line > len(d.lines) {
+		fmt.Fprintf(d.out, "invalid line: %q\n", arg)
+		return 0, false
+	}
+	return line, true
+}
+
+// where prints the current node with its span and the source line it starts on.
+func (d *Debugger) where() {
+	span := ast.NodeSpan(d.current)
+	fmt.Fprintf(d.out, "-> %s  %s\n", span, d.current.String())
+	if span.StartLine >= 1 && span.StartLine <= len(d.lines) {
+		fmt.Fprintf(d.out, "%4d | %s\n", span.StartLine, d.lines[span.StartLine-1])
+	}
+}
+
+// print evaluates input in the current environment. It runs with a context
+// of its own, so it is not traced and does not count towards the limits of
+// the session.
+func (d *Debugger) print(input string) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			io.WriteString(d.out, "\t"+msg+"\n")
+		}
+		return
+	}
+
+	c := eval.NewContext()
+	c.Loader, c.Output = d.context.Loader, d.context.Output
+	result := c.Run(context.Background(), program, d.env)
+	if result != nil {
+		io.WriteString(d.out, describe(result)+"\n")
+	}
+}
+
+// printEnv lists the bindings from the innermost to the global scope.
+func (d *Debugger) printEnv() {
+	for env, level := d.env, 0; env != nil; env, level = env.Outer(), level+1 {
+		names := env.Names()
+		sort.Strings(names)
+		for _, name := range names {
+			val, _ := env.Get(name)
+			fmt.Fprintf(d.out, "%s%s = %s\n", strings.Repeat("  ", level), name, describe(val))
+		}
+	}
+}
+
+// describe shortens functions to their signature.
+func describe(obj object.Object) string {
+	if fn, ok := obj.(*object.Function); ok {
+		return "fn(" + fn.ParameterList() + ")"
+	}
+	return obj.Inspect()
+}
+
+// pausable filters out wrapper nodes that share the position of their child.
+func pausable(node ast.Node) bool {
+	switch node.(type) {
+	case *ast.Program, *ast.BlockStatement, *ast.ExpressionStatement:
+		return false
+	default:
+		return true
+	}
+}
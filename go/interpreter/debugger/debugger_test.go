@@ -0,0 +1,125 @@
+package debugger
+
+import (
+	"bytes"
+	"context"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"strings"
+	"testing"
+)
+
+const source = `let add = fn(a, b) {
+  let s = a + b;
+  s * 2
+};
+let x = 3;
+let y = add(x, 4);
+y + 1`
+
+func TestBreakpointAndInspect(t *testing.T) {
+	out := runSession("b 6\nc\np x + 1\nenv\nc\n")
+
+	expected := []string{
+		"Breakpoint set on line 6",
+		"Breakpoint on line 6",
+		"-> 6:1-6:16  let y = add(x, 4);",
+		"(debug) 4\n",
+		"add = fn(a, b)",
+		"x = 3",
+		"Program finished: 15",
+	}
+	checkOutput(t, out, expected)
+}
+
+func TestStepping(t *testing.T) {
+	tests := []struct {
+		name     string
+		commands string
+		expected []string
+	}{
+		{
+			"step into call",
+			"b 6\nc\ns\ns\ns\ns\ns\nenv\nc\n",
+			[]string{"-> 2:3-2:15  let s = (a + b);", "a = 3", "b = 4"},
+		},
+		{
+			"step over let",
+			"n\nn\n",
+			[]string{"-> 5:1-5:9  let x = 3;"},
+		},
+		{
+			"step out of function",
+			"b 2\nc\nout\n",
+			[]string{"Breakpoint on line 2", "-> 7:1-7:5  (y + 1)"},
+		},
+		{
+			"quit",
+			"q\n",
+			[]string{"Evaluation aborted"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checkOutput(t, runSession(tt.commands), tt.expected)
+		})
+	}
+}
+
+func TestSessionKeepsToItsContext(t *testing.T) {
+	var out, other bytes.Buffer
+	c := eval.NewContext()
+	Start(c, `println("in session")`, strings.NewReader("c\n"), &out)
+	if c.Tracer != nil {
+		t.Errorf("the debugger stayed installed as tracer: %T", c.Tracer)
+	}
+
+	plain := eval.NewContext()
+	plain.Output = &other
+	program := parser.New(lexer.New(`println("elsewhere")`)).ParseProgram()
+	plain.Run(context.Background(), program, object.NewEnvironment())
+	if !strings.Contains(out.String(), "in session") || other.String() != "elsewhere\n" {
+		t.Errorf("output went to the wrong writer. session=%q, other=%q", out.String(), other.String())
+	}
+}
+
+func TestQuitInTask(t *testing.T) {
+	var out bytes.Buffer
+	source := "let t = spawn fn() {\n  1 + 2\n};\nrecv(t)"
+	Start(eval.NewContext(), source, strings.NewReader("b 2\nc\nq\n"), &out)
+	checkOutput(t, out.String(), []string{"Breakpoint on line 2", "Evaluation aborted"})
+	if strings.Contains(out.String(), "Program finished") {
+		t.Errorf("the program went on after quitting in a task. got=\n%s", out.String())
+	}
+}
+
+func TestStartChecksProgram(t *testing.T) {
+	var out bytes.Buffer
+	Start(eval.NewContext(), "let x: int = true; foo", strings.NewReader("c\n"), &out)
+	checkOutput(t, out.String(), []string{
+		"1:14: error: cannot assign bool to x of type int",
+		"1:20: error: undefined identifier: foo",
+	})
+	if strings.Contains(out.String(), PROMPT) {
+		t.Errorf("the session started despite errors. got=\n%s", out.String())
+	}
+}
+
+// HELPER
+
+func runSession(commands string) string {
+	var out bytes.Buffer
+	Start(eval.NewContext(), source, strings.NewReader(commands), &out)
+	return out.String()
+}
+
+func checkOutput(t *testing.T, out string, expected []string) {
+	t.Helper()
+	for _, e := range expected {
+		if !strings.Contains(out, e) {
+			t.Errorf("output does not contain %q. got=\n%s", e, out)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"strings"
+)
+
+// namedArguments holds the name: value arguments of a call. It is passed at
+// the end of the argument list, so it travels through applyFunction and tail
+// calls like the positional arguments.
+type namedArguments struct {
+	names  []string
+	values map[string]object.Object
+}
+
+func (na *namedArguments) Type() object.ObjectType { return "NAMED_ARGUMENTS" }
+func (na *namedArguments) Inspect() string {
+	args := []string{}
+	for _, name := range na.names {
+		args = append(args, name+": "+na.values[name].Inspect())
+	}
+	return strings.Join(args, ", ")
+}
+
+// evalArguments evaluates the arguments of a call like evalExpressions and
+// collects the named ones in a namedArguments at the end.
+func (c *Context) evalArguments(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+	var named *namedArguments
+
+	for _, e := range exps {
+		arg, ok := e.(*ast.NamedArgument)
+		if !ok {
+			evaluated := c.eval(e, env)
+			if isError(evaluated) {
+				return []object.Object{evaluated}
+			}
+			result = append(result, evaluated)
+			continue
+		}
+
+		evaluated := c.eval(arg.Value, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		if named == nil {
+			named = &namedArguments{values: make(map[string]object.Object)}
+		}
+		named.names = append(named.names, arg.Name.Value)
+		named.values[arg.Name.Value] = evaluated
+	}
+
+	if named != nil {
+		result = append(result, named)
+	}
+	return result
+}
+
+// splitArguments separates the positional from the named arguments.
+func splitArguments(args []object.Object) ([]object.Object, *namedArguments) {
+	if n := len(args); n > 0 {
+		if named, ok := args[n-1].(*namedArguments); ok {
+			return args[:n-1], named
+		}
+	}
+	return args, nil
+}
+
+// bindArguments assigns the arguments of a call to the parameters of
+// function. The value of a parameter that is left out and has a default is
+// nil. With a rest parameter, the leftover positional arguments come last
+// as an array.
+func bindArguments(function *object.Function, args []object.Object) ([]object.Object, *object.Error) {
+	args, named := splitArguments(args)
+	params := function.Parameters
+
+	required := 0
+	for i := range params {
+		if defaultValue(function, i) == nil {
+			required = i + 1
+		}
+	}
+	if len(args) > len(params) && function.Rest == nil || len(args) < required && named == nil {
+		return nil, arityError(function, required, len(args))
+	}
+
+	values := make([]object.Object, len(params))
+	copy(values, args)
+	if named != nil {
+		for _, name := range named.names {
+			i := parameterIndex(function, name)
+			if i < 0 {
+				return nil, createError(object.ARGUMENT_ERROR, "%s has no parameter %s", function.Signature(), name)
+			}
+			if values[i] != nil {
+				return nil, createError(object.ARGUMENT_ERROR, "argument %s of %s given twice", name, function.Signature())
+			}
+			values[i] = named.values[name]
+		}
+	}
+	for i, param := range params {
+		if values[i] == nil && defaultValue(function, i) == nil {
+			return nil, createError(object.ARGUMENT_ERROR, "missing argument %s of %s", param.Value, function.Signature())
+		}
+	}
+
+	if function.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(params) {
+			rest = append(rest, args[len(params):]...)
+		}
+		values = append(values, &object.Array{Elements: rest})
+	}
+	return values, nil
+}
+
+func arityError(function *object.Function, required, got int) *object.Error {
+	signature := function.Signature()
+	switch {
+	case function.Rest != nil:
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want at least %d, got=%d",
+			signature, required, got)
+	case required < len(function.Parameters):
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want %d to %d, got=%d",
+			signature, required, len(function.Parameters), got)
+	default:
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want=%d, got=%d",
+			signature, required, got)
+	}
+}
+
+// bindParameters binds the values from bindArguments in env. Defaults are
+// evaluated in env in the order of the parameters, so they can refer to the
+// parameters before them. It returns an error of a default or nil.
+func (c *Context) bindParameters(function *object.Function, values []object.Object, env *object.Environment) object.Object {
+	for i, param := range function.Parameters {
+		value := values[i]
+		if value == nil {
+			value = c.eval(defaultValue(function, i), env)
+			if isError(value) {
+				return value
+			}
+		}
+		bind(env, param, value)
+	}
+	if function.Rest != nil {
+		bind(env, function.Rest, values[len(values)-1])
+	}
+	return nil
+}
+
+func defaultValue(function *object.Function, i int) ast.Expression {
+	if i < len(function.Defaults) {
+		return function.Defaults[i]
+	}
+	return nil
+}
+
+func parameterIndex(function *object.Function, name string) int {
+	for i, param := range function.Parameters {
+		if param.Value == name {
+			return i
+		}
+	}
+	return -1
+}
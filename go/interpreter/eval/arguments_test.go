@@ -0,0 +1,67 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestParametersAndArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let inc = fn(x, step = 1) { x + step }; [inc(1), inc(1, 5)]`, `[2, 6]`},
+		{`let f = fn(a, b = a * 2) { [a, b] }; f(3)`, `[3, 6]`},
+		{`let n = 10; let f = fn(a = n) { a }; let n = 20; f()`, `20`},
+		{`let f = fn(first, ...others) { [first, others] }; f(1, 2, 3)`, `[1, [2, 3]]`},
+		{`let f = fn(first, ...others) { others }; f(1)`, `[]`},
+		{`let f = fn(...all) { len(all) }; f()`, `0`},
+		{`let plot = fn(f, from = 0, to = 1) { [from, to] }; plot(len, from: -10, to: 10)`, `[-10, 10]`},
+		{`let plot = fn(f, from = 0, to = 1) { [from, to] }; plot(len, to: 5)`, `[0, 5]`},
+		{`let sub = fn(a, b) { a - b }; sub(b: 1, a: 10)`, `9`},
+		{`let f = fn(a, b = 2, ...rest) { [a, b, rest] }; f(1, 3, 4, 5)`, `[1, 3, [4, 5]]`},
+		{`let f = fn(x, by = 1) { x * by }; 3 |> f(by: 4)`, `12`},
+		{`let count = fn(n, acc = 0) { if (n == 0) { acc } else { count(n - 1, acc: acc + 1) } }; count(20000)`, `20000`},
+		{`let f = fn(a, b = 1, ...c) { a }; f`, "fn(a, b = 1, ...c) {\na\n}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let f = fn(a, b) { a }; f(1, 2, 3)`, "wrong number of arguments for f(a, b): want=2, got=3"},
+		{`let f = fn(x, step = 1) { x }; f()`, "wrong number of arguments for f(x, step = 1): want 1 to 2, got=0"},
+		{`let f = fn(x, ...xs) { x }; f()`, "wrong number of arguments for f(x, ...xs): want at least 1, got=0"},
+		{`let f = fn(a, b) { a }; f(1, c: 2)`, "f(a, b) has no parameter c"},
+		{`let f = fn(a, b) { a }; f(1, a: 2)`, "argument a of f(a, b) given twice"},
+		{`let f = fn(a, b) { a }; f(b: 2)`, "missing argument a of f(a, b)"},
+		{`let f = fn(a, ...r) { a }; f(1, r: 2)`, "f(a, ...r) has no parameter r"},
+		{`len(x: 1)`, "len does not take named arguments"},
+		{`fn(a = 1 / 0) { a }()`, "division by zero"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Message != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Message)
+		}
+	}
+
+	errObj := testEval("let f = fn(a = 1 / 0) { a };\nf()").(*object.Error)
+	if got := errObj.Inspect(); got != "ERROR: 1:18: ZeroDivisionError: division by zero\n\tin f called at 2:1" {
+		t.Errorf("wrong error for default. got=%q", got)
+	}
+}
@@ -0,0 +1,97 @@
+package eval
+
+import (
+	"interpreter/object"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+var builtins = map[string]*object.Builtin{
+	"len": {Name: "len", Fn: func(args ...object.Object) object.Object {
+		if len(args) != 1 {
+			return createError(object.ARGUMENT_ERROR, "wrong number of arguments: want=1, got=%d", len(args))
+		}
+		switch arg := args[0].(type) {
+		case *object.String:
+			// characters, like substr and chars count them
+			return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
+		case *object.Array:
+			return &object.Integer{Value: int64(len(arg.Elements))}
+		case *object.Hash:
+			return &object.Integer{Value: int64(len(arg.Pairs))}
+		case *object.Range:
+			n, ok := arg.Len()
+			if !ok {
+				return createError(object.ARGUMENT_ERROR, "length of %s does not fit an integer", arg.Inspect())
+			}
+			return &object.Integer{Value: n}
+		default:
+			return createError(object.TYPE_ERROR, "argument to len not supported, got %s", arg.Type())
+		}
+	}},
+	"split":    {Name: "split", Fn: builtinSplit},
+	"contains": {Name: "contains", Fn: builtinContains},
+	"substr":   {Name: "substr", Fn: builtinSubstr},
+	"chars":    {Name: "chars", Fn: builtinChars},
+	"freeze":   {Name: "freeze", Fn: builtinFreeze},
+}
+
+// builtinFunction is a builtin that needs the context of the evaluation
+// calling it, to call back into the program or to check a limit.
+type builtinFunction func(c *Context, args ...object.Object) object.Object
+
+// contextBuiltins are bound to the context they are looked up in, see
+// Context.builtin.
+var contextBuiltins = map[string]builtinFunction{
+	"join":    (*Context).builtinJoin,
+	"trim":    stringFunction("trim", 1, func(s []string) string { return strings.TrimSpace(s[0]) }),
+	"upper":   stringFunction("upper", 1, func(s []string) string { return strings.ToUpper(s[0]) }),
+	"lower":   stringFunction("lower", 1, func(s []string) string { return strings.ToLower(s[0]) }),
+	"replace": stringFunction("replace", 3, func(s []string) string { return strings.ReplaceAll(s[0], s[1], s[2]) }),
+	"format":  (*Context).builtinFormat,
+	"print":   (*Context).builtinPrint,
+	"println": (*Context).builtinPrintln,
+	"push":    (*Context).builtinPush,
+}
+
+// BuiltinNames returns the names of the builtin functions, which static
+// checks have to treat as bound.
+func BuiltinNames() []string {
+	names := []string{}
+	for name := range builtins {
+		names = append(names, name)
+	}
+	for name := range contextBuiltins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// builtin returns the builtin called name. Builtins that need a context
+// are bound to c, once per name, so they keep their identity.
+func (c *Context) builtin(name string) (*object.Builtin, bool) {
+	if b, ok := builtins[name]; ok {
+		return b, true
+	}
+	fn, ok := contextBuiltins[name]
+	if !ok {
+		return nil, false
+	}
+	if b, ok := c.builtins[name]; ok {
+		return b, true
+	}
+	if c.builtins == nil {
+		c.builtins = make(map[string]*object.Builtin)
+	}
+	b := &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object { return fn(c, args...) }}
+	c.builtins[name] = b
+	return b, true
+}
+
+// Apply calls the function or builtin fn with args in c. Builtins of a host
+// program use it to call back into the program that called them.
+func (c *Context) Apply(fn object.Object, args []object.Object) object.Object {
+	return c.applyFunction(fn, args)
+}
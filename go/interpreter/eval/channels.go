@@ -0,0 +1,377 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"math/rand/v2"
+	"time"
+)
+
+func init() {
+	for name, fn := range map[string]builtinFunction{
+		"chan":  (*Context).builtinChan,
+		"send":  (*Context).builtinSend,
+		"recv":  (*Context).builtinRecv,
+		"after": (*Context).builtinAfter,
+		"close": (*Context).builtinClose,
+	} {
+		contextBuiltins[name] = fn
+	}
+}
+
+// maxChannelSize bounds the buffer of a channel, which is allocated up
+// front.
+const maxChannelSize = 1 << 20
+
+// channelArgument returns the argument i of the builtin name as a channel.
+func channelArgument(name string, i int, args []object.Object) (*object.Channel, *object.Error) {
+	ch, ok := args[i].(*object.Channel)
+	if !ok {
+		return nil, createError(object.TYPE_ERROR, "argument %d to %s must be CHANNEL, got %s", i+1, name, args[i].Type())
+	}
+	return ch, nil
+}
+
+// builtinChan returns a new channel. chan(n) buffers up to n values, chan()
+// none, so each send waits for a receiver.
+func (c *Context) builtinChan(args ...object.Object) object.Object {
+	if len(args) > 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for chan: want=0 or 1, got=%d", len(args))
+	}
+	if len(args) == 0 {
+		return object.NewChannel(0)
+	}
+	n, ok := args[0].(*object.Integer)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to chan must be INTEGER, got %s", args[0].Type())
+	}
+	if n.Value < 0 || n.Value > maxChannelSize {
+		return createError(object.ARGUMENT_ERROR, "channel size must be between 0 and %d, got %d", maxChannelSize, n.Value)
+	}
+	if err := c.checkSize(int(n.Value)); err != nil {
+		return err
+	}
+	return object.NewChannel(int(n.Value))
+}
+
+// builtinSend sends a value on a channel and waits until it is received or
+// buffered.
+func (c *Context) builtinSend(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for send: want=2, got=%d", len(args))
+	}
+	ch, err := channelArgument("send", 0, args)
+	if err != nil {
+		return err
+	}
+	if _, _, _, err := c.selectOp([]channelOp{{ch: ch, value: args[1]}}, true); err != nil {
+		return err
+	}
+	return NULL
+}
+
+// builtinRecv waits for a value from a channel. Once the channel is closed
+// and empty it returns null.
+func (c *Context) builtinRecv(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for recv: want=1, got=%d", len(args))
+	}
+	ch, err := channelArgument("recv", 0, args)
+	if err != nil {
+		return err
+	}
+	value, ok := c.receive(ch)
+	if !ok {
+		return NULL
+	}
+	return value
+}
+
+// builtinClose closes a channel. Values already sent can still be received.
+func (c *Context) builtinClose(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for close: want=1, got=%d", len(args))
+	}
+	ch, err := channelArgument("close", 0, args)
+	if err != nil {
+		return err
+	}
+	s := &c.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch.Closed {
+		return createError(object.CHANNEL_ERROR, "close of closed channel")
+	}
+	s.close(ch)
+	return NULL
+}
+
+// builtinAfter returns a channel that receives null after the given number
+// of milliseconds, for timeouts in select. Until then no deadlock is
+// reported, the channel may still wake a task.
+func (c *Context) builtinAfter(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for after: want=1, got=%d", len(args))
+	}
+	ms, ok := args[0].(*object.Integer)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to after must be INTEGER, got %s", args[0].Type())
+	}
+	ch, s := object.NewChannel(1), &c.scheduler
+	s.mu.Lock()
+	s.timers++
+	s.mu.Unlock()
+	time.AfterFunc(time.Duration(ms.Value)*time.Millisecond, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.send(ch, NULL)
+		s.timers--
+		s.checkDeadlock()
+	})
+	return ch
+}
+
+// channelOp is a send of value on ch, or a receive if value is nil.
+type channelOp struct {
+	ch    *object.Channel
+	value object.Object
+}
+
+// waiter is a goroutine blocked on the operations ops. The goroutine that
+// lets one of them proceed completes the waiter with its result and signals
+// wake.
+type waiter struct {
+	ops  []channelOp
+	wake chan struct{}
+	done bool
+
+	chosen int
+	value  object.Object
+	ok     bool
+	err    *object.Error
+}
+
+// selectOp waits until one of ops can proceed, or, unless block is set,
+// returns -1 if none can right away. value is the value received, ok is
+// false for a send and for a receive from a closed and empty channel. It
+// returns an error if the run is done first or if all tasks wait for each
+// other.
+func (c *Context) selectOp(ops []channelOp, block bool) (chosen int, value object.Object, ok bool, err *object.Error) {
+	s := &c.scheduler
+	s.mu.Lock()
+	// like select in Go, pick one of the operations that can proceed at
+	// random
+	for _, i := range rand.Perm(len(ops)) {
+		if ready, value, ok, err := s.try(ops[i]); ready {
+			s.mu.Unlock()
+			return i, value, ok, err
+		}
+	}
+	if !block {
+		s.mu.Unlock()
+		return -1, nil, false, nil
+	}
+
+	w := &waiter{ops: ops, wake: make(chan struct{}, 1)}
+	for _, op := range ops {
+		s.waiting[op.ch] = append(s.waiting[op.ch], w)
+	}
+	s.blocked++
+	s.checkDeadlock()
+	s.mu.Unlock()
+
+	var done <-chan struct{}
+	if c.ctx != nil {
+		done = c.ctx.Done()
+	}
+	h := c.release()
+	select {
+	case <-w.wake:
+	case <-done:
+	}
+	s.mu.Lock()
+	if !w.done {
+		s.unblock(w)
+	}
+	s.mu.Unlock()
+	c.acquire(h)
+
+	// the tasks ending with a canceled run may leave the others looking
+	// deadlocked, the cancellation is the cause
+	if err := c.canceled(); err != nil {
+		return 0, nil, false, err
+	}
+	if w.err != nil {
+		return 0, nil, false, w.err
+	}
+	return w.chosen, w.value, w.ok, nil
+}
+
+// try performs op if it can proceed right away. mu must be held.
+func (s *scheduler) try(op channelOp) (ready bool, value object.Object, ok bool, err *object.Error) {
+	ch := op.ch
+	if op.value != nil {
+		if ch.Closed {
+			return true, nil, false, createError(object.CHANNEL_ERROR, "send on closed channel")
+		}
+		return s.send(ch, op.value), nil, false, nil
+	}
+
+	select {
+	case value := <-ch.C:
+		// the buffer has room now for the value of a blocked sender
+		if w, i := s.waiter(ch, true); w != nil {
+			ch.C <- w.ops[i].value
+			s.complete(w, i, nil, false)
+		}
+		return true, value, true, nil
+	default:
+	}
+	if w, i := s.waiter(ch, true); w != nil {
+		s.complete(w, i, nil, false)
+		return true, w.ops[i].value, true, nil
+	}
+	return ch.Closed, nil, false, nil
+}
+
+// send hands value to a goroutine blocked receiving from ch or buffers it.
+// It reports false if neither is possible. mu must be held.
+func (s *scheduler) send(ch *object.Channel, value object.Object) bool {
+	if w, i := s.waiter(ch, false); w != nil {
+		s.complete(w, i, value, true)
+		return true
+	}
+	select {
+	case ch.C <- value:
+		return true
+	default:
+		return false
+	}
+}
+
+// close closes ch and completes the goroutines blocked on it: receivers
+// get nothing, senders an error. mu must be held.
+func (s *scheduler) close(ch *object.Channel) {
+	ch.Closed = true
+	for _, w := range s.waiting[ch] {
+		for i, op := range w.ops {
+			if op.ch != ch || w.done {
+				continue
+			}
+			if op.value != nil {
+				w.err = createError(object.CHANNEL_ERROR, "send on closed channel")
+			}
+			s.complete(w, i, nil, false)
+		}
+	}
+}
+
+// waiter returns the first goroutine blocked sending on ch, or receiving
+// from it unless send is set, and the index of its operation. mu must be
+// held.
+func (s *scheduler) waiter(ch *object.Channel, send bool) (*waiter, int) {
+	for _, w := range s.waiting[ch] {
+		for i, op := range w.ops {
+			if op.ch == ch && (op.value != nil) == send {
+				return w, i
+			}
+		}
+	}
+	return nil, 0
+}
+
+// complete ends the wait of w with the result of its operation i. mu must
+// be held.
+func (s *scheduler) complete(w *waiter, i int, value object.Object, ok bool) {
+	w.done, w.chosen, w.value, w.ok = true, i, value, ok
+	s.unblock(w)
+	w.wake <- struct{}{}
+}
+
+// unblock removes w from the waiting goroutines. mu must be held.
+func (s *scheduler) unblock(w *waiter) {
+	for _, op := range w.ops {
+		waiting := s.waiting[op.ch]
+		for i, other := range waiting {
+			if other == w {
+				waiting = append(waiting[:i:i], waiting[i+1:]...)
+				break
+			}
+		}
+		if len(waiting) == 0 {
+			delete(s.waiting, op.ch)
+		} else {
+			s.waiting[op.ch] = waiting
+		}
+	}
+	s.blocked--
+}
+
+// receive waits for the next value of ch. ok is false once ch is closed and
+// empty. An error received from a task is returned as value.
+func (c *Context) receive(ch *object.Channel) (value object.Object, ok bool) {
+	_, value, ok, err := c.selectOp([]channelOp{{ch: ch}}, true)
+	if err != nil {
+		return err, true
+	}
+	return value, ok
+}
+
+// channelIterator receives from ch until it is closed.
+func (c *Context) channelIterator(ch *object.Channel) object.Iterator {
+	return &object.FuncIterator{Name: "chan", Fn: func() (object.Object, bool) {
+		return c.receive(ch)
+	}}
+}
+
+// evalSelectExpression evaluates the channels and values of all cases, then
+// waits for the first operation that can proceed and evaluates the body of
+// its case. If several can, one of them is chosen at random. With a default
+// case it does not wait.
+func (c *Context) evalSelectExpression(exp *ast.SelectExpression, env *object.Environment) object.Object {
+	var ops []channelOp
+	var selected []*ast.SelectCase
+	var fallback *ast.SelectCase
+	for _, sc := range exp.Cases {
+		if sc.Op == nil {
+			fallback = sc
+			continue
+		}
+		args := c.evalExpressions(sc.Op.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		name := sc.Op.Function.(*ast.Identifier).Value
+		ch, err := channelArgument(name, 0, args)
+		if err != nil {
+			return locateError(sc.Op.Arguments[0], err)
+		}
+		op := channelOp{ch: ch}
+		if name == "send" {
+			op.value = args[1]
+		}
+		ops = append(ops, op)
+		selected = append(selected, sc)
+	}
+
+	chosen, value, ok, err := c.selectOp(ops, fallback == nil)
+	if err != nil {
+		return locateError(exp, err)
+	}
+	if chosen < 0 {
+		return c.eval(fallback.Body, object.NewEnclosedEnvironment(env))
+	}
+	if isError(value) {
+		return value
+	}
+
+	sc := selected[chosen]
+	caseEnv := object.NewEnclosedEnvironment(env)
+	if sc.Name != nil && sc.Name.Value != ast.Wildcard {
+		if !ok {
+			value = NULL
+		}
+		bind(caseEnv, sc.Name, value)
+	}
+	return c.eval(sc.Body, caseEnv)
+}
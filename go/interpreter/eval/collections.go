@@ -0,0 +1,184 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+)
+
+func (c *Context) evalArrayLiteral(array *ast.ArrayLiteral, env *object.Environment) object.Object {
+	if err := c.checkSize(len(array.Elements)); err != nil {
+		return err
+	}
+	elements := c.evalExpressions(array.Elements, env)
+	if len(elements) == 1 && isError(elements[0]) {
+		return elements[0]
+	}
+	return &object.Array{Elements: elements}
+}
+
+func (c *Context) evalHashLiteral(hash *ast.HashLiteral, env *object.Environment) object.Object {
+	if err := c.checkSize(len(hash.Keys)); err != nil {
+		return err
+	}
+	result := object.NewHash()
+	for i, keyNode := range hash.Keys {
+		key := c.eval(keyNode, env)
+		if isError(key) {
+			return key
+		}
+		hashKey, ok := key.(object.Hashable)
+		if !ok {
+			return locateError(keyNode, createError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type()))
+		}
+
+		value := c.eval(hash.Values[i], env)
+		if isError(value) {
+			return value
+		}
+		result.Set(hashKey, value)
+	}
+	return result
+}
+
+func evalIndexExpression(left, index object.Object) object.Object {
+	switch {
+	case left.Type() == object.ARRAY_OBJ && index.Type() == object.INTEGER_OBJ:
+		elements := left.(*object.Array).Elements
+		i := index.(*object.Integer).Value
+		if i < 0 || i >= int64(len(elements)) {
+			return NULL
+		}
+		return elements[i]
+	case left.Type() == object.HASH_OBJ:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return createError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+		}
+		pair, ok := left.(*object.Hash).Pairs[key.HashKey()]
+		if !ok {
+			return NULL
+		}
+		return pair.Value
+	default:
+		return createError(object.TYPE_ERROR, "index operator not supported: %s[%s]", left.Type(), index.Type())
+	}
+}
+
+// evalIndexAssignment sets left[index] to the value of valueNode and returns
+// the value.
+func (c *Context) evalIndexAssignment(target *ast.IndexExpression, valueNode ast.Expression, env *object.Environment) object.Object {
+	left := c.eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+	index := c.eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+	value := c.eval(valueNode, env)
+	if isError(value) {
+		return value
+	}
+	if err := c.assignIndex(left, index, value); err != nil {
+		return locateError(target, err)
+	}
+	return value
+}
+
+// assignIndex sets an element of an array, which must exist, or of a hash,
+// which gets a new key if needed. Frozen arrays and hashes cannot be changed.
+func (c *Context) assignIndex(left, index, value object.Object) *object.Error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := index.(*object.Integer)
+		if !ok {
+			return createError(object.TYPE_ERROR, "array index must be INTEGER, got %s", index.Type())
+		}
+		if left.Frozen {
+			return createError(object.FROZEN_ERROR, "cannot assign to an element of a frozen array")
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return createError(object.INDEX_ERROR, "index %d out of range for array of length %d", i.Value, len(left.Elements))
+		}
+		left.Elements[i.Value] = value
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return createError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+		}
+		if left.Frozen {
+			return createError(object.FROZEN_ERROR, "cannot assign to an element of a frozen hash")
+		}
+		if _, ok := left.Pairs[key.HashKey()]; !ok {
+			if err := c.checkSize(len(left.Pairs) + 1); err != nil {
+				return err
+			}
+		}
+		left.Set(key, value)
+	default:
+		return createError(object.TYPE_ERROR, "index assignment not supported: %s[%s]", left.Type(), index.Type())
+	}
+	return nil
+}
+
+// builtinPush returns a new array of the elements of an array followed by
+// the other arguments. Pushing to a frozen array returns a frozen array that
+// shares the elements, see object.Array.Append.
+func (c *Context) builtinPush(args ...object.Object) object.Object {
+	if len(args) < 2 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for push: want at least 2, got=%d", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to push must be ARRAY, got %s", args[0].Type())
+	}
+	if err := c.checkSize(len(array.Elements) + len(args) - 1); err != nil {
+		return err
+	}
+	return array.Append(args[1:]...)
+}
+
+// builtinFreeze returns a frozen copy of an array or hash. Other values are
+// immutable already and returned as they are.
+func builtinFreeze(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for freeze: want=1, got=%d", len(args))
+	}
+	return freeze(args[0], map[object.Object]object.Object{})
+}
+
+// freeze returns obj if it is frozen or no collection, otherwise a frozen
+// copy with all arrays and hashes inside frozen as well. copies maps the
+// collections being frozen to their copies, so a collection that contains
+// itself is copied once.
+func freeze(obj object.Object, copies map[object.Object]object.Object) object.Object {
+	if c, ok := copies[obj]; ok {
+		return c
+	}
+	switch obj := obj.(type) {
+	case *object.Array:
+		if obj.Frozen {
+			return obj
+		}
+		c := &object.Array{Elements: make([]object.Object, len(obj.Elements)), Frozen: true}
+		copies[obj] = c
+		for i, el := range obj.Elements {
+			c.Elements[i] = freeze(el, copies)
+		}
+		return c
+	case *object.Hash:
+		if obj.Frozen {
+			return obj
+		}
+		c := object.NewHash()
+		c.Frozen = true
+		copies[obj] = c
+		for _, key := range obj.Keys {
+			pair := obj.Pairs[key]
+			c.Set(pair.Key.(object.Hashable), freeze(pair.Value, copies))
+		}
+		return c
+	default:
+		return obj
+	}
+}
@@ -0,0 +1,222 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestArrayLiterals(t *testing.T) {
+	evaluated := testEval("[1, 2 * 2, 3 + 3]")
+	result, ok := evaluated.(*object.Array)
+	if !ok {
+		t.Fatalf("object is not Array. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(result.Elements) != 3 {
+		t.Fatalf("array has wrong num of elements. got=%d", len(result.Elements))
+	}
+	testIntegerObject(t, result.Elements[0], 1)
+	testIntegerObject(t, result.Elements[1], 4)
+	testIntegerObject(t, result.Elements[2], 6)
+}
+
+func TestHashLiterals(t *testing.T) {
+	evaluated := testEval(`let two = "two";
+{"one": 10 - 9, two: 1 + 1, "thr" + "ee": 6 / 2, 4: 4, true: 5, false: 6}`)
+	result, ok := evaluated.(*object.Hash)
+	if !ok {
+		t.Fatalf("object is not Hash. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	expected := map[object.HashKey]int64{
+		(&object.String{Value: "one"}).HashKey():   1,
+		(&object.String{Value: "two"}).HashKey():   2,
+		(&object.String{Value: "three"}).HashKey(): 3,
+		(&object.Integer{Value: 4}).HashKey():      4,
+		TRUE.HashKey():                             5,
+		FALSE.HashKey():                            6,
+	}
+	if len(result.Pairs) != len(expected) {
+		t.Fatalf("hash has wrong num of pairs. got=%d", len(result.Pairs))
+	}
+	for key, value := range expected {
+		pair, ok := result.Pairs[key]
+		if !ok {
+			t.Errorf("no pair for given key in pairs")
+		}
+		testIntegerObject(t, pair.Value, value)
+	}
+
+	if got := result.Inspect(); got != `{"one": 1, "two": 2, "three": 3, 4: 4, true: 5, false: 6}` {
+		t.Errorf("hash keeps wrong order. got=%s", got)
+	}
+}
+
+func TestIndexExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3][0]", 1},
+		{"[1, 2, 3][1 + 1]", 3},
+		{"let i = 0; [1][i];", 1},
+		{"let a = [1, 2, 3]; a[0] + a[1] + a[2];", 6},
+		{"[1, 2, 3][3]", nil},
+		{"[1, 2, 3][-1]", nil},
+		{`{"foo": 5}["foo"]`, 5},
+		{`{"foo": 5}["bar"]`, nil},
+		{`let key = "foo"; {"foo": 5}[key]`, 5},
+		{`{}["foo"]`, nil},
+		{`{5: 5}[5]`, 5},
+		{`{true: 5}[true]`, 5},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if integer, ok := tt.expected.(int); ok {
+			testIntegerObject(t, evaluated, int64(integer))
+		} else {
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestBuiltinLen(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`len("")`, 0},
+		{`len("four")`, 4},
+		{`len("héllo")`, 5},
+		{`let s = "héllo"; len(substr(s, 1)) + len(chars(s))`, 9},
+		{`len([1, [2, 3]])`, 2},
+		{`len({"a": 1})`, 1},
+		{`let len = fn(x) { 42 }; len("a")`, 42},
+		{`len(1)`, "argument to len not supported, got INTEGER"},
+		{`len("one", "two")`, "wrong number of arguments: want=1, got=2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestCollectionErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind string
+		expected     string
+	}{
+		{`{[1]: 2}`, object.TYPE_ERROR, "unusable as hash key: ARRAY"},
+		{`{"a": 1}[fn(x) { x }]`, object.TYPE_ERROR, "unusable as hash key: FUNCTION"},
+		{`1[0]`, object.TYPE_ERROR, "index operator not supported: INTEGER[INTEGER]"},
+		{`[1]["a"]`, object.TYPE_ERROR, "index operator not supported: ARRAY[STRING]"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind || errObj.Message != tt.expected {
+			t.Errorf("%s: wrong error. expected=%s: %s, got=%s: %s",
+				tt.input, tt.expectedKind, tt.expected, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2, 3]; a[1] = 5; a", "[1, 5, 3]"},
+		{"let a = [1, 2]; a[0] = a[1] = 7; a", "[7, 7]"},
+		{`let h = {"a": 1}; h["b"] = 2; h["a"] = 3; h`, `{"a": 3, "b": 2}`},
+		{"let m = [[0], [0]]; m[1][0] = 1; m", "[[0], [1]]"},
+		{"let a = [1]; a[0] = 2", "2"},
+		{"let a = [1]; a[0] = a; a", "[[...]]"},
+		{`let a = [1]; a[0] = a; "${a}"`, "[[...]]"},
+		{`let h = {"k": 1}; h["k"] = [h, "s"]; h`, `{"k": [{...}, "s"]}`},
+		{"let a = [1]; [a, a]", "[[1], [1]]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestFreezeAndPush(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = [1, 2]; let b = push(a, 3); a[0] = 0; [a, b]", "[[0, 2], [1, 2, 3]]"},
+		{"push([], 1, 2)", "[1, 2]"},
+		{"freeze([1, [2], {\"a\": [3]}])", `[1, [2], {"a": [3]}]`},
+		{"let a = [1]; let f = freeze(a); a[0] = 2; [a, f]", "[[2], [1]]"},
+		{"let f = freeze([1]); let g = push(f, 2); let h = push(f, 3); [f, g, h, push(g, 4)]",
+			"[[1], [1, 2], [1, 3], [1, 2, 4]]"},
+		{"freeze(1)", "1"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	frozen := testEval("let f = freeze([[1]]); push(f, 2)").(*object.Array)
+	if !frozen.Frozen || !frozen.Elements[0].(*object.Array).Frozen {
+		t.Errorf("push to a frozen array returned a mutable one: %s", frozen.Inspect())
+	}
+	cyclic := testEval("let a = [0]; a[0] = a; freeze(a)").(*object.Array)
+	if cyclic.Elements[0] != cyclic {
+		t.Errorf("freeze broke the cycle: %T", cyclic.Elements[0])
+	}
+}
+
+func TestFrozenErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = freeze([1]);\na[0] = 2", "ERROR: 2:2: FrozenError: cannot assign to an element of a frozen array"},
+		{"let h = freeze({});\nh[\"a\"] = 2", "ERROR: 2:2: FrozenError: cannot assign to an element of a frozen hash"},
+		{"let m = freeze([[1]]);\nm[0][0] = 2", "ERROR: 2:5: FrozenError: cannot assign to an element of a frozen array"},
+		{"let a = [1];\na[1] = 2", "ERROR: 2:2: IndexError: index 1 out of range for array of length 1"},
+		{"let a = [1];\na[\"x\"] = 2", "ERROR: 2:2: TypeError: array index must be INTEGER, got STRING"},
+		{"let s = \"ab\";\ns[0] = 1", "ERROR: 2:2: TypeError: index assignment not supported: STRING[INTEGER]"},
+		{"push(1, 2)", "ERROR: 1:5: TypeError: argument 1 to push must be ARRAY, got INTEGER"},
+		{"push([])", "ERROR: 1:5: ArgumentError: wrong number of arguments for push: want at least 2, got=1"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"interpreter/ast"
+	"interpreter/object"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Capability names a side effect a program may have.
+type Capability string
+
+const (
+	// CapImport allows reading modules from the file system.
+	CapImport Capability = "import"
+	// CapOutput allows print and println to write to the output.
+	CapOutput Capability = "output"
+)
+
+// AllCapabilities returns a new set with every capability.
+func AllCapabilities() map[Capability]bool {
+	return map[Capability]bool{CapImport: true, CapOutput: true}
+}
+
+// Limits bound the resources of an evaluation. A zero field means no limit,
+// except for MaxDepth, where it means DefaultMaxDepth.
+type Limits struct {
+	// MaxSteps is the number of nodes that may be evaluated.
+	MaxSteps int64
+	Timeout  time.Duration
+	// MaxDepth is the number of nested function calls. Tail calls do not count.
+	MaxDepth int
+	// MaxSize is the largest size of a value the program may build: the
+	// bytes of a string or the elements of an array, a hash or the buffer
+	// of a channel.
+	MaxSize int
+}
+
+// Context holds the state of an evaluation: the hooks installed by tools
+// like the debugger and the limits of a sandboxed run. It is passed along
+// the whole evaluation, so runs with different contexts are independent.
+// A context runs one evaluation at a time; the tasks a program spawns take
+// turns with it, see turn.
+type Context struct {
+	Tracer Tracer
+	Loader *Loader
+	Limits Limits
+	// Capabilities are the side effects the program may have. nil allows all.
+	Capabilities map[Capability]bool
+	// Output receives what print and println write. nil discards it.
+	Output io.Writer
+
+	ctx   context.Context
+	steps int64
+	// running is set while Run evaluates.
+	running bool
+	// depth and generator belong to the goroutine holding the turn and are
+	// swapped when it is passed on. generator is the generator whose body
+	// is running, the one a yield hands its value to.
+	depth     int
+	generator *generatorState
+	// tasks counts the tasks spawned by the program that are still running.
+	tasks     *sync.WaitGroup
+	scheduler scheduler
+	// generators holds the generators created during the run that have not
+	// finished.
+	generators map[*generatorState]bool
+	// builtins holds the builtins bound to c.
+	builtins map[string]*object.Builtin
+}
+
+// NewContext returns a context without limits that allows all capabilities
+// and writes to os.Stdout.
+func NewContext() *Context {
+	return &Context{Loader: NewLoader("", nil), Output: os.Stdout, scheduler: newScheduler()}
+}
+
+// Run evaluates node in env within the limits of c. Cancelling ctx stops the
+// evaluation with a CanceledError. The limits apply to the whole run,
+// including the tasks spawned by the program, which are stopped when it
+// ends, as are the generators it created.
+//
+// Run called while c is running, e.g. to expand the macros of an imported
+// module, evaluates node as part of the outer run and ignores ctx.
+func (c *Context) Run(ctx context.Context, node ast.Node, env *object.Environment) object.Object {
+	if c.running {
+		return c.eval(node, env)
+	}
+	var cancel context.CancelFunc
+	if c.Limits.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Limits.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	if c.Loader == nil {
+		c.Loader = NewLoader("", nil)
+	}
+	if c.scheduler.turn == nil {
+		c.scheduler = newScheduler()
+	}
+	c.ctx, c.steps, c.depth, c.running = ctx, 0, 0, true
+
+	defer func() {
+		c.running = false
+		cancel()
+		c.stopTasks()
+		c.stopGenerators()
+	}()
+
+	return c.eval(node, env)
+}
+
+// Steps returns the number of nodes evaluated by the last run.
+func (c *Context) Steps() int64 {
+	return c.steps
+}
+
+// checkInterval is the number of steps between two checks of the context,
+// which are too expensive to do on every node.
+const checkInterval = 256
+
+// step counts the evaluation of one node and reports an error once a limit
+// is exceeded. With tasks running it also passes the turn on from time to
+// time, so a task that never blocks does not starve the others.
+func (c *Context) step() *object.Error {
+	c.steps++
+	if c.Limits.MaxSteps > 0 && c.steps > c.Limits.MaxSteps {
+		return createError(object.STEP_LIMIT_ERROR, "step limit of %d exceeded", c.Limits.MaxSteps)
+	}
+	if c.steps%checkInterval != 0 {
+		return nil
+	}
+	if c.tasks != nil {
+		c.pass()
+	}
+	return c.canceled()
+}
+
+// canceled reports an error once the context of the run is done.
+func (c *Context) canceled() *object.Error {
+	if c.ctx == nil {
+		return nil
+	}
+	switch err := c.ctx.Err(); {
+	case errors.Is(err, context.DeadlineExceeded):
+		return createError(object.TIMEOUT_ERROR, "evaluation timed out")
+	case err != nil:
+		return createError(object.CANCELED_ERROR, "evaluation canceled")
+	}
+	return nil
+}
+
+func (c *Context) maxDepth() int {
+	if c.Limits.MaxDepth > 0 {
+		return c.Limits.MaxDepth
+	}
+	return DefaultMaxDepth
+}
+
+// checkSize reports an error if a value of size n is too large.
+func (c *Context) checkSize(n int) *object.Error {
+	if c.Limits.MaxSize > 0 && n > c.Limits.MaxSize {
+		return createError(object.SIZE_LIMIT_ERROR, "size limit of %d exceeded", c.Limits.MaxSize)
+	}
+	return nil
+}
+
+// allow reports an error if the program may not have the side effect cap.
+func (c *Context) allow(cap Capability) *object.Error {
+	if c.Capabilities == nil || c.Capabilities[cap] {
+		return nil
+	}
+	return createError(object.PERMISSION_ERROR, "permission denied: %s", cap)
+}
+
+// isLimitError reports whether err stops the evaluation for good. Such
+// errors cannot be caught, otherwise a sandboxed program could ignore them.
+func isLimitError(err *object.Error) bool {
+	switch err.Kind {
+	case object.STEP_LIMIT_ERROR, object.TIMEOUT_ERROR, object.CANCELED_ERROR:
+		return true
+	default:
+		return false
+	}
+}
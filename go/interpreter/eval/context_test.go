@@ -0,0 +1,99 @@
+package eval
+
+import (
+	"context"
+	"interpreter/ast"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"testing"
+	"time"
+)
+
+const infiniteLoop = `let loop = fn(n) { loop(n + 1) }; loop(0)`
+
+func TestContextLimits(t *testing.T) {
+	tests := []struct {
+		name         string
+		input        string
+		limits       Limits
+		capabilities map[Capability]bool
+		expectedKind string
+	}{
+		{"steps", infiniteLoop, Limits{MaxSteps: 1000}, nil, object.STEP_LIMIT_ERROR},
+		{"steps in try", `try { ` + infiniteLoop + ` } catch { 1 }`, Limits{MaxSteps: 1000}, nil, object.STEP_LIMIT_ERROR},
+		{"timeout", infiniteLoop, Limits{Timeout: 20 * time.Millisecond}, nil, object.TIMEOUT_ERROR},
+		{"depth", `let f = fn(n) { 1 + f(n) }; f(0)`, Limits{MaxDepth: 10}, nil, object.STACK_OVERFLOW_ERROR},
+		{
+			"size",
+			`let grow = fn(s) { grow(s + s) }; grow("ab")`,
+			Limits{MaxSize: 1024},
+			nil,
+			object.SIZE_LIMIT_ERROR,
+		},
+		{"size of range", `collect(range(0, 9223372036854775807, 1024))`, Limits{MaxSize: 1024}, nil, object.SIZE_LIMIT_ERROR},
+		{"import", `import "lib"`, Limits{}, map[Capability]bool{}, object.PERMISSION_ERROR},
+		{"output in match arm", `let x = match (1) { _ => print("leak") }`, Limits{}, map[Capability]bool{}, object.PERMISSION_ERROR},
+		{"steps in match arm", `let loop = fn(n) { loop(n + 1) }; let x = match (1) { _ => loop(0) }; x`, Limits{MaxSteps: 1000}, nil, object.STEP_LIMIT_ERROR},
+	}
+
+	for _, tt := range tests {
+		c := NewContext()
+		c.Limits = tt.limits
+		c.Capabilities = tt.capabilities
+
+		evaluated := c.Run(context.Background(), parseProgram(t, tt.input), object.NewEnvironment())
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned. got=%T(%+v)", tt.name, evaluated, evaluated)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("%s: wrong error kind. expected=%q, got=%q (%s)",
+				tt.name, tt.expectedKind, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		cancel()
+	}()
+
+	evaluated := NewContext().Run(ctx, parseProgram(t, infiniteLoop), object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Kind != object.CANCELED_ERROR {
+		t.Fatalf("evaluation not canceled. got=%+v", evaluated)
+	}
+}
+
+func TestContextWithinLimits(t *testing.T) {
+	c := NewContext()
+	c.Limits = Limits{MaxSteps: 1000, Timeout: time.Second, MaxDepth: 10, MaxSize: 10}
+	c.Capabilities = map[Capability]bool{CapImport: true}
+
+	input := `let s = "ab" + "cd"; try { s + s + s } catch (e) { e.kind }`
+	evaluated := c.Run(context.Background(), parseProgram(t, input), object.NewEnvironment())
+	str, ok := evaluated.(*object.String)
+	if !ok || str.Value != object.SIZE_LIMIT_ERROR {
+		t.Errorf("size limit error not caught. got=%+v", evaluated)
+	}
+	if c.Steps() == 0 || c.Steps() > 1000 {
+		t.Errorf("wrong number of steps. got=%d", c.Steps())
+	}
+
+	// the limits of a run do not apply to later evaluations
+	testIntegerObject(t, testEval(`let f = fn(n) { if (n == 0) { 0 } else { 1 + f(n - 1) } }; f(100)`), 100)
+}
+
+func parseProgram(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
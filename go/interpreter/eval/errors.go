@@ -0,0 +1,88 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"strings"
+)
+
+func (c *Context) evalThrowExpression(exp *ast.ThrowExpression, env *object.Environment) object.Object {
+	val := c.eval(exp.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	switch val := val.(type) {
+	case *object.ErrorValue:
+		return val.Err
+	case *object.String:
+		return &object.Error{Kind: object.THROWN_ERROR, Message: val.Value, Value: val}
+	default:
+		return &object.Error{Kind: object.THROWN_ERROR, Message: val.Inspect(), Value: val}
+	}
+}
+
+// evalTryExpression evaluates the handler if the body ends in an error. The
+// handler runs in an environment of its own, where the parameter of the
+// catch is bound to the error, so it does not outlive the handler.
+func (c *Context) evalTryExpression(exp *ast.TryExpression, env *object.Environment) object.Object {
+	result := c.eval(exp.Body, env)
+	err, ok := result.(*object.Error)
+	if !ok || isLimitError(err) {
+		return result
+	}
+
+	handlerEnv := object.NewEnclosedEnvironment(env)
+	if exp.Param != nil {
+		bind(handlerEnv, exp.Param, &object.ErrorValue{Err: err})
+	}
+	return c.eval(exp.Handler, handlerEnv)
+}
+
+// traceCall puts the position of call on the frame applyFunction added to
+// an error that left the called function.
+func traceCall(call *ast.CallExpression, result object.Object) object.Object {
+	return traceAt(call.Function, result)
+}
+
+// traceAt puts the position of the called expression function on the frame
+// applyFunction added to an error.
+func traceAt(function ast.Expression, result object.Object) object.Object {
+	err, ok := result.(*object.Error)
+	if !ok || len(err.Trace) == 0 {
+		return result
+	}
+	frame := &err.Trace[len(err.Trace)-1]
+	if frame.Line == 0 {
+		span := ast.NodeSpan(function)
+		frame.Line, frame.Column = span.StartLine, span.StartColumn
+	}
+	return result
+}
+
+// errorField returns the field name of a caught error.
+func errorField(err *object.Error, name string) object.Object {
+	switch name {
+	case "message":
+		return &object.String{Value: err.Message}
+	case "kind":
+		return &object.String{Value: err.Kind}
+	case "value":
+		if err.Value == nil {
+			return NULL
+		}
+		return err.Value
+	case "line":
+		return &object.Integer{Value: int64(err.Line)}
+	case "column":
+		return &object.Integer{Value: int64(err.Column)}
+	case "trace":
+		lines := []string{}
+		for _, f := range err.Trace {
+			lines = append(lines, f.String())
+		}
+		return &object.String{Value: strings.Join(lines, "\n")}
+	default:
+		return createError(object.NAME_ERROR, "error has no field %s", name)
+	}
+}
@@ -0,0 +1,83 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw 1; 2 } catch (e) { 3 }`, 3},
+		{`try { throw 42 } catch (e) { e.value }`, 42},
+		{`try { throw "boom" } catch (e) { e.message }`, "boom"},
+		{`try { throw "boom" } catch (e) { e.kind }`, "Error"},
+		{`try { 1 / 0 } catch (e) { e.kind }`, "ZeroDivisionError"},
+		{`try { 1 / 0 } catch (e) { e.message }`, "division by zero"},
+		{`try { 1 + true } catch (e) { e.kind }`, "TypeError"},
+		{`try { fn(a) { a }() } catch (e) { e.kind }`, "ArgumentError"},
+		{`try { 1 + true } catch (e) { e.value }`, nil},
+		{`try {
+  1 + true
+} catch (e) { e.line }`, 2},
+		{`try { 1 + true } catch { 5 }`, 5},
+		{`let f = fn() { throw 7 }; try { f() } catch (e) { e.value }`, 7},
+		{`let f = fn() { try { return 1; } catch { 2 }; 3 }; f()`, 1},
+		{`try { try { throw 1 } catch (e) { throw e } } catch (e) { e.value }`, 1},
+		{`try { try { throw 1 } catch (e) { throw 2 } } catch (e) { e.value }`, 2},
+		{`let e = 1; try { throw 2 } catch (e) { e.value }; e`, 1},
+		{`let f = try { throw 3 } catch (e) { fn() { e.value } }; f()`, 3},
+		{`try { throw 1 } catch (e) { let x = 4; x }`, 4},
+		{`let f = fn() { 1 / 0 }; let g = fn() { let r = f(); r }; try { g() } catch (e) { e.trace }`,
+			"f called at 1:48\ng called at 1:64"},
+		// g calls f in tail position, so g is no longer on the stack
+		{`let f = fn() { 1 / 0 }; let g = fn() { f() }; try { g() } catch (e) { e.trace }`,
+			"f called at 1:40"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String for %q. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value for %q. got=%q, want=%q", tt.input, str.Value, expected)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "boom"`, "ERROR: 1:1: Error: boom"},
+		{`throw 1 + 1`, "ERROR: 1:1: Error: 2"},
+		{`try { 1 } catch (e) { 2 }; throw true`, "ERROR: 1:28: Error: true"},
+		{
+			"let f = fn(x) { x + true };\nlet g = fn() { f(1) + 1 };\ng()",
+			"ERROR: 1:19: TypeError: type mismatch: INTEGER + BOOLEAN\n\tin f called at 2:16\n\tin g called at 3:1",
+		},
+		{"fn() { throw 1 }()", "ERROR: 1:8: Error: 1\n\tin fn called at 1:1"},
+		{`try { 1 + true } catch (e) { e.nope }`, "ERROR: 1:31: NameError: error has no field nope"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q.\nexpected=%q\ngot=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
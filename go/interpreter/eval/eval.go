@@ -1,6 +1,7 @@
 package eval
 
 import (
+	"context"
 	"fmt"
 	"interpreter/ast"
 	"interpreter/object"
@@ -12,56 +13,191 @@ var (
 	NULL  = &object.Null{}
 )
 
-func Eval(node ast.Node) object.Object {
+// Tracer is notified before and after every node evaluation. Enter may block,
+// which pauses the evaluation until it returns.
+type Tracer interface {
+	Enter(node ast.Node, env *object.Environment)
+	Leave(node ast.Node, result object.Object)
+}
+
+// Eval evaluates node in env with a new context without limits. Use
+// Context.Run to install a tracer, a loader or limits.
+func Eval(node ast.Node, env *object.Environment) object.Object {
+	return NewContext().Run(context.Background(), node, env)
+}
+
+func (c *Context) eval(node ast.Node, env *object.Environment) object.Object {
+	return c.evalNode(node, env, c.evaluate)
+}
+
+// evalNode counts the step, notifies the tracer and evaluates node with f.
+func (c *Context) evalNode(node ast.Node, env *object.Environment, f func(ast.Node, *object.Environment) object.Object) object.Object {
+	if err := c.step(); err != nil {
+		return locateError(node, err)
+	}
+	if c.Tracer == nil {
+		return locateError(node, f(node, env))
+	}
+	c.Tracer.Enter(node, env)
+	result := locateError(node, f(node, env))
+	c.Tracer.Leave(node, result)
+	return result
+}
+
+// locateError attaches the position of node to an error that has none yet,
+// so every error points at the innermost node it came from.
+func locateError(node ast.Node, result object.Object) object.Object {
+	if err, ok := result.(*object.Error); ok && err.Line == 0 {
+		err.Line, err.Column = ast.Pos(node)
+	}
+	return result
+}
+
+func (c *Context) evaluate(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node)
+		return c.evalProgram(node, env)
 
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression)
+		return c.eval(node.Expression, env)
 
 	case *ast.PrefixExpression:
-		right := Eval(node.Right)
+		right := c.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
+		if result, ok := c.evalPrefixHook(node, right); ok {
+			return result
+		}
 		return evalPrefixExpression(node.Operator, right)
 
 	case *ast.InfixExpression:
-		left := Eval(node.Left)
+		left := c.eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.Right)
+		right := c.eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
-		return evalInfixExpression(node.Operator, left, right)
+		if result, ok := c.evalInfixHook(node, left, right); ok {
+			return result
+		}
+		return c.evalInfixExpression(node.Operator, left, right)
 
 	case *ast.BlockStatement:
-		return evalBlockStatement(node)
+		return c.evalBlockStatement(node, env)
 
 	case *ast.IfExpression:
-		ifCond := Eval(node.Condition)
+		ifCond := c.eval(node.Condition, env)
 		if isError(ifCond) {
 			return ifCond
 		}
 
 		if isTruthy(ifCond) {
-			return Eval(node.Consequence)
+			return c.eval(node.Consequence, env)
 		} else if node.Alternative != nil {
-			return Eval(node.Alternative)
+			return c.eval(node.Alternative, env)
 		}
 
 		return NULL
 
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue)
+		val := c.eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.LetStatement:
+		return c.evalLetStatement(node, env)
+
+	case *ast.ImportStatement:
+		return c.evalImportStatement(node, env)
+
+	case *ast.StructStatement:
+		bind(env, node.Name, &object.RecordType{Name: node.Name.Value, Fields: node.Fields})
+
+	case *ast.AssignExpression:
+		return c.evalAssignExpression(node, env)
+
+	case *ast.MethodDeclaration:
+		return c.evalMethodDeclaration(node, env)
+
+	case *ast.RangeExpression:
+		return c.evalRangeExpression(node, env)
+
+	case *ast.ForExpression:
+		return c.evalForExpression(node, env)
+
+	case *ast.SelectorExpression:
+		return c.evalSelectorExpression(node, env)
+
+	case *ast.Identifier:
+		return c.evalIdentifier(node, env)
+
+	case *ast.ArrayLiteral:
+		return c.evalArrayLiteral(node, env)
+
+	case *ast.HashLiteral:
+		return c.evalHashLiteral(node, env)
+
+	case *ast.IndexExpression:
+		left := c.eval(node.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := c.eval(node.Index, env)
+		if isError(index) {
+			return index
+		}
+		return evalIndexExpression(left, index)
+
+	case *ast.FunctionLiteral:
+		return &object.Function{Name: node.Name, Parameters: node.Parameters, Defaults: node.Defaults,
+			Rest: node.Rest, Body: node.Body, Env: env, Generator: node.Generator}
+
+	case *ast.YieldExpression:
+		return c.evalYieldExpression(node, env)
+
+	case *ast.SpawnExpression:
+		return c.evalSpawnExpression(node, env)
+
+	case *ast.SelectExpression:
+		return c.evalSelectExpression(node, env)
+
+	case *ast.ThrowExpression:
+		return c.evalThrowExpression(node, env)
+
+	case *ast.TryExpression:
+		return c.evalTryExpression(node, env)
+
+	case *ast.MacroLiteral:
+		return createError(object.SYNTAX_ERROR, "macro literal outside of a top-level let")
+
+	case *ast.PipeExpression:
+		return c.evalPipeExpression(node, env)
+
+	case *ast.MatchExpression:
+		return c.evalMatchExpression(node, env, c.eval)
+
+	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			if len(node.Arguments) != 1 {
+				return createError(object.ARGUMENT_ERROR, "wrong number of arguments for quote: want=1, got=%d", len(node.Arguments))
+			}
+			return c.quote(node.Arguments[0], env)
+		}
+		function := c.eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := c.evalArguments(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return traceCall(node, c.applyFunction(function, args))
+
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 
@@ -70,6 +206,12 @@ func Eval(node ast.Node) object.Object {
 
 	case *ast.FloatLiteral:
 		return &object.Float{Value: node.Value}
+
+	case *ast.StringLiteral:
+		return &object.String{Value: node.Value}
+
+	case *ast.InterpolatedString:
+		return c.evalInterpolatedString(node, env)
 	}
 
 	return NULL
@@ -88,18 +230,41 @@ func isTruthy(ifCond object.Object) bool {
 	}
 }
 
-func evalInfixExpression(operator string, left, right object.Object) object.Object {
+func (c *Context) evalInfixExpression(operator string, left, right object.Object) object.Object {
 	switch {
 	case isNumber(left) && isNumber(right):
 		return evalNumberInfixExpression(operator, left, right)
+	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
+		return c.evalStringInfixExpression(operator, left, right)
+	case left.Type() == object.RECORD_OBJ && right.Type() == object.RECORD_OBJ && (operator == "==" || operator == "!="):
+		return getNativeBooleanObject(equalObjects(left, right) == (operator == "=="))
 	case operator == "==":
 		return getNativeBooleanObject(left == right)
 	case operator == "!=":
 		return getNativeBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return createError("type mismatch: %s %s %s", left.Type(), operator, right.Type())
+		return createError(object.TYPE_ERROR, "type mismatch: %s %s %s", left.Type(), operator, right.Type())
+	default:
+		return createError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func (c *Context) evalStringInfixExpression(operator string, left, right object.Object) object.Object {
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		if err := c.checkSize(len(leftVal) + len(rightVal)); err != nil {
+			return err
+		}
+		return &object.String{Value: leftVal + rightVal}
+	case "==":
+		return getNativeBooleanObject(leftVal == rightVal)
+	case "!=":
+		return getNativeBooleanObject(leftVal != rightVal)
 	default:
-		return createError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+		return createError(object.TYPE_ERROR, "unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
@@ -158,7 +323,7 @@ func evalFloatInfix(operator string, leftVal, rightVal float64) object.Object {
 	case "/":
 		return &object.Float{Value: leftVal / rightVal}
 	default:
-		return createError("unknown operator: %f %s %f", leftVal, operator, rightVal)
+		return createError(object.TYPE_ERROR, "unknown operator: %f %s %f", leftVal, operator, rightVal)
 	}
 }
 
@@ -171,9 +336,12 @@ func evalIntInfix(operator string, leftVal, rightVal int64) object.Object {
 	case "*":
 		return &object.Integer{Value: leftVal * rightVal}
 	case "/":
+		if rightVal == 0 {
+			return createError(object.ZERO_DIVISION_ERROR, "division by zero")
+		}
 		return &object.Integer{Value: leftVal / rightVal}
 	default:
-		return createError("unknown operator: %d %s %d", leftVal, operator, rightVal)
+		return createError(object.TYPE_ERROR, "unknown operator: %d %s %d", leftVal, operator, rightVal)
 	}
 }
 
@@ -184,7 +352,7 @@ func evalPrefixExpression(operator string, right object.Object) object.Object {
 	case "-":
 		return evalMinusOperatorExpression(right)
 	default:
-		return createError("unknown operator: %s%s", operator, right.Type())
+		return createError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
@@ -201,19 +369,17 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
+// evalMinusOperatorExpression negates right into a new object. The operand
+// may be bound to a name or shared by tasks, so it is never changed.
 func evalMinusOperatorExpression(right object.Object) object.Object {
 	switch right := right.(type) {
 	case *object.Float:
-		right.Value = right.Value * -1
-		return right
+		return &object.Float{Value: -right.Value}
 	case *object.Integer:
-		right.Value = right.Value * -1
-		return right
+		return &object.Integer{Value: -right.Value}
 	default:
-		return createError("unknown operator: -%s", right.Type())
+		return createError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
-
-	return NULL
 }
 
 func getNativeBooleanObject(b bool) *object.Boolean {
@@ -230,11 +396,69 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalBlockStatement(block *ast.BlockStatement) object.Object {
+// evalLetStatement binds the names of a let or const statement. A name
+// bound by a const cannot be bound again in the same scope.
+func (c *Context) evalLetStatement(node *ast.LetStatement, env *object.Environment) object.Object {
+	names := node.Names()
+	for _, name := range names {
+		if env.IsConst(name.Value) {
+			return locateError(name, createError(object.NAME_ERROR, "cannot reassign constant %s", name.Value))
+		}
+	}
+
+	val := c.eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+	if node.Pattern != nil {
+		if err := c.destructure(node.Pattern, val, env); err != nil {
+			return err
+		}
+	} else {
+		bind(env, node.Name, val)
+	}
+	if node.Const() {
+		for _, name := range names {
+			env.SetConst(name.Value)
+		}
+	}
+	return NULL
+}
+
+// evalIdentifier looks a name up in the slot the resolver found for it and
+// then by name, which also finds the builtins.
+func (c *Context) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	if node.Resolved {
+		if val, ok := env.GetAt(node.Depth, node.Index, node.Value); ok {
+			return val
+		}
+	}
+	if val, ok := env.Get(node.Value); ok {
+		return val
+	}
+	if builtin, ok := c.builtin(node.Value); ok {
+		return builtin
+	}
+	return locateError(node, createError(object.NAME_ERROR, "undefined identifier: %s", node.Value))
+}
+
+// bind binds the name ident declares in env, in its slot if the resolver
+// gave it one. The global scope is only bound by name: the REPL and host
+// programs add to it between runs, so its slots would differ from run to
+// run.
+func bind(env *object.Environment, ident *ast.Identifier, value object.Object) {
+	if ident.Resolved && ident.Depth == 0 && env.Outer() != nil {
+		env.SetAt(ident.Index, ident.Value, value)
+	} else {
+		env.Set(ident.Value, value)
+	}
+}
+
+func (c *Context) evalBlockStatement(block *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 
 	for _, statement := range block.Statements {
-		result = Eval(statement)
+		result = c.eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
@@ -247,10 +471,10 @@ func evalBlockStatement(block *ast.BlockStatement) object.Object {
 	return result
 }
 
-func evalProgram(program *ast.Program) object.Object {
+func (c *Context) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range program.Statements {
-		result = Eval(statement)
+		result = c.eval(statement, env)
 
 		// check for ReturnValue or else last Statement will be result
 		switch result := result.(type) {
@@ -263,6 +487,98 @@ func evalProgram(program *ast.Program) object.Object {
 	return result
 }
 
-func createError(format string, a ...any) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func (c *Context) evalExpressions(exps []ast.Expression, env *object.Environment) []object.Object {
+	var result []object.Object
+
+	for _, e := range exps {
+		evaluated := c.eval(e, env)
+		if isError(evaluated) {
+			return []object.Object{evaluated}
+		}
+		result = append(result, evaluated)
+	}
+
+	return result
+}
+
+// applyFunction calls fn with args. Calls in tail position of the body come
+// back as a tailCall and are run by the loop here, so a chain of tail calls
+// needs no Go stack.
+func (c *Context) applyFunction(fn object.Object, args []object.Object) object.Object {
+	if c.depth >= c.maxDepth() {
+		return createError(object.STACK_OVERFLOW_ERROR, "stack overflow: more than %d nested calls", c.maxDepth())
+	}
+	c.depth++
+	defer func() { c.depth-- }()
+
+	var call *ast.CallExpression
+	for {
+		result := c.callFunction(fn, args)
+		tail, ok := result.(*tailCall)
+		if !ok {
+			if call != nil {
+				result = traceCall(call, locateError(call, result))
+			}
+			return result
+		}
+		fn, args, call = tail.function, tail.args, tail.call
+	}
+}
+
+func (c *Context) callFunction(fn object.Object, args []object.Object) object.Object {
+	if method, ok := fn.(*object.BoundMethod); ok {
+		fn, args = method.Method, append([]object.Object{method.Receiver}, args...)
+	}
+	if builtin, ok := fn.(*object.Builtin); ok {
+		if _, named := splitArguments(args); named != nil {
+			return createError(object.ARGUMENT_ERROR, "%s does not take named arguments", builtin.Name)
+		}
+		return builtin.Fn(args...)
+	}
+	if rt, ok := fn.(*object.RecordType); ok {
+		return construct(rt, args)
+	}
+	function, ok := fn.(*object.Function)
+	if !ok {
+		return createError(object.TYPE_ERROR, "not a function: %s", fn.Type())
+	}
+	values, err := bindArguments(function, args)
+	if err != nil {
+		return err
+	}
+
+	extendedEnv := object.NewEnclosedEnvironment(function.Env)
+	evaluated := c.bindParameters(function, values, extendedEnv)
+	if evaluated == nil && function.Generator {
+		return c.newGenerator(function, extendedEnv)
+	}
+	if evaluated == nil {
+		evaluated = c.evalTail(function.Body, extendedEnv)
+	}
+	if err, ok := evaluated.(*object.Error); ok {
+		name := function.Name
+		if name == "" {
+			name = "fn"
+		}
+		err.Trace = append(err.Trace, object.Frame{Function: name})
+	}
+	if _, ok := evaluated.(*tailCall); ok {
+		return evaluated
+	}
+	return unwrapReturnValue(evaluated)
+}
+
+// unwrapReturnValue stops a return from bubbling up past the function call.
+func unwrapReturnValue(obj object.Object) object.Object {
+	if obj == nil {
+		return NULL
+	}
+	if returnValue, ok := obj.(*object.ReturnValue); ok {
+		return returnValue.Value
+	}
+	return obj
+}
+
+func createError(kind, format string, a ...any) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
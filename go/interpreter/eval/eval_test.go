@@ -1,9 +1,12 @@
 package eval
 
 import (
+	"interpreter/ast"
+	"interpreter/diag"
 	"interpreter/lexer"
 	"interpreter/object"
 	"interpreter/parser"
+	"interpreter/resolve"
 	"testing"
 )
 
@@ -27,6 +30,8 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"let x = 5; -x; x", 5},
+		{"let x = 5; -(-x) + x", 10},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -92,19 +97,6 @@ func TestEvalBooleanExpression(t *testing.T) {
 	}
 }
 
-func TestEvalNullExpression(t *testing.T) {
-	tests := []struct {
-		input string
-	}{
-		{"fn (a) { a; }"},
-		{"ahoi"},
-	}
-	for _, tt := range tests {
-		evaluated := testEval(tt.input)
-		testNullObject(t, evaluated)
-	}
-}
-
 func TestBangOperator(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -202,13 +194,163 @@ func TestReturnStatements(t *testing.T) {
 	}
 }
 
+func TestLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a;", 5},
+		{"let a = 5 * 5; a;", 25},
+		{"let a = 5; let b = a; b;", 5},
+		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestConstStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"const a = 5; a;", 5},
+		{"const [a, b] = [1, 2]; a + b;", 3},
+		{"const a = 1; let f = fn() { let a = 2; a }; f();", 2},
+		{"let a = 1; const a = 2; a;", 2},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{"const a = 1;\nlet a = 2;", "ERROR: 2:5: NameError: cannot reassign constant a"},
+		{"const a = 1;\nconst a = 1;", "ERROR: 2:7: NameError: cannot reassign constant a"},
+		{"const {\"x\": x} = {\"x\": 1};\nlet [y, x] = [1, 2];", "ERROR: 2:9: NameError: cannot reassign constant x"},
+	}
+	for _, tt := range errors {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestFunctionObject(t *testing.T) {
+	input := "fn(x) { x + 2; };"
+
+	evaluated := testEval(input)
+	fn, ok := evaluated.(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+	}
+	if len(fn.Parameters) != 1 {
+		t.Fatalf("function has wrong parameters. Parameters=%+v", fn.Parameters)
+	}
+	if fn.Parameters[0].String() != "x" {
+		t.Fatalf("parameter is not 'x'. got=%q", fn.Parameters[0])
+	}
+	if fn.Body.String() != "(x + 2)" {
+		t.Fatalf("body is not %q. got=%q", "(x + 2)", fn.Body.String())
+	}
+}
+
+func TestFunctionApplication(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let identity = fn(x) { x; }; identity(5);", 5},
+		{"let identity = fn(x) { return x; }; identity(5);", 5},
+		{"let double = fn(x) { x * 2; }; double(5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
+		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
+		{"fn(x) { x; }(5)", 5},
+		{"let add = fn(a: int, b: int) -> int { a + b }; let x: int = add(2, 3); x;", 5},
+		{"let newAdder = fn(x) { fn(y) { x + y }; }; let addTwo = newAdder(2); addTwo(2);", 4},
+	}
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
 // HELPER
 
+func TestStringExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`"Hello World!"`, "Hello World!"},
+		{`"Hello" + " " + "World!"`, "Hello World!"},
+		{`"a" == "a"`, true},
+		{`"a" != "a"`, false},
+		{`"a" == "b"`, false},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q, want=%q", str.Value, expected)
+			}
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		}
+	}
+
+	evaluated := testEval(`"Hello" - "World"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok || errObj.Message != "unknown operator: STRING - STRING" {
+		t.Errorf("wrong result for string subtraction. got=%+v", evaluated)
+	}
+}
+
+func TestSlots(t *testing.T) {
+	closure, ok := testEval("let f = fn(a, b) { let c = a + b; fn() { c } }; f(2, 3)").(*object.Function)
+	if !ok {
+		t.Fatalf("object is not Function. got=%T", closure)
+	}
+	if value, ok := closure.Env.GetAt(0, 2, "c"); !ok || value.Inspect() != "5" {
+		t.Errorf("c not bound in slot 2. got=%v, %t", value, ok)
+	}
+	if value, ok := closure.Env.Get("a"); !ok || value.Inspect() != "2" {
+		t.Errorf("slot of a not found by name. got=%v, %t", value, ok)
+	}
+
+	// a slot that does not hold the name falls back to the lookup by name
+	input := "let f = fn(a) { let b = 1; a }; f(7)"
+	program := parser.New(lexer.New(input)).ParseProgram()
+	resolve.Resolve(program, BuiltinNames())
+	fn := program.Statements[0].(*ast.LetStatement).Value.(*ast.FunctionLiteral)
+	fn.Body.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.Identifier).Index = 1
+	testIntegerObject(t, Eval(program, object.NewEnvironment()), 7)
+}
+
+// testEval evaluates input the way programs run, with the slots from the
+// resolver, unless the resolver finds errors the test is about.
 func testEval(input string) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
-	return Eval(program)
+	if diagnostics := resolve.Resolve(program, BuiltinNames()); diag.HasErrors(diagnostics) {
+		program = parser.New(lexer.New(input)).ParseProgram()
+	}
+	env := object.NewEnvironment()
+	return Eval(program, env)
 }
 
 func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
@@ -304,6 +446,22 @@ func TestErrorHandling(t *testing.T) {
              `,
 			"unknown operator: BOOLEAN + BOOLEAN",
 		},
+		{
+			"let f = fn(a, b) { a + b }; f(1);",
+			"wrong number of arguments for f(a, b): want=2, got=1",
+		},
+		{
+			"let f = 5; f(1);",
+			"not a function: INTEGER",
+		},
+		{
+			"ahoi",
+			"undefined identifier: ahoi",
+		},
+		{
+			"fn (a) { a; }(ahoi)",
+			"undefined identifier: ahoi",
+		},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
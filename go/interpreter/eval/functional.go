@@ -0,0 +1,403 @@
+package eval
+
+import (
+	"cmp"
+	"fmt"
+	"interpreter/ast"
+	"interpreter/object"
+	"slices"
+)
+
+// The builtins taking callbacks are registered here and not in the
+// initializer of contextBuiltins, because through applyFunction they refer
+// to evaluate, which refers to contextBuiltins.
+func init() {
+	for name, fn := range map[string]builtinFunction{
+		"map":     (*Context).builtinMap,
+		"filter":  (*Context).builtinFilter,
+		"reduce":  (*Context).builtinReduce,
+		"each":    (*Context).builtinEach,
+		"sort":    (*Context).builtinSort,
+		"any":     (*Context).builtinAny,
+		"all":     (*Context).builtinAll,
+		"iter":    (*Context).builtinIter,
+		"collect": (*Context).builtinCollect,
+		"take":    (*Context).builtinTake,
+	} {
+		contextBuiltins[name] = fn
+	}
+	for _, b := range []*object.Builtin{
+		{Name: "zip", Fn: builtinZip},
+		{Name: "range", Fn: builtinRange},
+		{Name: "enumerate", Fn: builtinEnumerate},
+		{Name: "next", Fn: builtinNext},
+	} {
+		builtins[b.Name] = b
+	}
+}
+
+// callback calls fn for the element at index of the array passed to the
+// builtin name. An error gets a frame naming the builtin and the index.
+func (c *Context) callback(name string, index int, fn object.Object, args ...object.Object) object.Object {
+	result := c.applyFunction(fn, args)
+	if err, ok := result.(*object.Error); ok {
+		err.Trace = append(err.Trace, object.Frame{Function: fmt.Sprintf("%s at index %d", name, index)})
+	}
+	return result
+}
+
+// iterableAndFunction checks the arguments of builtins like each, which
+// take an iterable and a function.
+func (c *Context) iterableAndFunction(name string, args []object.Object) (object.Iterator, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want=2, got=%d", name, len(args))
+	}
+	it, err := c.iterableArgument(name, 0, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, createError(object.TYPE_ERROR, "argument 2 to %s must be a function, got %s", name, args[1].Type())
+	}
+	return it, args[1], nil
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.Builtin, *object.BoundMethod:
+		return true
+	default:
+		return false
+	}
+}
+
+// builtinMap calls fn for every element. Over an array it returns an array,
+// over any other iterable a lazy iterator, which calls fn only when its
+// elements are consumed.
+func (c *Context) builtinMap(args ...object.Object) object.Object {
+	it, fn, err := c.iterableAndFunction("map", args)
+	if err != nil {
+		return err
+	}
+	i := 0
+	mapped := &object.FuncIterator{Name: "map", Fn: func() (object.Object, bool) {
+		el, ok := it.Next()
+		if !ok || isError(el) {
+			return el, ok
+		}
+		i++
+		return c.callback("map", i-1, fn, el), true
+	}}
+	if _, ok := args[0].(*object.Array); ok {
+		return c.collect(mapped)
+	}
+	return mapped
+}
+
+// builtinFilter keeps the elements fn returns a truthy value for. Like map
+// it is lazy unless it filters an array.
+func (c *Context) builtinFilter(args ...object.Object) object.Object {
+	it, fn, err := c.iterableAndFunction("filter", args)
+	if err != nil {
+		return err
+	}
+	i := 0
+	filtered := &object.FuncIterator{Name: "filter", Fn: func() (object.Object, bool) {
+		for {
+			el, ok := it.Next()
+			if !ok || isError(el) {
+				return el, ok
+			}
+			i++
+			result := c.callback("filter", i-1, fn, el)
+			if isError(result) {
+				return result, true
+			}
+			if isTruthy(result) {
+				return el, true
+			}
+		}
+	}}
+	if _, ok := args[0].(*object.Array); ok {
+		return c.collect(filtered)
+	}
+	return filtered
+}
+
+// builtinReduce is reduce(iterable, fn(acc, el), initial). Without initial
+// the first element is the initial value.
+func (c *Context) builtinReduce(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for reduce: want=2 or 3, got=%d", len(args))
+	}
+	it, fn, err := c.iterableAndFunction("reduce", args[:2])
+	if err != nil {
+		return err
+	}
+
+	var acc object.Object
+	i := 0
+	if len(args) == 3 {
+		acc = args[2]
+	} else if first, ok := it.Next(); ok {
+		acc, i = first, 1
+	} else {
+		return createError(object.ARGUMENT_ERROR, "reduce of an empty array without initial value")
+	}
+	if isError(acc) {
+		return acc
+	}
+
+	for ; ; i++ {
+		el, ok := it.Next()
+		if !ok {
+			return acc
+		}
+		if isError(el) {
+			return el
+		}
+		acc = c.callback("reduce", i, fn, acc, el)
+		if isError(acc) {
+			return acc
+		}
+	}
+}
+
+func (c *Context) builtinEach(args ...object.Object) object.Object {
+	it, fn, err := c.iterableAndFunction("each", args)
+	if err != nil {
+		return err
+	}
+	for i := 0; ; i++ {
+		el, ok := it.Next()
+		if !ok {
+			return NULL
+		}
+		if isError(el) {
+			return el
+		}
+		if result := c.callback("each", i, fn, el); isError(result) {
+			return result
+		}
+	}
+}
+
+// builtinSort returns a sorted copy of an array. The optional comparator
+// returns a negative integer if its first argument comes first, a positive
+// one if the second does and 0 if they are equal. Without it numbers and
+// strings are sorted in ascending order. The sort is stable.
+func (c *Context) builtinSort(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for sort: want=1 or 2, got=%d", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to sort must be ARRAY, got %s", args[0].Type())
+	}
+	compare := compareObjects
+	if len(args) == 2 {
+		if !isCallable(args[1]) {
+			return createError(object.TYPE_ERROR, "argument 2 to sort must be a function, got %s", args[1].Type())
+		}
+		compare = c.comparator(args[1])
+	}
+
+	elements := slices.Clone(array.Elements)
+	var err *object.Error
+	slices.SortStableFunc(elements, func(a, b object.Object) int {
+		if err != nil {
+			return 0
+		}
+		var n int
+		n, err = compare(a, b)
+		return n
+	})
+	if err != nil {
+		return err
+	}
+	return &object.Array{Elements: elements}
+}
+
+// comparator turns the function fn into a comparison for sort.
+func (c *Context) comparator(fn object.Object) func(a, b object.Object) (int, *object.Error) {
+	return func(a, b object.Object) (int, *object.Error) {
+		result := c.applyFunction(fn, []object.Object{a, b})
+		switch result := result.(type) {
+		case *object.Error:
+			result.Trace = append(result.Trace, object.Frame{Function: "sort comparator"})
+			return 0, result
+		case *object.Integer:
+			return cmp.Compare(result.Value, 0), nil
+		default:
+			return 0, createError(object.TYPE_ERROR, "sort comparator must return INTEGER, got %s", result.Type())
+		}
+	}
+}
+
+// compareObjects orders two numbers or two strings.
+func compareObjects(a, b object.Object) (int, *object.Error) {
+	if isNumber(a) && isNumber(b) {
+		aVal, _ := getValueAndType(a)
+		bVal, _ := getValueAndType(b)
+		return cmp.Compare(aVal.(float64), bVal.(float64)), nil
+	}
+	aStr, aOk := a.(*object.String)
+	bStr, bOk := b.(*object.String)
+	if aOk && bOk {
+		return cmp.Compare(aStr.Value, bStr.Value), nil
+	}
+	return 0, createError(object.TYPE_ERROR, "cannot compare %s and %s", a.Type(), b.Type())
+}
+
+// builtinZip returns arrays of the elements at the same index of all its
+// arguments. It stops at the end of the shortest one.
+func builtinZip(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for zip: want at least 1, got=0")
+	}
+	arrays := make([]*object.Array, len(args))
+	length := -1
+	for i, arg := range args {
+		array, ok := arg.(*object.Array)
+		if !ok {
+			return createError(object.TYPE_ERROR, "argument %d to zip must be ARRAY, got %s", i+1, arg.Type())
+		}
+		arrays[i] = array
+		if length < 0 || len(array.Elements) < length {
+			length = len(array.Elements)
+		}
+	}
+
+	elements := make([]object.Object, length)
+	for i := range elements {
+		tuple := make([]object.Object, len(arrays))
+		for j, array := range arrays {
+			tuple[j] = array.Elements[i]
+		}
+		elements[i] = &object.Array{Elements: tuple}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinEnumerate pairs each element with its index: [[0, a], [1, b]].
+func builtinEnumerate(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for enumerate: want=1, got=%d", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to enumerate must be ARRAY, got %s", args[0].Type())
+	}
+	elements := make([]object.Object, len(array.Elements))
+	for i, el := range array.Elements {
+		elements[i] = &object.Array{Elements: []object.Object{&object.Integer{Value: int64(i)}, el}}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinRange is range(end), range(start, end) or range(start, end, step)
+// and returns the integers from start up to but excluding end. Like the
+// ranges of .. it is lazy, the integers are only created when consumed.
+func builtinRange(args ...object.Object) object.Object {
+	if len(args) < 1 || len(args) > 3 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for range: want=1 to 3, got=%d", len(args))
+	}
+	values := make([]int64, len(args))
+	for i, arg := range args {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return createError(object.TYPE_ERROR, "argument %d to range must be INTEGER, got %s", i+1, arg.Type())
+		}
+		values[i] = n.Value
+	}
+
+	start, end, step := int64(0), values[0], int64(1)
+	if len(values) > 1 {
+		start, end = values[0], values[1]
+	}
+	if len(values) > 2 {
+		step = values[2]
+	}
+	if step == 0 {
+		return createError(object.ARGUMENT_ERROR, "range step must not be 0")
+	}
+	return &object.Range{Start: start, End: end, Step: step}
+}
+
+// builtinAny reports whether fn returns a truthy value for any element. It
+// stops at the first one. Without fn the elements themselves are tested.
+func (c *Context) builtinAny(args ...object.Object) object.Object {
+	return c.findTruthy("any", args, true)
+}
+
+// builtinAll reports whether fn returns a truthy value for all elements. It
+// stops at the first element it does not. Without fn the elements
+// themselves are tested.
+func (c *Context) builtinAll(args ...object.Object) object.Object {
+	return c.findTruthy("all", args, false)
+}
+
+// findTruthy returns TRUE as soon as the truthiness of an element is want,
+// FALSE otherwise, or the inverse for all.
+func (c *Context) findTruthy(name string, args []object.Object, want bool) object.Object {
+	var it object.Iterator
+	var fn object.Object
+	var err *object.Error
+	if len(args) == 1 {
+		it, err = c.iterableArgument(name, 0, args)
+	} else {
+		it, fn, err = c.iterableAndFunction(name, args)
+	}
+	if err != nil {
+		return err
+	}
+
+	for i := 0; ; i++ {
+		el, ok := it.Next()
+		if !ok {
+			return getNativeBooleanObject(!want)
+		}
+		if isError(el) {
+			return el
+		}
+		result := el
+		if fn != nil {
+			result = c.callback(name, i, fn, el)
+			if isError(result) {
+				return result
+			}
+		}
+		if isTruthy(result) == want {
+			return getNativeBooleanObject(want)
+		}
+	}
+}
+
+// evalPipeExpression calls the stage on the right of |> with the value on
+// the left as first argument. Errors without position point at the stage.
+func (c *Context) evalPipeExpression(pipe *ast.PipeExpression, env *object.Environment) object.Object {
+	left := c.eval(pipe.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	stage, args := pipe.Right, []ast.Expression{}
+	if call, ok := pipe.Right.(*ast.CallExpression); ok {
+		stage, args = call.Function, call.Arguments
+	}
+	function := c.eval(stage, env)
+	if isError(function) {
+		return function
+	}
+	rest := c.evalArguments(args, env)
+	if len(rest) == 1 && isError(rest[0]) {
+		return rest[0]
+	}
+
+	result := c.applyFunction(function, append([]object.Object{left}, rest...))
+	if err, ok := result.(*object.Error); ok && err.Line == 0 {
+		span := ast.NodeSpan(pipe.Right)
+		err.Line, err.Column = span.StartLine, span.StartColumn
+	}
+	return traceAt(stage, result)
+}
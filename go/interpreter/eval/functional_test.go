@@ -0,0 +1,139 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestHigherOrderBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, `[2, 4, 6]`},
+		{`map([], fn(x) { x })`, `[]`},
+		{`map(["a", "b"], upper)`, `["A", "B"]`},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, `[3, 4]`},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x })`, `6`},
+		{`reduce([1, 2, 3], fn(acc, x) { acc + x }, 10)`, `16`},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, `0`},
+		{`let sum = 0; each([1, 2], fn(x) { sum + x })`, `null`},
+		{`sort([3, 1, 2.5])`, `[1, 2.5, 3]`},
+		{`sort(["b", "c", "a"])`, `["a", "b", "c"]`},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, `[3, 2, 1]`},
+		{`sort([[2, "a"], [1, "b"], [2, "c"]], fn(a, b) { a[0] - b[0] })`, `[[1, "b"], [2, "a"], [2, "c"]]`},
+		{`let xs = [2, 1]; sort(xs); xs`, `[2, 1]`},
+		{`zip([1, 2, 3], ["a", "b"])`, `[[1, "a"], [2, "b"]]`},
+		{`zip([1], [2], [3])`, `[[1, 2, 3]]`},
+		{`enumerate(["a", "b"])`, `[[0, "a"], [1, "b"]]`},
+		{`collect(range(3))`, `[0, 1, 2]`},
+		{`collect(range(2, 5))`, `[2, 3, 4]`},
+		{`collect(range(0, 10, 4))`, `[0, 4, 8]`},
+		{`collect(range(5, 0, -2))`, `[5, 3, 1]`},
+		{`collect(range(5, 0))`, `[]`},
+		{`range(2, 5)`, `2..5`},
+		{`range(0, 10, 4)`, `range(0, 10, 4)`},
+		{`len(range(5, 0, -2))`, `3`},
+		{`reduce(range(0, 10, 3), fn(a, x) { a + x }, 0)`, `18`},
+		{`collect(take(range(0, 9223372036854775807), 2))`, `[0, 1]`},
+		{`len(range(0, 9223372036854775807, 4611686018427387904))`, `2`},
+		{`collect(range(-9223372036854775807, 9223372036854775807, 9223372036854775807))`, `[-9223372036854775807, 0]`},
+		{`collect(range(9223372036854775807, -9223372036854775807, -9223372036854775807))`, `[9223372036854775807, 0]`},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, `true`},
+		{`any([1, 2, 3], fn(x) { x > 5 })`, `false`},
+		{`any([false, 0])`, `true`},
+		{`any([])`, `false`},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, `true`},
+		{`all([1, 2, 3], fn(x) { x < 3 })`, `false`},
+		{`all([])`, `true`},
+		{`any([1, 2, 3], fn(x) { if (x == 1) { true } else { 1 / 0 } })`, `true`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestHigherOrderBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind string
+		expected     string
+		trace        []string
+	}{
+		{
+			`map([1, 2, 3], fn(x) { 6 / (x - 2) })`,
+			object.ZERO_DIVISION_ERROR, "division by zero",
+			[]string{"fn", "map at index 1 called at 1:1"},
+		},
+		{
+			`let check = fn(x) { if (x == 3) { throw "bad" } else { true } }; filter([1, 2, 3], check)`,
+			object.THROWN_ERROR, "bad",
+			[]string{"check", "filter at index 2 called at 1:66"},
+		},
+		{
+			`reduce([1, 2], fn(acc, x) { acc + "x" })`,
+			object.TYPE_ERROR, "type mismatch: INTEGER + STRING",
+			[]string{"fn", "reduce at index 1 called at 1:1"},
+		},
+		{`map([1], fn(a, b) { a })`, object.ARGUMENT_ERROR, "wrong number of arguments for fn(a, b): want=2, got=1", nil},
+		{`map(1, fn(x) { x })`, object.TYPE_ERROR, "argument 1 to map must be iterable, got INTEGER", nil},
+		{`filter([1], 2)`, object.TYPE_ERROR, "argument 2 to filter must be a function, got INTEGER", nil},
+		{`each([1])`, object.ARGUMENT_ERROR, "wrong number of arguments for each: want=2, got=1", nil},
+		{`reduce([], fn(a, x) { a })`, object.ARGUMENT_ERROR, "reduce of an empty array without initial value", nil},
+		{`sort([1, "a"])`, object.TYPE_ERROR, "cannot compare STRING and INTEGER", nil},
+		{`sort([1, 2], fn(a, b) { true })`, object.TYPE_ERROR, "sort comparator must return INTEGER, got BOOLEAN", nil},
+		{`zip([1], 2)`, object.TYPE_ERROR, "argument 2 to zip must be ARRAY, got INTEGER", nil},
+		{`range(1, 2, 0)`, object.ARGUMENT_ERROR, "range step must not be 0", nil},
+		{`range(1.5)`, object.TYPE_ERROR, "argument 1 to range must be INTEGER, got FLOAT", nil},
+		{
+			`len(range(-9223372036854775807, 9223372036854775807))`,
+			object.ARGUMENT_ERROR, "length of -9223372036854775807..9223372036854775807 does not fit an integer", nil,
+		},
+		{
+			`len(range(9223372036854775807, -9223372036854775807, -1))`,
+			object.ARGUMENT_ERROR, "length of range(9223372036854775807, -9223372036854775807, -1) does not fit an integer", nil,
+		},
+		{`any(1)`, object.TYPE_ERROR, "argument 1 to any must be iterable, got INTEGER", nil},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind || errObj.Message != tt.expected {
+			t.Errorf("%s: wrong error. expected=%s: %s, got=%s: %s",
+				tt.input, tt.expectedKind, tt.expected, errObj.Kind, errObj.Message)
+		}
+		if tt.trace == nil {
+			continue
+		}
+		trace := []string{}
+		for _, f := range errObj.Trace {
+			trace = append(trace, f.String())
+		}
+		if len(trace) != len(tt.trace) {
+			t.Errorf("%s: wrong trace. expected=%q, got=%q", tt.input, tt.trace, trace)
+			continue
+		}
+		for i := range trace {
+			if trace[i] != tt.trace[i] {
+				t.Errorf("%s: wrong trace. expected=%q, got=%q", tt.input, tt.trace, trace)
+				break
+			}
+		}
+	}
+}
+
+func TestCallbackCatch(t *testing.T) {
+	input := `try { map([1, 0], fn(x) { 1 / x }) } catch (e) { e.trace }`
+	expected := "fn\nmap at index 1 called at 1:7"
+	if got := testEval(input).Inspect(); got != expected {
+		t.Errorf("wrong trace. expected=%s, got=%s", expected, got)
+	}
+}
@@ -0,0 +1,178 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"runtime"
+	"sync"
+)
+
+// generator is the iterator returned by a call of a generator function.
+// Its body runs in a goroutine of its own, which is only ever running while
+// the consumer waits for the next element in Next. So at any time either the
+// consumer or the generator evaluates, and they can share the context.
+type generator struct {
+	name  string
+	state *generatorState
+}
+
+// generatorState is the part of a generator its goroutine refers to. The
+// generator itself is not referenced by the goroutine, so it can be
+// collected once the consumer drops it, which stops the goroutine. A
+// goroutine that still refers to its generator, e.g. through a binding in
+// the environment of the body, keeps it alive; it is stopped when the run
+// ends, see Context.stopGenerators.
+type generatorState struct {
+	context  *Context
+	function *object.Function
+	env      *object.Environment
+
+	// resume lets the body run up to the next yield, yield hands over the
+	// element. yield is closed when the body has finished. done is closed
+	// when the generator is abandoned, by the finalizer of the generator or
+	// at the end of the run, whichever comes first.
+	resume  chan struct{}
+	yield   chan object.Object
+	done    chan struct{}
+	abandon sync.Once
+	// panicked holds a panic of the body, which is raised again in the
+	// goroutine of the consumer.
+	panicked any
+
+	started  bool
+	finished bool
+	// stopped is set when the run that created the generator has ended.
+	stopped bool
+}
+
+// abandoned unwinds the body of a generator that is no longer used. It is
+// a panic and not an error so that no try in the body can catch it.
+type abandoned struct{}
+
+func (c *Context) newGenerator(function *object.Function, env *object.Environment) *generator {
+	name := function.Name
+	if name == "" {
+		name = "fn"
+	}
+	s := &generatorState{
+		context:  c,
+		function: function,
+		env:      env,
+		resume:   make(chan struct{}),
+		yield:    make(chan object.Object),
+		done:     make(chan struct{}),
+	}
+	g := &generator{name: name, state: s}
+	runtime.SetFinalizer(g, func(g *generator) { g.state.stop() })
+	if c.generators == nil {
+		c.generators = make(map[*generatorState]bool)
+	}
+	c.generators[s] = true
+	return g
+}
+
+// stop abandons the generator, which unwinds its body if it is waiting at
+// a yield.
+func (s *generatorState) stop() {
+	s.abandon.Do(func() { close(s.done) })
+}
+
+// stopGenerators abandons the generators created during a run that have
+// not finished. Their goroutines end with the run like its tasks.
+func (c *Context) stopGenerators() {
+	for s := range c.generators {
+		s.stopped = true
+		s.stop()
+	}
+	c.generators = nil
+}
+
+// finish marks the generator as finished, its goroutine has ended.
+func (s *generatorState) finish() {
+	s.finished = true
+	delete(s.context.generators, s)
+}
+
+func (g *generator) Type() object.ObjectType { return object.GENERATOR_OBJ }
+func (g *generator) Inspect() string         { return "generator " + g.name }
+
+// Iterate returns the generator itself, it can be consumed only once.
+func (g *generator) Iterate() object.Iterator { return g }
+
+// Next runs the body up to the next yield. An error of the body is returned
+// as element and ends the generator.
+func (g *generator) Next() (object.Object, bool) {
+	s := g.state
+	if s.finished {
+		return nil, false
+	}
+	if s.stopped {
+		s.finished = true
+		return createError(object.CANCELED_ERROR, "generator %s ended with the run that created it", g.name), true
+	}
+
+	c := s.context
+	previous := c.generator
+	c.generator = s
+	if s.started {
+		s.resume <- struct{}{}
+	} else {
+		s.started = true
+		go s.run()
+	}
+	value, ok := <-s.yield
+	c.generator = previous
+
+	if s.panicked != nil {
+		s.finish()
+		panic(s.panicked)
+	}
+	if !ok {
+		s.finish()
+		return nil, false
+	}
+	if err, isErr := value.(*object.Error); isErr {
+		s.finish()
+		err.Trace = append(err.Trace, object.Frame{Function: g.name})
+	}
+	return value, true
+}
+
+// run evaluates the body. A return ends the generator like reaching the end
+// of the body, its value is dropped.
+func (s *generatorState) run() {
+	defer close(s.yield)
+	defer func() {
+		if r := recover(); r != nil {
+			if _, ok := r.(abandoned); !ok {
+				s.panicked = r
+			}
+		}
+	}()
+
+	result := s.context.eval(s.function.Body, s.env)
+	if err, ok := result.(*object.Error); ok {
+		s.yield <- err
+	}
+}
+
+// evalYieldExpression hands the value to the consumer and waits until the
+// generator is resumed.
+func (c *Context) evalYieldExpression(exp *ast.YieldExpression, env *object.Environment) object.Object {
+	value := c.eval(exp.Value, env)
+	if isError(value) {
+		return value
+	}
+	s := c.generator
+	if s == nil {
+		return createError(object.SYNTAX_ERROR, "yield outside of a generator")
+	}
+
+	s.yield <- value
+	select {
+	case <-s.resume:
+		return NULL
+	case <-s.done:
+		panic(abandoned{})
+	}
+}
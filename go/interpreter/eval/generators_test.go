@@ -0,0 +1,114 @@
+package eval
+
+import (
+	"interpreter/object"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestGenerators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let gen = fn() { yield 1; yield 2 }; collect(gen())`, `[1, 2]`},
+		{`let gen = fn() { yield 1 }; gen()`, `generator gen`},
+		{`let squares = fn(from, to) { for (i in from..to) { yield i * i } }; collect(squares(1, 4))`, `[1, 4, 9]`},
+		{`let gen = fn() { yield 1; return 5; yield 2 }; collect(gen())`, `[1]`},
+		{`let gen = fn() { yield 1; yield 2 }; let g = gen(); [next(g), next(g), next(g, "end")]`, `[1, 2, "end"]`},
+		{`let gen = fn() { yield 1; yield 2 }; let a = gen(); next(a); [next(a), next(gen())]`, `[2, 1]`},
+		{`let nat = fn() { for (n in 0..=9223372036854775807) { yield n } }; collect(take(nat(), 3))`, `[0, 1, 2]`},
+		{`let nat = fn() { for (n in 0..=9223372036854775807) { yield n } }; nat() |> map(fn(n) { n * n }) |> take(4) |> collect`, `[0, 1, 4, 9]`},
+		{`let gen = fn() { let x = yield 1; yield x }; collect(gen())`, `[1, null]`},
+		{`let gen = fn() { yield 1 }; let g = gen(); collect(g); collect(g)`, `[]`},
+		{`let pairs = fn(h) { for ([k, v] in h) { if (v > 1) { yield k } } }; collect(pairs({"a": 1, "b": 2, "c": 3}))`, `["b", "c"]`},
+		{`let inner = fn() { yield 1; yield 2 }; let outer = fn() { for (x in inner()) { yield x * 10 }; yield 0 }; collect(outer())`, `[10, 20, 0]`},
+		{`let gen = fn() { try { yield 1; throw "x" } catch (e) { yield e.message } }; collect(gen())`, `[1, "x"]`},
+		{`struct Tree { left, value, right };
+fn (t Tree) walk() {
+  if (t.left) { for (v in t.left.walk()) { yield v } };
+  yield t.value;
+  if (t.right) { for (v in t.right.walk()) { yield v } }
+};
+let tree = Tree(Tree(false, 1, false), 2, Tree(Tree(false, 3, false), 4, false));
+collect(tree.walk())`, `[1, 2, 3, 4]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestGeneratorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let gen = fn() {\n  yield 1;\n  throw \"boom\"\n};\ncollect(gen())", "ERROR: 3:3: Error: boom\n\tin gen called at 5:1"},
+		{"let gen = fn() { yield 1 / 0 };\nfor (x in gen()) { x }", "ERROR: 1:26: ZeroDivisionError: division by zero\n\tin gen"},
+		{"let gen = fn(a) { yield a };\ngen()", "ERROR: 2:4: ArgumentError: wrong number of arguments for gen(a): want=1, got=0"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+
+	caught := testEval(`let gen = fn() { yield 1; throw "boom" }; try { collect(gen()) } catch (e) { e.message }`)
+	if caught.Inspect() != "boom" {
+		t.Errorf("error of a generator not caught by the consumer. got=%s", caught.Inspect())
+	}
+	after := testEval(`let gen = fn() { throw "boom"; yield 1 };
+let g = gen();
+let first = try { next(g) } catch (e) { e.message };
+[first, next(g, "done")]`)
+	if after.Inspect() != `["boom", "done"]` {
+		t.Errorf("generator goes on after an error. got=%s", after.Inspect())
+	}
+}
+
+func TestAbandonedGenerators(t *testing.T) {
+	before := runtime.NumGoroutine()
+	testEval(`let nat = fn() { for (n in 0..1000000) { try { yield n } catch (e) { 0 } } };
+each(0..50, fn(i) { next(nat()) })`)
+
+	for i := 0; i < 200 && runtime.NumGoroutine() > before; i++ {
+		runtime.GC()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("abandoned generators keep running. goroutines before=%d, after=%d", before, n)
+	}
+}
+
+func TestGeneratorsEndWithRun(t *testing.T) {
+	before := runtime.NumGoroutine()
+	for i := 0; i < 50; i++ {
+		// g is bound in an environment the parked body refers to
+		testEval(`let gen = fn() { yield 1; yield 2 }; let g = gen(); next(g)`)
+	}
+
+	for i := 0; i < 200 && runtime.NumGoroutine() > before; i++ {
+		runtime.GC()
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("generators keep running after the run. goroutines before=%d, after=%d", before, n)
+	}
+
+	g := testEval(`let gen = fn() { yield 1; yield 2 }; let g = gen(); next(g); g`)
+	el, ok := g.(object.Iterator).Next()
+	if err, isErr := el.(*object.Error); !ok || !isErr || err.Kind != object.CANCELED_ERROR {
+		t.Errorf("generator goes on after its run. got=%v", el)
+	}
+}
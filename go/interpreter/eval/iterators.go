@@ -0,0 +1,166 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+)
+
+// iterate returns an iterator over obj, false if obj is not iterable.
+// Channels are iterated here because receiving passes the turn on.
+func (c *Context) iterate(obj object.Object) (object.Iterator, bool) {
+	if ch, ok := obj.(*object.Channel); ok {
+		return c.channelIterator(ch), true
+	}
+	iterable, ok := obj.(object.Iterable)
+	if !ok {
+		return nil, false
+	}
+	return iterable.Iterate(), true
+}
+
+func (c *Context) evalRangeExpression(exp *ast.RangeExpression, env *object.Environment) object.Object {
+	bounds := [2]int64{}
+	for i, node := range []ast.Expression{exp.Start, exp.End} {
+		value := c.eval(node, env)
+		if isError(value) {
+			return value
+		}
+		n, ok := value.(*object.Integer)
+		if !ok {
+			return locateError(node, createError(object.TYPE_ERROR, "range bounds must be INTEGER, got %s", describeType(value)))
+		}
+		bounds[i] = n.Value
+	}
+	return &object.Range{Start: bounds[0], End: bounds[1], Inclusive: exp.Inclusive}
+}
+
+// evalForExpression runs the body of a for-in loop for every element of the
+// iterable. Each iteration binds the pattern in an environment of its own,
+// so closures created in the body keep the element of their iteration.
+func (c *Context) evalForExpression(loop *ast.ForExpression, env *object.Environment) object.Object {
+	iterable := c.eval(loop.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+	it, ok := c.iterate(iterable)
+	if !ok {
+		return locateError(loop.Iterable, createError(object.TYPE_ERROR, "cannot iterate over %s", describeType(iterable)))
+	}
+
+	for {
+		el, ok := it.Next()
+		if !ok {
+			return NULL
+		}
+		if isError(el) {
+			return el
+		}
+		loopEnv := object.NewEnclosedEnvironment(env)
+		if err := c.destructure(loop.Pattern, el, loopEnv); err != nil {
+			return err
+		}
+		result := c.eval(loop.Body, loopEnv)
+		if result != nil {
+			rt := result.Type()
+			if rt == object.RETURN_OBJ || rt == object.ERROR_OBJ {
+				return result
+			}
+		}
+	}
+}
+
+// iterableArgument returns an iterator over the argument i of the builtin
+// name.
+func (c *Context) iterableArgument(name string, i int, args []object.Object) (object.Iterator, *object.Error) {
+	it, ok := c.iterate(args[i])
+	if !ok {
+		return nil, createError(object.TYPE_ERROR, "argument %d to %s must be iterable, got %s", i+1, name, args[i].Type())
+	}
+	return it, nil
+}
+
+// builtinIter returns an iterator over its argument, which next can advance.
+func (c *Context) builtinIter(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for iter: want=1, got=%d", len(args))
+	}
+	it, err := c.iterableArgument("iter", 0, args)
+	if err != nil {
+		return err
+	}
+	return it
+}
+
+// builtinNext returns the next element of an iterator, or the default
+// value, null if none is given, once it is exhausted.
+func builtinNext(args ...object.Object) object.Object {
+	if len(args) != 1 && len(args) != 2 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for next: want=1 or 2, got=%d", len(args))
+	}
+	it, ok := args[0].(object.Iterator)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to next must be ITERATOR, got %s", args[0].Type())
+	}
+	el, ok := it.Next()
+	if !ok {
+		if len(args) == 2 {
+			return args[1]
+		}
+		return NULL
+	}
+	return el
+}
+
+// builtinCollect returns the elements of an iterable as an array.
+func (c *Context) builtinCollect(args ...object.Object) object.Object {
+	if len(args) != 1 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for collect: want=1, got=%d", len(args))
+	}
+	it, err := c.iterableArgument("collect", 0, args)
+	if err != nil {
+		return err
+	}
+	return c.collect(it)
+}
+
+// collect consumes it into an array.
+func (c *Context) collect(it object.Iterator) object.Object {
+	elements := []object.Object{}
+	for {
+		el, ok := it.Next()
+		if !ok {
+			return &object.Array{Elements: elements}
+		}
+		if isError(el) {
+			return el
+		}
+		if err := c.checkSize(len(elements) + 1); err != nil {
+			return err
+		}
+		elements = append(elements, el)
+	}
+}
+
+// builtinTake returns an iterator over the first n elements of an iterable.
+// It does not advance the iterable beyond them.
+func (c *Context) builtinTake(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for take: want=2, got=%d", len(args))
+	}
+	it, err := c.iterableArgument("take", 0, args)
+	if err != nil {
+		return err
+	}
+	n, ok := args[1].(*object.Integer)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 2 to take must be INTEGER, got %s", args[1].Type())
+	}
+	left := n.Value
+	return &object.FuncIterator{Name: "take", Fn: func() (object.Object, bool) {
+		if left <= 0 {
+			return nil, false
+		}
+		left--
+		return it.Next()
+	}}
+}
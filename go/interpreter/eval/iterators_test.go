@@ -0,0 +1,127 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestRanges(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`0..3`, `0..3`},
+		{`1..=3`, `1..=3`},
+		{`let n = 2; n - 1..n * 2`, `1..4`},
+		{`collect(0..3)`, `[0, 1, 2]`},
+		{`collect(1..=3)`, `[1, 2, 3]`},
+		{`collect(3..1)`, `[]`},
+		{`collect(3..=3)`, `[3]`},
+		{`collect(9223372036854775806..=9223372036854775807)`, `[9223372036854775806, 9223372036854775807]`},
+		{`len(0..10)`, `10`},
+		{`len(0..=10)`, `11`},
+		{`len(5..0)`, `0`},
+		{`len(5..5)`, `0`},
+		{`len(5..=5)`, `1`},
+		{`len(-1..9223372036854775806)`, `9223372036854775807`},
+		{`len(0..=9223372036854775806)`, `9223372036854775807`},
+		{`0..1000000000 |> map(fn(x) { x * 2 }) |> take(3) |> collect`, `[0, 2, 4]`},
+		{`0..1000000000 |> filter(fn(x) { x > 5 }) |> take(2) |> collect`, `[6, 7]`},
+		{`reduce(1..=4, fn(acc, x) { acc * x })`, `24`},
+		{`any(0..1000000000, fn(x) { x == 3 })`, `true`},
+		{`all(1..=3)`, `true`},
+		{`map(0..3, fn(x) { x })`, `iterator map`},
+		{`map([1, 2], fn(x) { x + 1 })`, `[2, 3]`},
+		{`collect(map({"a": 1}, fn(p) { p[0] }))`, `["a"]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestIterators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let it = iter([1, 2]); [next(it), next(it), next(it)]`, `[1, 2, null]`},
+		{`let it = iter(0..1); next(it); next(it, "done")`, `done`},
+		{`collect("héllo")`, `["h", "é", "l", "l", "o"]`},
+		{`collect({"a": 1, "b": 2})`, `[["a", 1], ["b", 2]]`},
+		{`let it = iter(0..10); let first = collect(take(it, 3)); [first, next(it)]`, `[[0, 1, 2], 3]`},
+		{`let it = iter([1, 2, 3]); collect(it); collect(it)`, `[]`},
+		{`iter(0..3)`, `iterator range`},
+		{`collect(take([1, 2], 5))`, `[1, 2]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestForExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Sum { v }; let s = Sum(0); for (x in 1..=4) { s.v = s.v + x }; s.v`, `10`},
+		{`struct Acc { v }; let a = Acc(""); for (c in "abc") { a.v = c + a.v }; a.v`, `cba`},
+		{`struct Acc { v }; let a = Acc(""); for ([k, v] in {"a": 1, "b": 2}) { a.v = a.v + k + "${v}" }; a.v`, `a1b2`},
+		{`struct Acc { v }; let a = Acc(0); for ({"n": n} in [{"n": 1}, {"n": 2}]) { a.v = a.v + n }; a.v`, `3`},
+		{`for (x in []) { x }`, `null`},
+		{`for (x in [1]) { x }`, `null`},
+		{`let f = fn() { for (x in 0..100) { if (x == 3) { return x } }; -1 }; f()`, `3`},
+		{`struct Cell { f }; let cells = [Cell(0), Cell(0), Cell(0)]; for (x in 0..3) { cells[x].f = fn() { x } }; map(cells, fn(c) { c.f() })`, `[0, 1, 2]`},
+		{`struct Acc { v }; let a = Acc(0); for (x in take(map(0..1000000000, fn(x) { x * x }), 4)) { a.v = a.v + x }; a.v`, `14`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestIteratorErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1.5..3", "ERROR: 1:1: TypeError: range bounds must be INTEGER, got FLOAT"},
+		{"0..\"a\"", "ERROR: 1:4: TypeError: range bounds must be INTEGER, got STRING"},
+		{"for (x in 5) { x }", "ERROR: 1:11: TypeError: cannot iterate over INTEGER"},
+		{"for ([a, b] in [1]) { a }", "ERROR: 1:6: TypeError: cannot destructure INTEGER as ARRAY"},
+		{"for (x in 0..3) {\n  if (x == 2) { throw \"stop\" }\n}", "ERROR: 2:17: Error: stop"},
+		{"collect(map(0..3, fn(x) { 1 / (x - 1) }))", "ERROR: 1:29: ZeroDivisionError: division by zero\n\tin fn\n\tin map at index 1 called at 1:1"},
+		{"next([1])", "ERROR: 1:5: TypeError: argument 1 to next must be ITERATOR, got ARRAY"},
+		{"take(0..3, \"a\")", "ERROR: 1:5: TypeError: argument 2 to take must be INTEGER, got STRING"},
+		{"collect(1)", "ERROR: 1:8: TypeError: argument 1 to collect must be iterable, got INTEGER"},
+		{
+			"len(-9223372036854775807..9223372036854775807)",
+			"ERROR: 1:4: ArgumentError: length of -9223372036854775807..9223372036854775807 does not fit an integer",
+		},
+		{
+			"len(0..=9223372036854775807)",
+			"ERROR: 1:4: ArgumentError: length of 0..=9223372036854775807 does not fit an integer",
+		},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
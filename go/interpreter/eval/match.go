@@ -0,0 +1,225 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"strconv"
+)
+
+// evalMatchExpression evaluates the body of the first arm that matches with
+// evalBody, which is evalTail in tail position. Every arm binds the names of
+// its pattern in an environment of its own.
+func (c *Context) evalMatchExpression(match *ast.MatchExpression, env *object.Environment, evalBody func(ast.Node, *object.Environment) object.Object) object.Object {
+	value := c.eval(match.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	for _, arm := range match.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		matched, err := c.matchPattern(arm.Pattern, value, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+		if arm.Guard != nil {
+			guard := c.eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+		return evalBody(arm.Body, armEnv)
+	}
+	return createError(object.MATCH_ERROR, "no arm matches %s", inspectValue(value))
+}
+
+// matchPattern reports whether value matches pattern and binds the names of
+// the pattern in env. On a mismatch some names may already be bound.
+func (c *Context) matchPattern(pattern ast.Expression, value object.Object, env *object.Environment) (bool, *object.Error) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != ast.Wildcard {
+			bind(env, pattern, value)
+		}
+		return true, nil
+
+	case *ast.ArrayLiteral:
+		array, ok := value.(*object.Array)
+		if !ok || !fitsArrayPattern(pattern, array) {
+			return false, nil
+		}
+		for i, el := range pattern.Elements {
+			if rest, ok := el.(*ast.RestPattern); ok {
+				bindRest(rest, array.Elements[i:], env)
+				break
+			}
+			if matched, err := c.matchPattern(el, array.Elements[i], env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	case *ast.CallExpression:
+		return c.matchRecord(pattern, value, env)
+
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			return false, nil
+		}
+		for i, keyNode := range pattern.Keys {
+			key, ok := c.eval(keyNode, env).(object.Hashable)
+			if !ok {
+				return false, createError(object.SYNTAX_ERROR, "invalid hash pattern key: %s", keyNode)
+			}
+			pair, ok := hash.Pairs[key.HashKey()]
+			if !ok {
+				return false, nil
+			}
+			if matched, err := c.matchPattern(pattern.Values[i], pair.Value, env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	default:
+		literal := c.eval(pattern, env)
+		if err, ok := literal.(*object.Error); ok {
+			return false, err
+		}
+		return equalObjects(literal, value), nil
+	}
+}
+
+// destructure binds the names of the pattern of a let in env. Unlike a match,
+// a value that does not have the shape of the pattern is an error, located
+// at the part of the pattern that does not fit.
+func (c *Context) destructure(pattern ast.Expression, value object.Object, env *object.Environment) object.Object {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != ast.Wildcard {
+			bind(env, pattern, value)
+		}
+		return nil
+
+	case *ast.ArrayLiteral:
+		array, ok := value.(*object.Array)
+		if !ok {
+			err := createError(object.TYPE_ERROR, "cannot destructure %s as ARRAY", describeType(value))
+			return locateError(pattern, err)
+		}
+		if !fitsArrayPattern(pattern, array) {
+			err := createError(object.MATCH_ERROR, "cannot destructure array of length %d into %s", len(array.Elements), pattern)
+			return locateError(pattern, err)
+		}
+		for i, el := range pattern.Elements {
+			if rest, ok := el.(*ast.RestPattern); ok {
+				bindRest(rest, array.Elements[i:], env)
+				break
+			}
+			if err := c.destructure(el, array.Elements[i], env); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case *ast.CallExpression:
+		return c.destructureRecord(pattern, value, env)
+
+	case *ast.HashLiteral:
+		hash, ok := value.(*object.Hash)
+		if !ok {
+			err := createError(object.TYPE_ERROR, "cannot destructure %s as HASH", describeType(value))
+			return locateError(pattern, err)
+		}
+		for i, keyNode := range pattern.Keys {
+			key := c.eval(keyNode, env)
+			hashable, ok := key.(object.Hashable)
+			if !ok {
+				return locateError(keyNode, createError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type()))
+			}
+			pair, ok := hash.Pairs[hashable.HashKey()]
+			if !ok {
+				return locateError(keyNode, createError(object.MATCH_ERROR, "missing key %s", inspectValue(key)))
+			}
+			if err := c.destructure(pattern.Values[i], pair.Value, env); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	default:
+		return locateError(pattern, createError(object.SYNTAX_ERROR, "invalid pattern: %s", pattern))
+	}
+}
+
+// fitsArrayPattern reports whether array has as many elements as pattern
+// or, with a rest pattern, at least as many as come before the rest.
+func fitsArrayPattern(pattern *ast.ArrayLiteral, array *object.Array) bool {
+	n := len(pattern.Elements)
+	if n > 0 {
+		if _, ok := pattern.Elements[n-1].(*ast.RestPattern); ok {
+			return len(array.Elements) >= n-1
+		}
+	}
+	return len(array.Elements) == n
+}
+
+func bindRest(rest *ast.RestPattern, elements []object.Object, env *object.Environment) {
+	if rest.Name.Value != ast.Wildcard {
+		bind(env, rest.Name, &object.Array{Elements: append([]object.Object{}, elements...)})
+	}
+}
+
+// equalObjects compares like ==, but values of different types are unequal
+// instead of an error. Records are equal if they have the same struct and
+// equal fields.
+func equalObjects(a, b object.Object) bool {
+	return equalValues(a, b, map[[2]*object.Record]bool{})
+}
+
+// equalValues is equalObjects for the fields of the records in visited,
+// which are being compared already. Comparing such a pair again, through a
+// record that contains itself, does not find a difference.
+func equalValues(a, b object.Object, visited map[[2]*object.Record]bool) bool {
+	if aRec, ok := a.(*object.Record); ok {
+		bRec, ok := b.(*object.Record)
+		if !ok || aRec.Struct != bRec.Struct {
+			return false
+		}
+		pair := [2]*object.Record{aRec, bRec}
+		if visited[pair] {
+			return true
+		}
+		visited[pair] = true
+		for i := range aRec.Values {
+			if !equalValues(aRec.Values[i], bRec.Values[i], visited) {
+				return false
+			}
+		}
+		return true
+	}
+	if isNumber(a) && isNumber(b) {
+		aVal, _ := getValueAndType(a)
+		bVal, _ := getValueAndType(b)
+		return aVal.(float64) == bVal.(float64)
+	}
+	if aStr, ok := a.(*object.String); ok {
+		bStr, ok := b.(*object.String)
+		return ok && aStr.Value == bStr.Value
+	}
+	return a == b
+}
+
+// inspectValue quotes strings, so error messages show "1" and 1 apart.
+func inspectValue(obj object.Object) string {
+	if s, ok := obj.(*object.String); ok {
+		return strconv.Quote(s.Value)
+	}
+	return obj.Inspect()
+}
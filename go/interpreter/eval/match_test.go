@@ -0,0 +1,112 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (2) { 1 => "one", 2 => "two", _ => "many" }`, `two`},
+		{`match (7) { 1 => "one", _ => "many" }`, `many`},
+		{`match (2.0) { 2 => "int", _ => "other" }`, `int`},
+		{`match (-1) { -1 => "minus one", _ => "other" }`, `minus one`},
+		{`match ("b") { "a" => 1, "b" => 2 }`, `2`},
+		{`match (true) { false => 0, true => 1 }`, `1`},
+		{`match (5) { n => n * 2 }`, `10`},
+		{`match ([1, 2]) { [a] => a, [a, b] => a + b, _ => 0 }`, `3`},
+		{`match ([1, [2, 3]]) { [_, [x, y]] => x * y }`, `6`},
+		{`match ({"x": 1, "y": 2}) { {"x": 0} => "zero", {"x": x, "y": y} => x + y }`, `3`},
+		{`match ({"x": 1}) { {"y": y} => y, _ => "no y" }`, `no y`},
+		{`match (3) { n if n > 5 => "big", n if n > 1 => "medium", _ => "small" }`, `medium`},
+		{`let n = 1; match (5) { n => n }; n`, `1`},
+		{`let x = 10; match ([1]) { [y] => x + y }`, `11`},
+		{`match (1) { x => match (x + 1) { y => x + y } }`, `3`},
+		{`let count = fn(n, acc) { match (n) { 0 => acc, _ => count(n - 1, acc + 1) } }; count(20000, 0)`, `20000`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMatchErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (3) { 1 => 1, 2 => 2 }`, "ERROR: 1:1: MatchError: no arm matches 3"},
+		{`match ("3") { 3 => 1 }`, `ERROR: 1:1: MatchError: no arm matches "3"`},
+		{`match ([1, 2]) { [a] => a }`, "ERROR: 1:1: MatchError: no arm matches [1, 2]"},
+		{`match (1 / 0) { _ => 1 }`, "ERROR: 1:10: ZeroDivisionError: division by zero"},
+		{`match (1) { x if x / 0 => 1 }`, "ERROR: 1:20: ZeroDivisionError: division by zero"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestDestructuringLet(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b] = [1, 2]; a + b`, `3`},
+		{`let {"x": x, "y": y} = {"x": 3, "y": 4, "z": 5}; x * y`, `12`},
+		{`let [head, ...tail] = [1, 2, 3]; tail`, `[2, 3]`},
+		{`let [head, ...tail] = [1]; tail`, `[]`},
+		{`let [_, second, ..._] = [1, 2, 3, 4]; second`, `2`},
+		{`let [[a, b], {"c": c}] = [[1, 2], {"c": 3}]; a + b + c`, `6`},
+		{`let divmod = fn(a, b) { return [a / b, a - a / b * b]; }; let [q, r] = divmod(7, 2); q * 10 + r`, `31`},
+		{`let xs = [1, 2]; let [...copy] = xs; copy == xs`, `false`},
+		{`let [a, b] = [1, 2]; let [a, b] = [b, a]; [a, b]`, `[2, 1]`},
+		{`match ([1, 2, 3]) { [x] => x, [x, ...rest] => rest }`, `[2, 3]`},
+		{`match ([]) { [x, ...rest] => rest, _ => "empty" }`, `empty`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b] = 5;`, "ERROR: 1:5: TypeError: cannot destructure INTEGER as ARRAY"},
+		{`let [a, b] = [1, 2, 3];`, "ERROR: 1:5: MatchError: cannot destructure array of length 3 into [a, b]"},
+		{`let [a, b, ...c] = [1];`, "ERROR: 1:5: MatchError: cannot destructure array of length 1 into [a, b, ...c]"},
+		{`let {"x": x} = [1];`, "ERROR: 1:5: TypeError: cannot destructure ARRAY as HASH"},
+		{`let {"x": x, "y": y} = {"x": 1};`, `ERROR: 1:14: MatchError: missing key "y"`},
+		{"let p = [1, [2]];\nlet [a, [b, c]] = p;", "ERROR: 2:9: MatchError: cannot destructure array of length 1 into [b, c]"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package eval
+
+import (
+	"interpreter/object"
+	"math"
+	"slices"
+)
+
+// builtinModules are the modules provided by the interpreter. They take
+// precedence over files of the same name and need no capability.
+var builtinModules = map[string]*object.Module{
+	"math": mathModule(),
+}
+
+func mathModule() *object.Module {
+	exports := map[string]object.Object{
+		"pi":  &object.Float{Value: math.Pi},
+		"e":   &object.Float{Value: math.E},
+		"inf": &object.Float{Value: math.Inf(1)},
+	}
+	unary := map[string]func(float64) float64{
+		"sqrt":  math.Sqrt,
+		"floor": math.Floor,
+		"ceil":  math.Ceil,
+		"round": math.Round,
+		"sin":   math.Sin,
+		"cos":   math.Cos,
+		"tan":   math.Tan,
+		"exp":   math.Exp,
+		"log":   math.Log,
+	}
+	for name, fn := range unary {
+		exports[name] = mathFunction(name, 1, func(args []float64) float64 { return fn(args[0]) })
+	}
+	exports["pow"] = mathFunction("pow", 2, func(args []float64) float64 { return math.Pow(args[0], args[1]) })
+	exports["atan2"] = mathFunction("atan2", 2, func(args []float64) float64 { return math.Atan2(args[0], args[1]) })
+
+	exports["abs"] = numberFunction("abs", 1,
+		func(args []int64) int64 {
+			if args[0] < 0 {
+				return -args[0]
+			}
+			return args[0]
+		},
+		func(args []float64) float64 { return math.Abs(args[0]) })
+	exports["min"] = numberFunction("min", -1, slices.Min, slices.Min)
+	exports["max"] = numberFunction("max", -1, slices.Max, slices.Max)
+
+	return &object.Module{Name: "math", Path: "math", Exports: exports}
+}
+
+// mathFunction returns a builtin that calls fn with its n arguments promoted
+// to floats and always returns a float.
+func mathFunction(name string, n int, fn func([]float64) float64) *object.Builtin {
+	return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+		values, _, err := numberArguments(name, n, args)
+		if err != nil {
+			return err
+		}
+		return &object.Float{Value: fn(values)}
+	}}
+}
+
+// numberFunction is like mathFunction, but calls intFn with the integers if
+// all arguments are integers, like the arithmetic operators do. n < 0
+// accepts one or more arguments.
+func numberFunction(name string, n int, intFn func([]int64) int64, floatFn func([]float64) float64) *object.Builtin {
+	return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+		values, isFloat, err := numberArguments(name, n, args)
+		if err != nil {
+			return err
+		}
+		if isFloat {
+			return &object.Float{Value: floatFn(values)}
+		}
+		integers := make([]int64, len(args))
+		for i, arg := range args {
+			integers[i] = arg.(*object.Integer).Value
+		}
+		return &object.Integer{Value: intFn(integers)}
+	}}
+}
+
+// numberArguments converts args to floats the way getValueAndType does and
+// reports whether any of them was a float.
+func numberArguments(name string, n int, args []object.Object) ([]float64, bool, *object.Error) {
+	switch {
+	case n >= 0 && len(args) != n:
+		return nil, false, createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want=%d, got=%d", name, n, len(args))
+	case n < 0 && len(args) == 0:
+		return nil, false, createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want at least 1, got=0", name)
+	}
+
+	values := make([]float64, len(args))
+	anyFloat := false
+	for i, arg := range args {
+		value, isFloat := getValueAndType(arg)
+		if value == nil {
+			return nil, false, createError(object.TYPE_ERROR, "argument to %s must be a number, got %s", name, arg.Type())
+		}
+		values[i] = value.(float64)
+		anyFloat = anyFloat || isFloat
+	}
+	return values, anyFloat, nil
+}
@@ -0,0 +1,108 @@
+package eval
+
+import (
+	"context"
+	"interpreter/object"
+	"math"
+	"testing"
+)
+
+func TestMathModule(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`math.sqrt(16)`, 4.0},
+		{`math.sqrt(2.25)`, 1.5},
+		{`math.pow(2, 10)`, 1024.0},
+		{`math.pow(4, 0.5)`, 2.0},
+		{`math.floor(2.7)`, 2.0},
+		{`math.ceil(2.1)`, 3.0},
+		{`math.round(2.5)`, 3.0},
+		{`math.round(-2.5)`, -3.0},
+		{`math.sin(0)`, 0.0},
+		{`math.cos(0)`, 1.0},
+		{`math.tan(0)`, 0.0},
+		{`math.atan2(1, 1) * 4.0`, math.Pi},
+		{`math.exp(0)`, 1.0},
+		{`math.log(math.e)`, 1.0},
+		{`math.pi`, math.Pi},
+		{`math.abs(-3)`, 3},
+		{`math.abs(-3.5)`, 3.5},
+		{`math.min(3, 1, 2)`, 1},
+		{`math.min(3, 1.5, 2)`, 1.5},
+		{`math.max(9007199254740993, 1)`, 9007199254740993},
+		{`math.max(-1.0)`, -1.0},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(`import "math"; ` + tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		}
+	}
+}
+
+func TestMathModuleErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind string
+		expected     string
+	}{
+		{`math.sqrt()`, object.ARGUMENT_ERROR, "wrong number of arguments for sqrt: want=1, got=0"},
+		{`math.pow(1)`, object.ARGUMENT_ERROR, "wrong number of arguments for pow: want=2, got=1"},
+		{`math.max()`, object.ARGUMENT_ERROR, "wrong number of arguments for max: want at least 1, got=0"},
+		{`math.sqrt("4")`, object.TYPE_ERROR, "argument to sqrt must be a number, got STRING"},
+		{`math.min(1, true)`, object.TYPE_ERROR, "argument to min must be a number, got BOOLEAN"},
+		{`math.tau`, object.NAME_ERROR, "module math has no export tau"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(`import "math"; ` + tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind || errObj.Message != tt.expected {
+			t.Errorf("%s: wrong error. expected=%s: %s, got=%s: %s",
+				tt.input, tt.expectedKind, tt.expected, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestMathModuleWithoutImportCapability(t *testing.T) {
+	c := NewContext()
+	c.Capabilities = map[Capability]bool{}
+
+	evaluated := c.Run(context.Background(), parseProgram(t, `import "math"; math.sqrt(4)`), object.NewEnvironment())
+	testFloatObject(t, evaluated, 2)
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"0.1 + 0.2", "0.30000000000000004"},
+		{"2.5", "2.5"},
+		{"1.0 / 3.0", "0.3333333333333333"},
+		{"0.000001", "1e-06"},
+		{"0.0001", "0.0001"},
+		{"-4.0 / 2.0", "-2.0"},
+		{"100000.0", "100000.0"},
+		{"1000000.0 * 1000000.0", "1e+12"},
+		{"0.0", "0.0"},
+		{"import \"math\"; math.inf", "+Inf"},
+		{"import \"math\"; -math.inf", "-Inf"},
+		{"import \"math\"; math.inf - math.inf", "NaN"},
+	}
+
+	for _, tt := range tests {
+		if got := testEval(tt.input).Inspect(); got != tt.expected {
+			t.Errorf("%s: wrong output. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
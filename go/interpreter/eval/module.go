@@ -0,0 +1,190 @@
+package eval
+
+import (
+	"context"
+	"interpreter/ast"
+	"interpreter/diag"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Extension is appended to import paths that name no existing file.
+const Extension = ".mk"
+
+// Loader finds, evaluates and caches the modules a program imports. Every
+// module is evaluated once, later imports of the same file share its object.
+type Loader struct {
+	// SearchPath lists the directories tried after the directory of the
+	// importing file.
+	SearchPath []string
+	// Prepare runs the passes between parsing and evaluation on a module
+	// and returns the program to evaluate. It gets the context of the run
+	// importing the module, which macros are expanded in. A module the
+	// passes report errors for is not evaluated. nil evaluates modules as
+	// parsed.
+	Prepare func(ctx context.Context, c *Context, program *ast.Program) (*ast.Program, []diag.Diagnostic)
+
+	modules map[string]*object.Module
+	// chain holds the files currently being evaluated, the innermost last.
+	chain []string
+}
+
+// NewLoader returns a loader for the program in file. Imports of the
+// program are resolved relative to its directory, or to the working
+// directory if file is empty.
+func NewLoader(file string, searchPath []string) *Loader {
+	if file != "" {
+		file = filepath.Clean(file)
+	}
+	return &Loader{
+		SearchPath: searchPath,
+		modules:    make(map[string]*object.Module),
+		chain:      []string{file},
+	}
+}
+
+func (c *Context) evalImportStatement(stmt *ast.ImportStatement, env *object.Environment) object.Object {
+	if module, ok := builtinModules[stmt.Path]; ok {
+		bind(env, stmt.Name, module)
+		return nil
+	}
+	if err := c.allow(CapImport); err != nil {
+		return err
+	}
+	module := c.Loader.load(c, stmt)
+	if isError(module) {
+		return module
+	}
+	bind(env, stmt.Name, module)
+	return nil
+}
+
+func (l *Loader) load(c *Context, stmt *ast.ImportStatement) object.Object {
+	path, ok := l.find(stmt.Path)
+	if !ok {
+		return createError(object.IMPORT_ERROR, "module not found: %s", stmt.Path)
+	}
+	if module, ok := l.modules[path]; ok {
+		return module
+	}
+	for i, file := range l.chain {
+		if file == path {
+			cycle := append(append([]string{}, l.chain[i:]...), path)
+			return createError(object.IMPORT_ERROR, "circular import: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return createError(object.IMPORT_ERROR, "cannot read module %s: %s", stmt.Path, err)
+	}
+	p := parser.New(lexer.New(string(source)))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return createError(object.IMPORT_ERROR, "cannot parse module %s: %s", stmt.Path, strings.Join(p.Errors(), "; "))
+	}
+	if l.Prepare != nil {
+		var diagnostics []diag.Diagnostic
+		program, diagnostics = l.Prepare(c.ctx, c, program)
+		if diag.HasErrors(diagnostics) {
+			return createError(object.IMPORT_ERROR, "cannot check module %s: %s", stmt.Path, errorMessages(diagnostics))
+		}
+	}
+
+	l.chain = append(l.chain, path)
+	defer func() { l.chain = l.chain[:len(l.chain)-1] }()
+
+	env := object.NewEnvironment()
+	if err, ok := c.eval(program, env).(*object.Error); ok {
+		return createError(err.Kind, "in module %s:%d:%d: %s", path, err.Line, err.Column, err.Message)
+	}
+
+	module := &object.Module{Name: stmt.Name.Value, Path: path, Exports: exports(program, env)}
+	l.modules[path] = module
+	return module
+}
+
+// errorMessages joins the diagnostics with the severity error.
+func errorMessages(diagnostics []diag.Diagnostic) string {
+	messages := []string{}
+	for _, d := range diagnostics {
+		if d.Severity == diag.Error {
+			messages = append(messages, d.String())
+		}
+	}
+	return strings.Join(messages, "; ")
+}
+
+// find returns the cleaned path of the file an import refers to.
+func (l *Loader) find(name string) (string, bool) {
+	dirs := []string{filepath.Dir(l.chain[len(l.chain)-1])}
+	if filepath.IsAbs(name) {
+		dirs = []string{""}
+	} else {
+		dirs = append(dirs, l.SearchPath...)
+	}
+
+	for _, dir := range dirs {
+		for _, candidate := range []string{name, name + Extension} {
+			path := filepath.Join(dir, filepath.FromSlash(candidate))
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return filepath.Clean(path), true
+			}
+		}
+	}
+	return "", false
+}
+
+// exports collects the values of the exported lets and structs of a module.
+// Lets inside functions are never exported.
+func exports(program *ast.Program, env *object.Environment) map[string]object.Object {
+	result := make(map[string]object.Object)
+	ast.Walk(program, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.StructStatement:
+			if n.Exported {
+				if val, ok := env.Get(n.Name.Value); ok {
+					result[n.Name.Value] = val
+				}
+			}
+		case *ast.LetStatement:
+			if n.Exported {
+				for _, name := range n.Names() {
+					if val, ok := env.Get(name.Value); ok {
+						result[name.Value] = val
+					}
+				}
+			}
+		}
+		return true
+	})
+	return result
+}
+
+func (c *Context) evalSelectorExpression(exp *ast.SelectorExpression, env *object.Environment) object.Object {
+	left := c.eval(exp.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	switch left := left.(type) {
+	case *object.Module:
+		val, ok := left.Exports[exp.Name.Value]
+		if !ok {
+			return createError(object.NAME_ERROR, "module %s has no export %s", left.Name, exp.Name.Value)
+		}
+		return val
+	case *object.ErrorValue:
+		return errorField(left.Err, exp.Name.Value)
+	case *object.Record:
+		return recordField(left, exp.Name.Value)
+	default:
+		return createError(object.TYPE_ERROR, "cannot select .%s from %s", exp.Name.Value, left.Type())
+	}
+}
@@ -0,0 +1,102 @@
+package eval
+
+import (
+	"context"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestImport(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "main.mk", "")
+	writeModule(t, dir, "lib/math.mk", `
+		export let square = fn(x) { x * x };
+		export let calls = 0;
+		let hidden = 1;
+	`)
+	writeModule(t, dir, "shapes.mk", `
+		import "lib/math"
+		export let area = fn(side) { math.square(side) };
+	`)
+	writeModule(t, dir, "counter", `export let n = 1;`)
+	writeModule(t, dir, "geo.mk", `export struct Point { x, y }; struct Hidden { a }`)
+	writeModule(t, dir, "cycle/a.mk", `import "b"; 1`)
+	writeModule(t, dir, "cycle/b.mk", `import "a"; 2`)
+	writeModule(t, dir, "broken.mk", `let x = 1 + true;`)
+	writeModule(t, filepath.Join(dir, "vendor"), "extra.mk", `export let answer = 42;`)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math"; math.square(3)`, "9"},
+		{`import "shapes"; shapes.area(4)`, "16"},
+		{`import "counter"; counter.n`, "1"},
+		{`import "lib/math"; math`, "module math"},
+		{`import "extra"; extra.answer`, "42"},
+		{`import "geo"; geo.Point(1, 2)`, "Point{x: 1, y: 2}"},
+		{`import "geo"; match (geo.Point(1, 2)) { geo.Point(x, y) => x + y }`, "3"},
+		{`import "geo"; geo.Hidden`, "ERROR: 1:18: NameError: module geo has no export Hidden"},
+		{`import "lib/math"; math.hidden`, "ERROR: 1:24: NameError: module math has no export hidden"},
+		{`let x = 1; x.y`, "ERROR: 1:13: TypeError: cannot select .y from INTEGER"},
+		{`import "missing"`, "ERROR: 1:1: ImportError: module not found: missing"},
+		{
+			`import "broken"`,
+			"ERROR: 1:1: TypeError: in module " + filepath.Join(dir, "broken.mk") + ":1:11: type mismatch: INTEGER + BOOLEAN",
+		},
+	}
+
+	for _, tt := range tests {
+		loader := NewLoader(filepath.Join(dir, "main.mk"), []string{filepath.Join(dir, "vendor")})
+		evaluated := evalModuleTest(t, loader, tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+
+	a := filepath.Join(dir, "cycle", "a.mk")
+	b := filepath.Join(dir, "cycle", "b.mk")
+	evaluated := evalModuleTest(t, NewLoader(a, nil), `import "b"; 1`)
+	expected := "circular import: " + strings.Join([]string{a, b, a}, " -> ")
+	if !strings.Contains(evaluated.Inspect(), expected) {
+		t.Errorf("cycle not reported. expected=%q, got=%q", expected, evaluated.Inspect())
+	}
+}
+
+func TestImportEvaluatesOnce(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "state.mk", `export let f = fn() { 1 };`)
+	writeModule(t, dir, "user.mk", `import "state"; export let g = state.f;`)
+
+	loader := NewLoader(filepath.Join(dir, "main.mk"), nil)
+	evaluated := evalModuleTest(t, loader, `import "state"; import "user"; state.f == user.g`)
+	testBooleanObject(t, evaluated, true)
+}
+
+func writeModule(t *testing.T, dir, name, source string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func evalModuleTest(t *testing.T, loader *Loader, input string) object.Object {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	c := NewContext()
+	c.Loader = loader
+	return c.Run(context.Background(), program, object.NewEnvironment())
+}
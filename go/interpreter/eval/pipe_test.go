@@ -0,0 +1,51 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let double = fn(x) { x * 2 }; 3 |> double`, `6`},
+		{`let add = fn(a, b) { a + b }; 1 |> add(2)`, `3`},
+		{`range(1, 6) |> map(fn(x) { x * x }) |> filter(fn(x) { x > 5 }) |> reduce(fn(a, b) { a + b })`, `50`},
+		{`"a,b" |> split(",") |> join("-") |> upper`, `A-B`},
+		{`1 + 2 |> fn(x) { x * 10 }`, `30`},
+		{`let sub = fn(a, b) { a - b }; 10 |> sub(1) |> sub(2)`, `7`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestPipeErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"[1, 2]\n  |> map(fn(x) { x })\n  |> len(1)", "ERROR: 3:6: ArgumentError: wrong number of arguments: want=1, got=2"},
+		{"1 |> 2", "ERROR: 1:6: TypeError: not a function: INTEGER"},
+		{"[0] |> map(fn(x) { 1 / x })", "ERROR: 1:22: ZeroDivisionError: division by zero\n\tin fn\n\tin map at index 0 called at 1:8"},
+		{"let f = fn(x) { throw x }; 1 |> f |> f", "ERROR: 1:17: Error: 1\n\tin f called at 1:33"},
+		{"(1 / 0) |> len", "ERROR: 1:4: ZeroDivisionError: division by zero"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"interpreter/token"
+	"strconv"
+)
+
+// quote returns node as code. Calls of unquote inside it are evaluated and
+// their results are spliced into a copy of the tree, so the quote expression
+// itself stays intact for the next evaluation.
+func (c *Context) quote(node ast.Node, env *object.Environment) object.Object {
+	node, err := c.evalUnquoteCalls(ast.Copy(node), env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+func (c *Context) evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+	node := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil || !isCallTo(call, "unquote") {
+			return node
+		}
+		if len(call.Arguments) != 1 {
+			err = createError(object.ARGUMENT_ERROR, "wrong number of arguments for unquote: want=1, got=%d", len(call.Arguments))
+			err.Line, err.Column = ast.Pos(call)
+			return node
+		}
+
+		unquoted := c.eval(call.Arguments[0], env)
+		if e, ok := unquoted.(*object.Error); ok {
+			err = e
+			return node
+		}
+		converted, ok := convertObjectToASTNode(unquoted, call.Token)
+		if !ok {
+			err = createError(object.TYPE_ERROR, "cannot unquote %s", unquoted.Type())
+			err.Line, err.Column = ast.Pos(call)
+			return node
+		}
+		return converted
+	})
+	return node, err
+}
+
+// convertObjectToASTNode turns a value back into code. The new literals get
+// the position of the unquote call they replace.
+func convertObjectToASTNode(obj object.Object, at token.Token) (ast.Node, bool) {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		t := token.Token{Type: token.INT, Literal: strconv.FormatInt(obj.Value, 10), Line: at.Line, Column: at.Column}
+		return &ast.IntegerLiteral{Token: t, Value: obj.Value}, true
+	case *object.Float:
+		t := token.Token{Type: token.FLOAT, Literal: strconv.FormatFloat(obj.Value, 'f', -1, 64), Line: at.Line, Column: at.Column}
+		return &ast.FloatLiteral{Token: t, Value: obj.Value}, true
+	case *object.Boolean:
+		t := token.Token{Type: token.FALSE, Literal: "false", Line: at.Line, Column: at.Column}
+		if obj.Value {
+			t.Type, t.Literal = token.TRUE, "true"
+		}
+		return &ast.Boolean{Token: t, Value: obj.Value}, true
+	case *object.String:
+		t := token.Token{Type: token.STRING, Literal: obj.Value, Line: at.Line, Column: at.Column}
+		return &ast.StringLiteral{Token: t, Value: obj.Value}, true
+	case *object.Quote:
+		// a copy, as the same quote may be spliced in several places
+		return ast.Copy(obj.Node), true
+	default:
+		return nil, false
+	}
+}
+
+// isCallTo reports whether call calls the identifier name directly.
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
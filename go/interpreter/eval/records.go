@@ -0,0 +1,244 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+)
+
+// construct creates a record of type rt. Fields are passed like the
+// arguments of a function with the fields as parameters.
+func construct(rt *object.RecordType, args []object.Object) object.Object {
+	values, err := bindArguments(&object.Function{Name: rt.Name, Parameters: rt.Fields}, args)
+	if err != nil {
+		return err
+	}
+	return &object.Record{Struct: rt, Values: values}
+}
+
+// recordField returns the field name of record, or the method name bound to
+// record.
+func recordField(record *object.Record, name string) object.Object {
+	i := record.Struct.FieldIndex(name)
+	if i < 0 {
+		if method, ok := record.Struct.Methods[name]; ok {
+			return &object.BoundMethod{Receiver: record, Method: method}
+		}
+		return createError(object.NAME_ERROR, "%s has no field %s", record.Struct.Name, name)
+	}
+	return record.Values[i]
+}
+
+// evalAssignExpression sets a field of a record, or an element of an array
+// or hash, and returns the new value.
+func (c *Context) evalAssignExpression(exp *ast.AssignExpression, env *object.Environment) object.Object {
+	if target, ok := exp.Target.(*ast.IndexExpression); ok {
+		return c.evalIndexAssignment(target, exp.Value, env)
+	}
+	target := exp.Target.(*ast.SelectorExpression)
+	left := c.eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+	value := c.eval(exp.Value, env)
+	if isError(value) {
+		return value
+	}
+
+	name := target.Name.Value
+	record, ok := left.(*object.Record)
+	if !ok {
+		err := createError(object.TYPE_ERROR, "cannot assign .%s of %s", name, left.Type())
+		return locateError(target, err)
+	}
+	i := record.Struct.FieldIndex(name)
+	if i < 0 {
+		err := createError(object.NAME_ERROR, "%s has no field %s", record.Struct.Name, name)
+		return locateError(target, err)
+	}
+	record.Values[i] = value
+	return value
+}
+
+// evalMethodDeclaration adds a method to its struct. The receiver becomes
+// the first parameter of the method.
+func (c *Context) evalMethodDeclaration(decl *ast.MethodDeclaration, env *object.Environment) object.Object {
+	obj := c.eval(decl.Struct, env)
+	if isError(obj) {
+		return obj
+	}
+	rt, ok := obj.(*object.RecordType)
+	if !ok {
+		return locateError(decl.Struct, createError(object.TYPE_ERROR, "%s is not a struct", decl.Struct))
+	}
+	name := decl.Name.Value
+	if rt.FieldIndex(name) >= 0 {
+		return locateError(decl.Name, createError(object.NAME_ERROR, "%s has a field %s", rt.Name, name))
+	}
+
+	fn := decl.Function
+	method := &object.Function{Name: fn.Name, Parameters: append([]*ast.Identifier{decl.Receiver}, fn.Parameters...),
+		Rest: fn.Rest, Body: fn.Body, Env: env, Generator: fn.Generator}
+	if fn.Defaults != nil {
+		method.Defaults = append([]ast.Expression{nil}, fn.Defaults...)
+	}
+	if rt.Methods == nil {
+		rt.Methods = make(map[string]*object.Function)
+	}
+	rt.Methods[name] = method
+	return NULL
+}
+
+// infixHooks are the methods overloading infix operators. != calls __eq__
+// and negates its result.
+var infixHooks = map[string]string{
+	"+":  "__add__",
+	"-":  "__sub__",
+	"*":  "__mul__",
+	"/":  "__div__",
+	"==": "__eq__",
+	"!=": "__eq__",
+	"<":  "__lt__",
+	">":  "__gt__",
+}
+
+// reflectedHooks are called on the right operand if the left one has no
+// hook: 2 * p calls p.__rmul__(2), 2 < p calls p.__gt__(2).
+var reflectedHooks = map[string]string{
+	"+":  "__radd__",
+	"-":  "__rsub__",
+	"*":  "__rmul__",
+	"/":  "__rdiv__",
+	"==": "__eq__",
+	"!=": "__eq__",
+	"<":  "__gt__",
+	">":  "__lt__",
+}
+
+// evalInfixHook calls the method overloading the operator of node if an
+// operand is a record with such a method. ok is false if none has one.
+func (c *Context) evalInfixHook(node *ast.InfixExpression, left, right object.Object) (result object.Object, ok bool) {
+	method, args := recordMethod(left, infixHooks[node.Operator]), []object.Object{left, right}
+	if method == nil {
+		method, args = recordMethod(right, reflectedHooks[node.Operator]), []object.Object{right, left}
+	}
+	if method == nil {
+		return nil, false
+	}
+	result = traceAt(node, c.applyFunction(method, args))
+	if isError(result) {
+		return result, true
+	}
+	switch node.Operator {
+	case "==", "<", ">":
+		return getNativeBooleanObject(isTruthy(result)), true
+	case "!=":
+		return getNativeBooleanObject(!isTruthy(result)), true
+	}
+	return result, true
+}
+
+// evalPrefixHook calls __neg__ for -record.
+func (c *Context) evalPrefixHook(node *ast.PrefixExpression, right object.Object) (result object.Object, ok bool) {
+	if node.Operator != "-" {
+		return nil, false
+	}
+	method := recordMethod(right, "__neg__")
+	if method == nil {
+		return nil, false
+	}
+	return traceAt(node, c.applyFunction(method, []object.Object{right})), true
+}
+
+// recordMethod returns the method name of obj, nil if obj is no record or
+// its struct has no such method.
+func recordMethod(obj object.Object, name string) *object.Function {
+	record, ok := obj.(*object.Record)
+	if !ok {
+		return nil
+	}
+	return record.Struct.Methods[name]
+}
+
+// matchRecord matches a constructor pattern like Point(x, y: 0) against
+// value. Positional patterns match the fields in order, named ones the
+// field of that name.
+func (c *Context) matchRecord(pattern *ast.CallExpression, value object.Object, env *object.Environment) (bool, *object.Error) {
+	rt, err := c.patternStruct(pattern, env)
+	if err != nil {
+		return false, err
+	}
+	record, ok := value.(*object.Record)
+	if !ok || record.Struct != rt {
+		return false, nil
+	}
+	for i, arg := range pattern.Arguments {
+		field, sub, err := patternField(rt, i, arg)
+		if err != nil {
+			return false, err
+		}
+		if matched, err := c.matchPattern(sub, record.Values[field], env); !matched || err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// destructureRecord is matchRecord for the patterns of lets.
+func (c *Context) destructureRecord(pattern *ast.CallExpression, value object.Object, env *object.Environment) object.Object {
+	rt, err := c.patternStruct(pattern, env)
+	if err != nil {
+		return locateError(pattern.Function, err)
+	}
+	record, ok := value.(*object.Record)
+	if !ok || record.Struct != rt {
+		err := createError(object.TYPE_ERROR, "cannot destructure %s as %s", describeType(value), rt.Name)
+		return locateError(pattern.Function, err)
+	}
+	for i, arg := range pattern.Arguments {
+		field, sub, err := patternField(rt, i, arg)
+		if err != nil {
+			return locateError(arg, err)
+		}
+		if err := c.destructure(sub, record.Values[field], env); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *Context) patternStruct(pattern *ast.CallExpression, env *object.Environment) (*object.RecordType, *object.Error) {
+	ctor := c.eval(pattern.Function, env)
+	if err, ok := ctor.(*object.Error); ok {
+		return nil, err
+	}
+	rt, ok := ctor.(*object.RecordType)
+	if !ok {
+		return nil, createError(object.TYPE_ERROR, "%s is not a struct", pattern.Function)
+	}
+	return rt, nil
+}
+
+// patternField returns the index of the field the i-th argument of a
+// constructor pattern matches and the pattern for it.
+func patternField(rt *object.RecordType, i int, arg ast.Expression) (int, ast.Expression, *object.Error) {
+	named, ok := arg.(*ast.NamedArgument)
+	if !ok {
+		if i >= len(rt.Fields) {
+			return 0, nil, createError(object.ARGUMENT_ERROR, "too many fields in pattern for %s", rt.Name)
+		}
+		return i, arg, nil
+	}
+	field := rt.FieldIndex(named.Name.Value)
+	if field < 0 {
+		return 0, nil, createError(object.NAME_ERROR, "%s has no field %s", rt.Name, named.Name.Value)
+	}
+	return field, named.Value, nil
+}
+
+// describeType names the type of obj, the struct name for records.
+func describeType(obj object.Object) string {
+	if record, ok := obj.(*object.Record); ok {
+		return record.Struct.Name
+	}
+	return string(obj.Type())
+}
@@ -0,0 +1,168 @@
+package eval
+
+import (
+	"interpreter/object"
+	"testing"
+)
+
+func TestRecords(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, y }; Point(1, 2)`, `Point{x: 1, y: 2}`},
+		{`struct Point { x, y }; Point(y: 2, x: 1)`, `Point{x: 1, y: 2}`},
+		{`struct Point { x, y }; Point`, `struct Point { x, y }`},
+		{`struct Point { x, y }; let p = Point(3, 4); p.x * p.y`, `12`},
+		{`struct Point { x, y }; let p = Point(3, 4); p.x = 10; p`, `Point{x: 10, y: 4}`},
+		{`struct Point { x, y }; let p = Point(3, 4); let q = p; q.y = 0; p.y`, `0`},
+		{`struct Box { v }; let a = Box(1); let b = Box(2); a.v = b.v = 5; [a.v, b.v]`, `[5, 5]`},
+		{`struct Point { x, y }; Point(1, 2) == Point(1, 2)`, `true`},
+		{`struct Point { x, y }; Point(1, 2) == Point(1.0, 2)`, `true`},
+		{`struct Point { x, y }; Point(1, 2) != Point(2, 1)`, `true`},
+		{`struct A { v }; struct B { v }; A(1) == B(1)`, `false`},
+		{`struct Line { from, to }; struct P { x }; Line(P(1), P(2)) == Line(P(1), P(2))`, `true`},
+		{`struct Point { x, y }; Point(1, 2) == 1`, `false`},
+		{`struct Empty {}; Empty()`, `Empty{}`},
+		{`struct Name { first, last }; Name("Ada", "Lovelace")`, `Name{first: "Ada", last: "Lovelace"}`},
+		{`struct P { x }; let p = P(1); p.x = p; p`, `P{x: P{...}}`},
+		{`struct P { x }; let p = P(1); p.x = [p]; "${p}"`, `P{x: [P{...}]}`},
+		{`struct P { x }; let p = P(1); p.x = p; p == p`, `true`},
+		{`struct P { x }; let p = P(1); let q = P(1); p.x = q; q.x = p; p == q`, `true`},
+		{`struct P { x }; let p = P(1); let q = P(2); p.x = p; q.x = q; p == q`, `true`},
+		{`struct P { x, y }; let p = P(1, 1); let q = P(1, 2); p.x = p; q.x = q; p == q`, `false`},
+		{`struct Point { x, y }; match (Point(0, 5)) { Point(0, y) => y, Point(x, _) => x }`, `5`},
+		{`struct Point { x, y }; match (Point(3, 5)) { Point(0, y) => y, Point(x, _) => x }`, `3`},
+		{`struct Point { x, y }; match (Point(3, 5)) { Point(y: 5, x: x) => x, _ => 0 }`, `3`},
+		{`struct A { v }; struct B { v }; match (B(1)) { A(v) => "a", B(v) => "b" }`, `b`},
+		{`struct Point { x, y }; match ([1, 2]) { Point(x, y) => 1, _ => 2 }`, `2`},
+		{`struct Point { x, y }; let [Point(a, b), c] = [Point(1, 2), 3]; a + b + c`, `6`},
+		{`struct Point { x, y }; [Point(1, 2)] |> map(fn(p) { p.x })`, `[1]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestRecordErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"struct Point { x, y };\nPoint(1, 2).z", "ERROR: 2:12: NameError: Point has no field z"},
+		{"struct Point { x, y };\nlet p = Point(1, 2);\np.z = 1", "ERROR: 3:2: NameError: Point has no field z"},
+		{"struct Point { x, y };\nPoint(1)", "ERROR: 2:6: ArgumentError: wrong number of arguments for Point(x, y): want=2, got=1"},
+		{"struct Point { x, y };\nPoint(1, z: 2)", "ERROR: 2:6: ArgumentError: Point(x, y) has no parameter z"},
+		{"let h = {};\nh.x = 1", "ERROR: 2:2: TypeError: cannot assign .x of HASH"},
+		{"struct Point { x, y };\nmatch (Point(1, 2)) { Point(a, b, c) => 1 }", "ERROR: 2:1: ArgumentError: too many fields in pattern for Point"},
+		{"let f = 1;\nmatch (1) { f(x) => x }", "ERROR: 2:1: TypeError: f is not a struct"},
+		{"struct Point { x, y };\nlet [Point(a, b)] = [1];", "ERROR: 2:6: TypeError: cannot destructure INTEGER as Point"},
+		{"struct P { x }; struct Q { x };\nlet [a] = Q(1);", "ERROR: 2:5: TypeError: cannot destructure Q as ARRAY"},
+		{"struct Point { x, y };\nPoint(1, 2) + 1", "ERROR: 2:13: TypeError: type mismatch: RECORD + INTEGER"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestMethods(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct P { x, y }; fn (p P) sum() { p.x + p.y }; P(1, 2).sum()`, `3`},
+		{`struct P { x, y }; fn (p P) scale(k, d = 0) { P(p.x * k + d, p.y * k + d) }; P(1, 2).scale(2, d: 1)`, `P{x: 3, y: 5}`},
+		{`struct P { x }; fn (p P) move(dx) { p.x = p.x + dx; p }; let p = P(1); p.move(2); p.x`, `3`},
+		{`struct P { x }; fn (p P) get() { p.x }; let g = P(7).get; g()`, `7`},
+		{`struct P { x }; fn (p P) get() { p.x }; [P(1), P(2)] |> map(fn(p) { p.get() })`, `[1, 2]`},
+		{`struct P { x }; fn (p P) get() { p.x }; P(1).get`, `method P.get`},
+		{`struct P { x }; fn (p P) get() { p.x }`, `null`},
+		{`struct N { n }; fn (c N) down() { if (c.n == 0) { return 0 }; c.n = c.n - 1; c.down() }; N(20000).down()`, `0`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestOperatorHooks(t *testing.T) {
+	vec := `struct V { x, y };
+fn (a V) __add__(b) { V(a.x + b.x, a.y + b.y) };
+fn (a V) __sub__(b) { V(a.x - b.x, a.y - b.y) };
+fn (a V) __mul__(k) { V(a.x * k, a.y * k) };
+fn (a V) __rmul__(k) { a * k };
+fn (a V) __div__(k) { V(a.x / k, a.y / k) };
+fn (a V) __neg__() { V(-a.x, -a.y) };
+fn (a V) __eq__(b) { a.x * a.x + a.y * a.y == b.x * b.x + b.y * b.y };
+fn (a V) __lt__(b) { a.x < b.x };
+fn (a V) __gt__(b) { if (a.x > b.x) { 1 } };
+`
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{vec + `V(1, 2) + V(3, 4)`, `V{x: 4, y: 6}`},
+		{vec + `V(1, 2) - V(3, 4)`, `V{x: -2, y: -2}`},
+		{vec + `V(1, 2) * 3`, `V{x: 3, y: 6}`},
+		{vec + `3 * V(1, 2)`, `V{x: 3, y: 6}`},
+		{vec + `V(4, 2) / 2`, `V{x: 2, y: 1}`},
+		{vec + `-V(1, 2)`, `V{x: -1, y: -2}`},
+		{vec + `V(3, 4) == V(0, 5)`, `true`},
+		{vec + `V(3, 4) != V(0, 5)`, `false`},
+		{vec + `V(1, 0) < V(2, 0)`, `true`},
+		{vec + `V(3, 0) > V(2, 0)`, `true`},
+		{vec + `V(1, 0) > V(2, 0)`, `false`},
+		{vec + `[V(1, 0), V(2, 0)] |> reduce(fn(acc, v) { acc + v }, V(0, 0))`, `V{x: 3, y: 0}`},
+		{`struct P { x }; fn (p P) __eq__(o) { true }; P(1) == 2`, `true`},
+		{`struct P { x }; fn (p P) __eq__(o) { true }; 2 == P(1)`, `true`},
+		{`struct P { x }; fn (p P) __gt__(o) { p.x > o }; 1 < P(2)`, `true`},
+		{`struct P { x }; P(1) == P(1)`, `true`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestMethodErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let P = 1;\nfn (p P) f() { 1 }", "ERROR: 2:7: TypeError: P is not a struct"},
+		{"struct P { x };\nfn (p P) x() { 1 }", "ERROR: 2:10: NameError: P has a field x"},
+		{"struct P { x };\nP(1).f()", "ERROR: 2:5: NameError: P has no field f"},
+		{"struct P { x };\nfn (p P) f(a) { a };\nP(1).f()", "ERROR: 3:7: ArgumentError: wrong number of arguments for P.f(p, a): want=2, got=1"},
+		{"struct P { x };\nP(1) + P(2)", "ERROR: 2:6: TypeError: unknown operator: RECORD + RECORD"},
+		{"struct P { x };\nfn (p P) __add__(o) { throw \"no\" };\nP(1) + 1", "ERROR: 2:23: Error: no\n\tin P.__add__ called at 3:1"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+}
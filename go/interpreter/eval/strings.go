@@ -0,0 +1,212 @@
+package eval
+
+import (
+	"fmt"
+	"interpreter/ast"
+	"interpreter/object"
+	"strings"
+)
+
+// stringArguments checks that args are n strings and returns their values.
+func stringArguments(name string, n int, args []object.Object) ([]string, *object.Error) {
+	if len(args) != n {
+		return nil, createError(object.ARGUMENT_ERROR, "wrong number of arguments for %s: want=%d, got=%d", name, n, len(args))
+	}
+	values := make([]string, n)
+	for i, arg := range args {
+		s, ok := arg.(*object.String)
+		if !ok {
+			return nil, createError(object.TYPE_ERROR, "argument %d to %s must be STRING, got %s", i+1, name, arg.Type())
+		}
+		values[i] = s.Value
+	}
+	return values, nil
+}
+
+// stringFunction returns a builtin that maps its n string arguments to a
+// string.
+func stringFunction(name string, n int, fn func([]string) string) builtinFunction {
+	return func(c *Context, args ...object.Object) object.Object {
+		values, err := stringArguments(name, n, args)
+		if err != nil {
+			return err
+		}
+		return c.newString(fn(values))
+	}
+}
+
+// newString returns s as a string object unless it exceeds the size limit.
+func (c *Context) newString(s string) object.Object {
+	if err := c.checkSize(len(s)); err != nil {
+		return err
+	}
+	return &object.String{Value: s}
+}
+
+func builtinSplit(args ...object.Object) object.Object {
+	values, err := stringArguments("split", 2, args)
+	if err != nil {
+		return err
+	}
+	parts := strings.Split(values[0], values[1])
+	elements := make([]object.Object, len(parts))
+	for i, part := range parts {
+		elements[i] = &object.String{Value: part}
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinJoin joins the elements of an array, which are printed like by
+// print, with a separator.
+func (c *Context) builtinJoin(args ...object.Object) object.Object {
+	if len(args) != 2 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for join: want=2, got=%d", len(args))
+	}
+	array, ok := args[0].(*object.Array)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to join must be ARRAY, got %s", args[0].Type())
+	}
+	sep, ok := args[1].(*object.String)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 2 to join must be STRING, got %s", args[1].Type())
+	}
+	parts := make([]string, len(array.Elements))
+	for i, el := range array.Elements {
+		parts[i] = el.Inspect()
+	}
+	return c.newString(strings.Join(parts, sep.Value))
+}
+
+func builtinContains(args ...object.Object) object.Object {
+	values, err := stringArguments("contains", 2, args)
+	if err != nil {
+		return err
+	}
+	return getNativeBooleanObject(strings.Contains(values[0], values[1]))
+}
+
+// builtinSubstr returns up to length characters of a string from start.
+// Without length it returns the rest of the string.
+func builtinSubstr(args ...object.Object) object.Object {
+	if len(args) != 2 && len(args) != 3 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for substr: want=2 or 3, got=%d", len(args))
+	}
+	s, ok := args[0].(*object.String)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to substr must be STRING, got %s", args[0].Type())
+	}
+	bounds := []int64{0, -1}
+	for i, arg := range args[1:] {
+		n, ok := arg.(*object.Integer)
+		if !ok {
+			return createError(object.TYPE_ERROR, "argument %d to substr must be INTEGER, got %s", i+2, arg.Type())
+		}
+		if n.Value < 0 {
+			return createError(object.ARGUMENT_ERROR, "negative argument %d to substr: %d", i+2, n.Value)
+		}
+		bounds[i] = n.Value
+	}
+
+	chars := []rune(s.Value)
+	start := min(bounds[0], int64(len(chars)))
+	end := int64(len(chars))
+	if bounds[1] >= 0 {
+		// clamping the length first keeps start+length from overflowing
+		end = start + min(bounds[1], end-start)
+	}
+	return &object.String{Value: string(chars[start:end])}
+}
+
+// builtinChars splits a string into its characters.
+func builtinChars(args ...object.Object) object.Object {
+	values, err := stringArguments("chars", 1, args)
+	if err != nil {
+		return err
+	}
+	elements := []object.Object{}
+	for _, char := range values[0] {
+		elements = append(elements, &object.String{Value: string(char)})
+	}
+	return &object.Array{Elements: elements}
+}
+
+// builtinFormat replaces each {} in the format string with the next
+// argument, printed like by print. {{ and }} stand for literal braces.
+func (c *Context) builtinFormat(args ...object.Object) object.Object {
+	if len(args) == 0 {
+		return createError(object.ARGUMENT_ERROR, "wrong number of arguments for format: want at least 1, got=0")
+	}
+	format, ok := args[0].(*object.String)
+	if !ok {
+		return createError(object.TYPE_ERROR, "argument 1 to format must be STRING, got %s", args[0].Type())
+	}
+
+	var out strings.Builder
+	values := args[1:]
+	used := 0
+	for i := 0; i < len(format.Value); i++ {
+		switch rest := format.Value[i:]; {
+		case strings.HasPrefix(rest, "{{"), strings.HasPrefix(rest, "}}"):
+			out.WriteByte(rest[0])
+			i++
+		case strings.HasPrefix(rest, "{}"):
+			if used == len(values) {
+				return createError(object.ARGUMENT_ERROR, "format has more {} than the %d arguments", len(values))
+			}
+			out.WriteString(values[used].Inspect())
+			used++
+			i++
+		case rest[0] == '{' || rest[0] == '}':
+			return createError(object.ARGUMENT_ERROR, "unmatched %c in format at %d", rest[0], i)
+		default:
+			out.WriteByte(rest[0])
+		}
+		if err := c.checkSize(out.Len()); err != nil {
+			return err
+		}
+	}
+	if used != len(values) {
+		return createError(object.ARGUMENT_ERROR, "format has %d {} but got %d arguments", used, len(values))
+	}
+	return &object.String{Value: out.String()}
+}
+
+// printArgs writes args separated by spaces to the output of c. Strings
+// are written without quotes.
+func (c *Context) printArgs(args []object.Object, end string) object.Object {
+	if err := c.allow(CapOutput); err != nil {
+		return err
+	}
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = arg.Inspect()
+	}
+	if c.Output != nil {
+		fmt.Fprint(c.Output, strings.Join(parts, " ")+end)
+	}
+	return NULL
+}
+
+func (c *Context) builtinPrint(args ...object.Object) object.Object {
+	return c.printArgs(args, "")
+}
+
+func (c *Context) builtinPrintln(args ...object.Object) object.Object {
+	return c.printArgs(args, "\n")
+}
+
+// evalInterpolatedString joins the parts of str, printed like by print.
+func (c *Context) evalInterpolatedString(str *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+	for _, part := range str.Parts {
+		val := c.eval(part, env)
+		if isError(val) {
+			return val
+		}
+		out.WriteString(val.Inspect())
+		if err := c.checkSize(out.Len()); err != nil {
+			return err
+		}
+	}
+	return &object.String{Value: out.String()}
+}
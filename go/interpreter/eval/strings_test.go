@@ -0,0 +1,177 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"interpreter/object"
+	"testing"
+)
+
+func TestStringBuiltins(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`split("a,b,,c", ",")`, `["a", "b", "", "c"]`},
+		{`split("abc", "")`, `["a", "b", "c"]`},
+		{`join(["a", "b"], ", ")`, "a, b"},
+		{`join([1, true, "x"], "-")`, "1-true-x"},
+		{`join([], ",")`, ""},
+		{`trim("  a b \n")`, "a b"},
+		{`upper("Grüße")`, "GRÜßE"},
+		{`lower("ÄB")`, "äb"},
+		{`contains("haystack", "st")`, true},
+		{`contains("haystack", "x")`, false},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`substr("héllo", 1, 3)`, "éll"},
+		{`substr("hello", 3)`, "lo"},
+		{`substr("hello", 3, 10)`, "lo"},
+		{`substr("hello", 10)`, ""},
+		{`substr("abc", 1, 9223372036854775807)`, "bc"},
+		{`substr("abc", 9223372036854775807, 9223372036854775807)`, ""},
+		{`chars("aé")`, `["a", "é"]`},
+		{`chars("")`, `[]`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		case string:
+			if _, ok := evaluated.(*object.Error); ok || evaluated.Inspect() != expected {
+				t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, expected, evaluated.Inspect())
+			}
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`format("{} + {} = {}", 1, 2, 1 + 2)`, "1 + 2 = 3"},
+		{`format("no placeholders")`, "no placeholders"},
+		{`format("{}, {}!", "Hello", ["World"])`, `Hello, ["World"]!`},
+		{`format("{{}} {}", 2.5)`, "{} 2.5"},
+		{`format("{}{}", true, if (false) { 1 })`, "truenull"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+}
+
+func TestStringBuiltinErrors(t *testing.T) {
+	tests := []struct {
+		input        string
+		expectedKind string
+		expected     string
+	}{
+		{`upper()`, object.ARGUMENT_ERROR, "wrong number of arguments for upper: want=1, got=0"},
+		{`split("a", 1)`, object.TYPE_ERROR, "argument 2 to split must be STRING, got INTEGER"},
+		{`join("ab", "")`, object.TYPE_ERROR, "argument 1 to join must be ARRAY, got STRING"},
+		{`substr("abc", -1)`, object.ARGUMENT_ERROR, "negative argument 2 to substr: -1"},
+		{`substr("abc", 0, "1")`, object.TYPE_ERROR, "argument 3 to substr must be INTEGER, got STRING"},
+		{`format()`, object.ARGUMENT_ERROR, "wrong number of arguments for format: want at least 1, got=0"},
+		{`format("{} {}", 1)`, object.ARGUMENT_ERROR, "format has more {} than the 1 arguments"},
+		{`format("{}", 1, 2)`, object.ARGUMENT_ERROR, "format has 1 {} but got 2 arguments"},
+		{`format("{", 1)`, object.ARGUMENT_ERROR, "unmatched { in format at 0"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%s: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Kind != tt.expectedKind || errObj.Message != tt.expected {
+			t.Errorf("%s: wrong error. expected=%s: %s, got=%s: %s",
+				tt.input, tt.expectedKind, tt.expected, errObj.Kind, errObj.Message)
+		}
+	}
+}
+
+func TestStringBuiltinsSizeLimit(t *testing.T) {
+	for _, input := range []string{
+		`replace("aaaa", "a", "bbbb")`,
+		`join(["aaaa", "bbbb"], "--")`,
+		`format("{}{}{}", "aaaa", "bbbb", "cccc")`,
+	} {
+		c := NewContext()
+		c.Limits.MaxSize = 8
+		errObj, ok := c.Run(context.Background(), parseProgram(t, input), object.NewEnvironment()).(*object.Error)
+		if !ok || errObj.Kind != object.SIZE_LIMIT_ERROR {
+			t.Errorf("%s: expected a SizeLimitError. got=%v", input, errObj)
+		}
+	}
+}
+
+func TestPrint(t *testing.T) {
+	var out bytes.Buffer
+	c := NewContext()
+	c.Output = &out
+
+	input := `print("a", 1); println(); println("b", [1, "c"], 2.5); print()`
+	evaluated := c.Run(context.Background(), parseProgram(t, input), object.NewEnvironment())
+	testNullObject(t, evaluated)
+
+	expected := "a 1\nb [1, \"c\"] 2.5\n"
+	if out.String() != expected {
+		t.Errorf("wrong output. expected=%q, got=%q", expected, out.String())
+	}
+
+	out.Reset()
+	c.Capabilities = map[Capability]bool{CapImport: true}
+	evaluated = c.Run(context.Background(), parseProgram(t, `println("a")`), object.NewEnvironment())
+	if err, ok := evaluated.(*object.Error); !ok || err.Inspect() != "ERROR: 1:8: PermissionError: permission denied: output" {
+		t.Errorf("output not denied. got=%s", evaluated.Inspect())
+	}
+	if out.Len() != 0 {
+		t.Errorf("denied output written. got=%q", out.String())
+	}
+
+	c = NewContext()
+	c.Output = nil
+	evaluated = c.Run(context.Background(), parseProgram(t, `println("a")`), object.NewEnvironment())
+	testNullObject(t, evaluated)
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Welt"; let n = 21; "Hallo ${name}, du hast ${n * 2} Punkte"`, "Hallo Welt, du hast 42 Punkte"},
+		{`"${1.5} ${true} ${[1, "a"]} ${{"k": "v"}}"`, `1.5 true [1, "a"] {"k": "v"}`},
+		{`let f = fn(x) { "<${x}>" }; "${f("${f(1)}")}"`, "<<1>>"},
+		{`"${ {"}": "{"}["}"] }"`, "{"},
+		{`"\${x} costs $5"`, "${x} costs $5"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("%s: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, str.Value)
+		}
+	}
+
+	errObj, ok := testEval(`"a ${1 / 0} b"`).(*object.Error)
+	if !ok || errObj.Kind != object.ZERO_DIVISION_ERROR {
+		t.Errorf("expected a ZeroDivisionError. got=%v", errObj)
+	}
+}
@@ -0,0 +1,85 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+)
+
+// DefaultMaxDepth is the number of nested function calls allowed before a
+// call fails with a stack overflow. Tail calls do not count.
+const DefaultMaxDepth = 10000
+
+// tailCall is a call in tail position of a function body. It is evaluated
+// up to the call itself, which is left to applyFunction.
+type tailCall struct {
+	function object.Object
+	args     []object.Object
+	call     *ast.CallExpression
+}
+
+func (tc *tailCall) Type() object.ObjectType { return "TAIL_CALL" }
+func (tc *tailCall) Inspect() string         { return "tail call of " + tc.call.Function.String() }
+
+// evalTail evaluates node in tail position of a function body: its value is
+// the value of the function. A call there is returned as a tailCall.
+func (c *Context) evalTail(node ast.Node, env *object.Environment) object.Object {
+	return c.evalNode(node, env, c.evalTailNode)
+}
+
+func (c *Context) evalTailNode(node ast.Node, env *object.Environment) object.Object {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		if len(node.Statements) == 0 {
+			return nil
+		}
+		last := len(node.Statements) - 1
+		for _, statement := range node.Statements[:last] {
+			result := c.eval(statement, env)
+			if result != nil {
+				rt := result.Type()
+				if rt == object.RETURN_OBJ || rt == object.ERROR_OBJ {
+					return result
+				}
+			}
+		}
+		return c.evalTail(node.Statements[last], env)
+
+	case *ast.ExpressionStatement:
+		return c.evalTail(node.Expression, env)
+
+	case *ast.ReturnStatement:
+		// the value of a return in tail position is the value of the function
+		return c.evalTail(node.ReturnValue, env)
+
+	case *ast.IfExpression:
+		condition := c.eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if isTruthy(condition) {
+			return c.evalTail(node.Consequence, env)
+		} else if node.Alternative != nil {
+			return c.evalTail(node.Alternative, env)
+		}
+		return NULL
+
+	case *ast.MatchExpression:
+		return c.evalMatchExpression(node, env, c.evalTail)
+
+	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			return c.evaluate(node, env)
+		}
+		function := c.eval(node.Function, env)
+		if isError(function) {
+			return function
+		}
+		args := c.evalArguments(node.Arguments, env)
+		if len(args) == 1 && isError(args[0]) {
+			return args[0]
+		}
+		return &tailCall{function: function, args: args, call: node}
+	}
+
+	return c.evaluate(node, env)
+}
@@ -0,0 +1,61 @@
+package eval
+
+import (
+	"context"
+	"interpreter/object"
+	"testing"
+)
+
+func TestTailCalls(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`let count = fn(n, acc) { if (n == 0) { acc } else { count(n - 1, acc + 1) } }; count(100000, 0)`, 100000},
+		{`let count = fn(n) { if (n == 0) { return 0; } return count(n - 1); }; count(100000)`, 0},
+		{
+			`let even = fn(n) { if (n == 0) { true } else { odd(n - 1) } };
+			let odd = fn(n) { if (n == 0) { false } else { even(n - 1) } };
+			let r = even(100001); if (r) { 1 } else { 2 }`,
+			2,
+		},
+		{`let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(50)`, 1275},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, evalWithDepth(t, 100, tt.input), tt.expected)
+	}
+}
+
+func TestStackOverflow(t *testing.T) {
+	input := `let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(1000)`
+	evaluated := evalWithDepth(t, 100, input)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Kind != object.STACK_OVERFLOW_ERROR {
+		t.Errorf("wrong error kind. expected=%q, got=%q", object.STACK_OVERFLOW_ERROR, errObj.Kind)
+	}
+	if errObj.Message != "stack overflow: more than 100 nested calls" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if len(errObj.Trace) != 100 {
+		t.Errorf("wrong trace length. expected=100, got=%d", len(errObj.Trace))
+	}
+
+	// the depth is unwound after the overflow
+	testIntegerObject(t, evalWithDepth(t, 100, `let sum = fn(n) { if (n == 0) { 0 } else { n + sum(n - 1) } }; sum(50)`), 1275)
+
+	caught := evalWithDepth(t, 100, `let f = fn() { 1 + f() }; try { f() } catch (e) { e.kind }`)
+	if str, ok := caught.(*object.String); !ok || str.Value != object.STACK_OVERFLOW_ERROR {
+		t.Errorf("stack overflow not caught. got=%+v", caught)
+	}
+}
+
+func evalWithDepth(t *testing.T, depth int, input string) object.Object {
+	t.Helper()
+	c := NewContext()
+	c.Limits.MaxDepth = depth
+	return c.Run(context.Background(), parseProgram(t, input), object.NewEnvironment())
+}
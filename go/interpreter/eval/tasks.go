@@ -0,0 +1,163 @@
+package eval
+
+import (
+	"interpreter/ast"
+	"interpreter/object"
+	"sync"
+)
+
+// scheduler lets the tasks of a run take turns and detects when they are
+// all blocked.
+//
+// The turn is passed between the goroutines that evaluate a program: the
+// one that called Run and those running the tasks started by spawn. Only
+// the goroutine holding the turn evaluates, so the tasks share the context,
+// the environments and all objects without further locking. The channel is
+// empty while the turn is held. The holder passes it on whenever it blocks
+// on a channel and every checkInterval steps.
+//
+// A goroutine blocked on channels waits as a waiter. The goroutine that
+// makes one of its operations proceed completes it on its behalf, so the
+// number of blocked goroutines is exact and a deadlock is found as soon as
+// the last goroutine blocks.
+type scheduler struct {
+	turn chan struct{}
+
+	// mu guards the channels and the fields below, which the timers of
+	// after change without holding the turn.
+	mu sync.Mutex
+	// waiting are the waiters by the channels they wait on, in the order
+	// they blocked.
+	waiting map[*object.Channel][]*waiter
+	// running is the number of tasks that have not ended, blocked the
+	// number of goroutines waiting on a channel and timers the number of
+	// channels of after that have not received yet.
+	running, blocked, timers int
+}
+
+func newScheduler() scheduler {
+	return scheduler{turn: make(chan struct{}, 1), waiting: make(map[*object.Channel][]*waiter)}
+}
+
+// checkDeadlock ends the wait of all blocked goroutines with an error if
+// the goroutine of the run and all tasks wait on channels no timer will
+// send to. mu must be held.
+func (s *scheduler) checkDeadlock() {
+	if s.blocked <= s.running || s.timers > 0 {
+		return
+	}
+	for _, waiting := range s.waiting {
+		for _, w := range waiting {
+			if !w.done {
+				w.err = createError(object.DEADLOCK_ERROR, "deadlock: all tasks are blocked")
+				s.complete(w, 0, nil, false)
+			}
+		}
+	}
+}
+
+// held is the part of the context that belongs to the goroutine holding the
+// turn. It is saved when the turn is passed on and restored with it.
+type held struct {
+	depth     int
+	generator *generatorState
+}
+
+// release passes the turn on and returns the state acquire restores.
+func (c *Context) release() held {
+	h := held{depth: c.depth, generator: c.generator}
+	c.scheduler.turn <- struct{}{}
+	return h
+}
+
+// acquire waits for the turn and restores h.
+func (c *Context) acquire(h held) {
+	<-c.scheduler.turn
+	c.depth, c.generator = h.depth, h.generator
+}
+
+// pass lets the goroutines waiting for the turn run first.
+func (c *Context) pass() {
+	c.acquire(c.release())
+}
+
+// stopTasks waits for the tasks spawned during a run. The context of the
+// run is done by then, so each task stops at its next step check or
+// blocking operation.
+func (c *Context) stopTasks() {
+	if c.tasks == nil {
+		return
+	}
+	h := c.release()
+	c.tasks.Wait()
+	c.acquire(h)
+	c.tasks = nil
+}
+
+// evalSpawnExpression starts a task. For spawn f(x) the function and the
+// arguments are evaluated before the task starts.
+func (c *Context) evalSpawnExpression(exp *ast.SpawnExpression, env *object.Environment) object.Object {
+	callee, arguments := exp.Value, []ast.Expression(nil)
+	if call, ok := exp.Value.(*ast.CallExpression); ok {
+		callee, arguments = call.Function, call.Arguments
+	}
+	fn := c.eval(callee, env)
+	if isError(fn) {
+		return fn
+	}
+	if !isCallable(fn) {
+		return locateError(callee, createError(object.TYPE_ERROR, "cannot spawn %s", describeType(fn)))
+	}
+	args := c.evalArguments(arguments, env)
+	if len(args) == 1 && isError(args[0]) {
+		return args[0]
+	}
+	return c.spawn(callee, fn, args)
+}
+
+// spawn calls fn with args in a new task and returns a channel that
+// receives the result of the call when the task ends. An error the task
+// does not catch is received in place of the result and raised by the
+// receiver, its trace ends with the call at callee.
+func (c *Context) spawn(callee ast.Expression, fn object.Object, args []object.Object) *object.Channel {
+	if c.tasks == nil {
+		c.tasks = &sync.WaitGroup{}
+	}
+	tasks, s := c.tasks, &c.scheduler
+	tasks.Add(1)
+	s.mu.Lock()
+	s.running++
+	s.mu.Unlock()
+	result := object.NewChannel(1)
+
+	go func() {
+		defer tasks.Done()
+		c.acquire(held{})
+
+		var value object.Object
+		if err := c.canceled(); err != nil {
+			value = err
+		} else {
+			value = traceAt(callee, c.runTask(fn, args))
+		}
+		s.mu.Lock()
+		s.send(result, value)
+		s.close(result)
+		s.running--
+		s.checkDeadlock()
+		s.mu.Unlock()
+		c.release()
+	}()
+	return result
+}
+
+// runTask calls fn with args. A panic, e.g. of a builtin of the host, ends
+// the task with an error instead of the process.
+func (c *Context) runTask(fn object.Object, args []object.Object) (result object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = createError(object.PANIC_ERROR, "task panicked: %v", r)
+		}
+	}()
+	return c.applyFunction(fn, args)
+}
@@ -0,0 +1,171 @@
+package eval
+
+import (
+	"context"
+	"interpreter/object"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestTasks(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let t = spawn fn() { 1 + 2 }; recv(t)`, `3`},
+		{`let add = fn(a, b) { a + b }; recv(spawn add(1, 2))`, `3`},
+		{`let c = chan(); spawn fn() { send(c, 1) }; recv(c)`, `1`},
+		{`let c = chan(2); send(c, 1); send(c, 2); close(c); [recv(c), recv(c), recv(c)]`, `[1, 2, null]`},
+		{`let c = chan(); spawn fn() { for (i in 0..3) { send(c, i) }; close(c) }; collect(c)`, `[0, 1, 2]`},
+		{`let c = chan(); let t = spawn fn() { recv(c) * 2 }; send(c, 21); recv(t)`, `42`},
+		{`let c = chan(3); for (i in 1..=3) { spawn fn() { send(c, i * 10) } }; sort(collect(take(c, 3)))`, `[10, 20, 30]`},
+		{`let ping = chan(); let pong = chan();
+spawn fn() { for (n in ping) { send(pong, n + 1) }; close(pong) };
+let total = reduce(0..5, fn(acc, n) { send(ping, n); acc + recv(pong) }, 0);
+close(ping);
+[total, recv(pong)]`, `[15, null]`},
+		{`let c = chan();
+let count = fn(n) { reduce(0..n, fn(a, b) { a + b }, 0) };
+let a = spawn fn() { send(c, count(2000)) };
+let b = spawn fn() { send(c, count(3000)) };
+recv(c) + recv(c)`, `6497500`},
+		{`let gen = fn(c) { for (x in c) { yield x * x } };
+let c = chan(); spawn fn() { send(c, 2); send(c, 3); close(c) };
+collect(gen(c))`, `[4, 9]`},
+		{`chan(4)`, `chan(4)`},
+		{`let t = spawn fn() { 1 }; recv(t); recv(t)`, `null`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = chan(1); let b = chan(1); send(b, 2); select { x = recv(a) => [1, x], y = recv(b) => [2, y] }`, `[2, 2]`},
+		{`let c = chan(); select { x = recv(c) => x, recv(after(10)) => "timeout" }`, `timeout`},
+		{`let c = chan(); select { x = recv(c) => x, _ => "empty" }`, `empty`},
+		{`let c = chan(1); select { send(c, 5) => recv(c), _ => "full" }`, `5`},
+		{`let c = chan(1); send(c, 1); select { send(c, 5) => "sent", _ => "full" }`, `full`},
+		{`let c = chan(); close(c); select { x = recv(c) => x }`, `null`},
+		{`let c = chan(); spawn fn() { send(c, 7) }; select { x = recv(c) => x + 1, recv(after(1000)) => 0 }`, `8`},
+		{`let out = chan(); spawn fn() { recv(out) }; select { send(out, 1) => "sent" }`, `sent`},
+		{`let x = 1; let c = chan(1); send(c, 2); select { x = recv(c) => x }; x`, `1`},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%s, got=%s", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTaskErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let t = spawn fn() {\n  throw \"boom\"\n};\nrecv(t)", "ERROR: 2:3: Error: boom\n\tin fn called at 1:15"},
+		{"let f = fn(n) { 1 / n };\nrecv(spawn f(0))", "ERROR: 1:19: ZeroDivisionError: division by zero\n\tin f called at 2:12"},
+		{`spawn 1`, "ERROR: 1:7: TypeError: cannot spawn INTEGER"},
+		{`spawn f()`, "ERROR: 1:7: NameError: undefined identifier: f"},
+		{`let c = chan(); close(c); send(c, 1)`, "ERROR: 1:31: ChannelError: send on closed channel"},
+		{`let c = chan(); close(c); close(c)`, "ERROR: 1:32: ChannelError: close of closed channel"},
+		{`recv(1)`, "ERROR: 1:5: TypeError: argument 1 to recv must be CHANNEL, got INTEGER"},
+		{`chan(-1)`, "ERROR: 1:5: ArgumentError: channel size must be between 0 and 1048576, got -1"},
+		{`select { x = recv(1) => x }`, "ERROR: 1:19: TypeError: argument 1 to recv must be CHANNEL, got INTEGER"},
+		{`let c = chan(); close(c); select { send(c, 1) => 1 }`, "ERROR: 1:27: ChannelError: send on closed channel"},
+		{`let c = chan(); spawn fn() { close(c) }; send(c, 1)`, "ERROR: 1:46: ChannelError: send on closed channel"},
+	}
+
+	for _, tt := range tests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("%q: no error object returned", tt.input)
+			continue
+		}
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, errObj.Inspect())
+		}
+	}
+
+	caught := testEval(`let t = spawn fn() { throw "boom" }; try { recv(t) } catch (e) { e.message }`)
+	if caught.Inspect() != "boom" {
+		t.Errorf("error of a task not caught by the receiver. got=%s", caught.Inspect())
+	}
+}
+
+func TestDeadlock(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`recv(chan())`, "ERROR: 1:5: DeadlockError: deadlock: all tasks are blocked"},
+		{`send(chan(), 1)`, "ERROR: 1:5: DeadlockError: deadlock: all tasks are blocked"},
+		{`let c = chan(); spawn fn() { recv(c) }; recv(c)`, "ERROR: 1:45: DeadlockError: deadlock: all tasks are blocked"},
+		{`let t = spawn fn() { recv(chan()) }; recv(t)`, "ERROR: 1:42: DeadlockError: deadlock: all tasks are blocked"},
+		{`let c = chan(); select { x = recv(c) => x }`, "ERROR: 1:17: DeadlockError: deadlock: all tasks are blocked"},
+		{`try { recv(chan()) } catch (e) { e.kind }`, "DeadlockError"},
+		{`let a = chan(); try { recv(a) } catch (e) { 1 }; let b = chan(1); send(b, 2); recv(b)`, "2"},
+		{`recv(after(10))`, "null"},
+		{`let c = chan(); spawn fn() { recv(after(20)); send(c, 1) }; recv(c)`, "1"},
+		{`let c = chan(); spawn fn() { for (i in 0..1000) { send(c, i) }; close(c) }; len(collect(c))`, "1000"},
+		{`let c = chan(); let d = chan(); spawn fn() { for (i in 0..500) { send(c, i); recv(d) } }; for (i in 0..500) { recv(c); send(d, i) }; recv(c)`, "ERROR: 1:138: DeadlockError: deadlock: all tasks are blocked"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("%s: wrong result. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestTaskPanics(t *testing.T) {
+	env := object.NewEnvironment()
+	env.Set("explode", &object.Builtin{Name: "explode", Fn: func(args ...object.Object) object.Object {
+		panic("host failure")
+	}})
+	program := parseProgram(t, `let t = spawn explode(); try { recv(t) } catch (e) { [e.kind, e.message] }`)
+	evaluated := NewContext().Run(context.Background(), program, env)
+	if evaluated.Inspect() != `["PanicError", "task panicked: host failure"]` {
+		t.Errorf("panic of a task not raised by the receiver. got=%s", evaluated.Inspect())
+	}
+}
+
+func TestTasksEndWithRun(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	c := NewContext()
+	c.Limits.Timeout = 50 * time.Millisecond
+	program := parseProgram(t, `let c = chan();
+spawn fn() { recv(c) };
+spawn fn() { let loop = fn(n) { loop(n + 1) }; loop(0) };
+recv(c)`)
+	evaluated := c.Run(context.Background(), program, object.NewEnvironment())
+	if err, ok := evaluated.(*object.Error); !ok || err.Kind != object.TIMEOUT_ERROR {
+		t.Errorf("blocked receive not timed out. got=%s", evaluated.Inspect())
+	}
+
+	c = NewContext()
+	program = parseProgram(t, `let c = chan(); spawn fn() { recv(c) }; spawn fn() { send(c, 1); send(c, 2) }; 1`)
+	if evaluated := c.Run(context.Background(), program, object.NewEnvironment()); evaluated.Inspect() != "1" {
+		t.Errorf("wrong result. got=%s", evaluated.Inspect())
+	}
+
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Errorf("tasks keep running after the run. goroutines before=%d, after=%d", before, n)
+	}
+}
@@ -0,0 +1,356 @@
+package interp
+
+import (
+	"errors"
+	"fmt"
+	"interpreter/eval"
+	"interpreter/object"
+	"reflect"
+)
+
+var (
+	objectType = reflect.TypeOf((*object.Object)(nil)).Elem()
+	errorType  = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// ToObject converts a Go value to an object. It supports bools, integers,
+// floats, strings, slices, arrays, maps and funcs, and pointers to them.
+// Objects are returned unchanged. Functions of the program that a converted
+// func is called with run in a new context without limits; Interpreter.Set
+// uses the context of the interpreter instead.
+func ToObject(v any) (object.Object, error) {
+	return toObject(nil, "func", reflect.ValueOf(v))
+}
+
+// toObject converts v. Functions of the program passed to a func that v
+// contains are called in c, or in a new context if c is nil.
+func toObject(c *eval.Context, name string, v reflect.Value) (object.Object, error) {
+	if !v.IsValid() {
+		return eval.NULL, nil
+	}
+	if v.Type().Implements(objectType) {
+		if v.IsNil() {
+			return eval.NULL, nil
+		}
+		return v.Interface().(object.Object), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return eval.TRUE, nil
+		}
+		return eval.FALSE, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return &object.Integer{Value: v.Int()}, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &object.Integer{Value: int64(v.Uint())}, nil
+	case reflect.Float32, reflect.Float64:
+		return &object.Float{Value: v.Float()}, nil
+	case reflect.String:
+		return &object.String{Value: v.String()}, nil
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return eval.NULL, nil
+		}
+		elements := make([]object.Object, v.Len())
+		for i := range elements {
+			el, err := toObject(c, name, v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			elements[i] = el
+		}
+		return &object.Array{Elements: elements}, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return eval.NULL, nil
+		}
+		hash := object.NewHash()
+		iter := v.MapRange()
+		for iter.Next() {
+			key, err := toObject(c, name, iter.Key())
+			if err != nil {
+				return nil, err
+			}
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return nil, fmt.Errorf("unusable as hash key: %s", v.Type().Key())
+			}
+			value, err := toObject(c, name, iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			hash.Set(hashKey, value)
+		}
+		return hash, nil
+	case reflect.Func:
+		if v.IsNil() {
+			return eval.NULL, nil
+		}
+		return wrapFunc(c, name, v)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return eval.NULL, nil
+		}
+		return toObject(c, name, v.Elem())
+	default:
+		return nil, fmt.Errorf("cannot convert %s to an object", v.Type())
+	}
+}
+
+// ToGo converts an object to a Go value: integers to int64, floats to
+// float64, arrays to []any and hashes to map[any]any. Null becomes nil and
+// all other objects, e.g. functions, are returned unchanged. An array or
+// hash that contains itself cannot be converted.
+func ToGo(obj object.Object) (any, error) {
+	return toGo(obj, make(map[object.Object]bool))
+}
+
+// toGo converts obj. path holds the arrays and hashes obj is an element of.
+func toGo(obj object.Object, path map[object.Object]bool) (any, error) {
+	switch obj := obj.(type) {
+	case nil, *object.Null:
+		return nil, nil
+	case *object.Integer:
+		return obj.Value, nil
+	case *object.Float:
+		return obj.Value, nil
+	case *object.Boolean:
+		return obj.Value, nil
+	case *object.String:
+		return obj.Value, nil
+	case *object.Array:
+		if path[obj] {
+			return nil, fmt.Errorf("cannot convert an array that contains itself")
+		}
+		path[obj] = true
+		defer delete(path, obj)
+
+		result := make([]any, len(obj.Elements))
+		for i, el := range obj.Elements {
+			v, err := toGo(el, path)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = v
+		}
+		return result, nil
+	case *object.Hash:
+		if path[obj] {
+			return nil, fmt.Errorf("cannot convert a hash that contains itself")
+		}
+		path[obj] = true
+		defer delete(path, obj)
+
+		result := make(map[any]any, len(obj.Pairs))
+		for _, pair := range obj.Pairs {
+			key, err := toGo(pair.Key, path)
+			if err != nil {
+				return nil, err
+			}
+			value, err := toGo(pair.Value, path)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+		return result, nil
+	default:
+		return obj, nil
+	}
+}
+
+// fromObject converts obj to a Go value of type t. A function becomes a Go
+// func that calls it in c.
+func fromObject(c *eval.Context, obj object.Object, t reflect.Type) (reflect.Value, error) {
+	if t == objectType {
+		return reflect.ValueOf(&obj).Elem(), nil
+	}
+
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Interface:
+		goValue, err := ToGo(obj)
+		if err != nil {
+			return v, err
+		}
+		if goValue != nil {
+			gv := reflect.ValueOf(goValue)
+			if !gv.Type().AssignableTo(t) {
+				break
+			}
+			v.Set(gv)
+		}
+		return v, nil
+	case reflect.Bool:
+		if b, ok := obj.(*object.Boolean); ok {
+			v.SetBool(b.Value)
+			return v, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if i, ok := obj.(*object.Integer); ok && !v.OverflowInt(i.Value) {
+			v.SetInt(i.Value)
+			return v, nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		if i, ok := obj.(*object.Integer); ok && i.Value >= 0 && !v.OverflowUint(uint64(i.Value)) {
+			v.SetUint(uint64(i.Value))
+			return v, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		// integers are promoted like in arithmetic
+		switch n := obj.(type) {
+		case *object.Integer:
+			v.SetFloat(float64(n.Value))
+			return v, nil
+		case *object.Float:
+			v.SetFloat(n.Value)
+			return v, nil
+		}
+	case reflect.String:
+		if s, ok := obj.(*object.String); ok {
+			v.SetString(s.Value)
+			return v, nil
+		}
+	case reflect.Slice:
+		if array, ok := obj.(*object.Array); ok {
+			v = reflect.MakeSlice(t, len(array.Elements), len(array.Elements))
+			for i, el := range array.Elements {
+				ev, err := fromObject(c, el, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.Index(i).Set(ev)
+			}
+			return v, nil
+		}
+	case reflect.Map:
+		if hash, ok := obj.(*object.Hash); ok {
+			v = reflect.MakeMapWithSize(t, len(hash.Pairs))
+			for _, pair := range hash.Pairs {
+				key, err := fromObject(c, pair.Key, t.Key())
+				if err != nil {
+					return v, err
+				}
+				value, err := fromObject(c, pair.Value, t.Elem())
+				if err != nil {
+					return v, err
+				}
+				v.SetMapIndex(key, value)
+			}
+			return v, nil
+		}
+	case reflect.Func:
+		switch obj.(type) {
+		case *object.Function, *object.Builtin, *object.BoundMethod:
+			return makeFunc(c, obj, t), nil
+		}
+	}
+	return v, fmt.Errorf("cannot use %s as %s", obj.Type(), t)
+}
+
+// wrapFunc turns the Go func fn into a builtin. Its arguments are converted
+// from objects and its result back to an object. A func may return a value,
+// an error, or a value and an error. A non-nil error is thrown; an error of
+// the program it called back keeps its kind and position.
+func wrapFunc(c *eval.Context, name string, fn reflect.Value) (*object.Builtin, error) {
+	t := fn.Type()
+	switch {
+	case t.NumOut() > 2,
+		t.NumOut() == 2 && t.Out(1) != errorType:
+		return nil, fmt.Errorf("%s: func must return a value, an error or both, got %s", name, t)
+	}
+
+	return &object.Builtin{Name: name, Fn: func(args ...object.Object) object.Object {
+		numIn := t.NumIn()
+		if t.IsVariadic() && len(args) < numIn-1 || !t.IsVariadic() && len(args) != numIn {
+			return &object.Error{
+				Kind:    object.ARGUMENT_ERROR,
+				Message: fmt.Sprintf("wrong number of arguments: want=%d, got=%d", numIn, len(args)),
+			}
+		}
+
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			paramType := t.In(min(i, numIn-1))
+			if t.IsVariadic() && i >= numIn-1 {
+				paramType = paramType.Elem()
+			}
+			v, err := fromObject(c, arg, paramType)
+			if err != nil {
+				return &object.Error{
+					Kind:    object.TYPE_ERROR,
+					Message: fmt.Sprintf("argument %d of %s: %s", i+1, name, err),
+				}
+			}
+			in[i] = v
+		}
+
+		out := fn.Call(in)
+		if len(out) > 0 && t.Out(len(out)-1) == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				// errors of the program called back by fn keep their kind
+				var runtimeErr *RuntimeError
+				if errors.As(err, &runtimeErr) {
+					return runtimeErr.Err
+				}
+				return &object.Error{Kind: object.THROWN_ERROR, Message: err.Error()}
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return eval.NULL
+		}
+		result, err := toObject(c, name, out[0])
+		if err != nil {
+			return &object.Error{Kind: object.TYPE_ERROR, Message: fmt.Sprintf("result of %s: %s", name, err)}
+		}
+		return result
+	}}, nil
+}
+
+// makeFunc returns a Go func of type t that calls the function fn of the
+// program in c, or in a new context if c is nil. If t returns an error as
+// last result, errors of the call are returned there, otherwise they panic.
+func makeFunc(c *eval.Context, fn object.Object, t reflect.Type) reflect.Value {
+	return reflect.MakeFunc(t, func(in []reflect.Value) []reflect.Value {
+		out := make([]reflect.Value, t.NumOut())
+		for i := range out {
+			out[i] = reflect.Zero(t.Out(i))
+		}
+		fail := func(err error) []reflect.Value {
+			if t.NumOut() == 0 || t.Out(t.NumOut()-1) != errorType {
+				panic(err)
+			}
+			out[len(out)-1] = reflect.ValueOf(&err).Elem()
+			return out
+		}
+
+		args := make([]object.Object, len(in))
+		for i, v := range in {
+			arg, err := toObject(c, "func", v)
+			if err != nil {
+				return fail(err)
+			}
+			args[i] = arg
+		}
+
+		apply := eval.NewContext().Apply
+		if c != nil {
+			apply = c.Apply
+		}
+		result := apply(fn, args)
+		if err, ok := result.(*object.Error); ok {
+			return fail(&RuntimeError{err})
+		}
+		if t.NumOut() > 0 && t.Out(0) != errorType {
+			v, err := fromObject(c, result, t.Out(0))
+			if err != nil {
+				return fail(err)
+			}
+			out[0] = v
+		}
+		return out
+	})
+}
@@ -0,0 +1,148 @@
+// Package interp embeds the interpreter in Go programs, e.g. to evaluate
+// configuration files or formulas. Values cross the boundary as plain Go
+// values; see ToObject and ToGo for the conversions.
+package interp
+
+import (
+	"context"
+	"fmt"
+	"interpreter/diag"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"interpreter/pipeline"
+	"interpreter/token"
+	"reflect"
+	"strings"
+)
+
+// Interpreter evaluates programs in a global scope that persists between
+// calls of Eval, like the lines of the REPL. Each interpreter has a context
+// of its own, so several of them can be used in parallel, but a single one
+// must not be used by several goroutines at once.
+type Interpreter struct {
+	// Context holds the limits and capabilities of every Eval.
+	Context *eval.Context
+
+	env    *object.Environment
+	macros *object.Environment
+}
+
+// New returns an interpreter with an empty global scope and no limits.
+func New() *Interpreter {
+	c := eval.NewContext()
+	c.Loader = pipeline.NewLoader("", nil)
+	return &Interpreter{
+		Context: c,
+		env:     object.NewEnvironment(),
+		macros:  object.NewEnvironment(),
+	}
+}
+
+// ParseError reports the syntax errors of a source.
+type ParseError struct {
+	Messages []string
+}
+
+func (e *ParseError) Error() string {
+	return "parse error: " + strings.Join(e.Messages, "; ")
+}
+
+// CheckError reports the errors the static passes found in a source.
+type CheckError struct {
+	Diagnostics []diag.Diagnostic
+}
+
+func (e *CheckError) Error() string {
+	messages := []string{}
+	for _, d := range e.Diagnostics {
+		messages = append(messages, d.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// RuntimeError is an error the program raised and did not catch.
+type RuntimeError struct {
+	Err *object.Error
+}
+
+func (e *RuntimeError) Error() string {
+	return strings.TrimPrefix(e.Err.Inspect(), "ERROR: ")
+}
+
+// Eval evaluates src and returns the value of its last statement converted
+// by ToGo, or the error of the conversion. The bindings of src stay visible
+// to later calls. Cancelling ctx stops the evaluation.
+func (in *Interpreter) Eval(ctx context.Context, src string) (any, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, &ParseError{Messages: p.Errors()}
+	}
+
+	globals := append(eval.BuiltinNames(), in.env.Names()...)
+	program, diagnostics := pipeline.Prepare(ctx, in.Context, program, in.macros, globals)
+	if diag.HasErrors(diagnostics) {
+		return nil, &CheckError{Diagnostics: errorsOnly(diagnostics)}
+	}
+
+	result := in.Context.Run(ctx, program, in.env)
+	if err, ok := result.(*object.Error); ok {
+		return nil, &RuntimeError{Err: err}
+	}
+	return ToGo(result)
+}
+
+func errorsOnly(diagnostics []diag.Diagnostic) []diag.Diagnostic {
+	result := []diag.Diagnostic{}
+	for _, d := range diagnostics {
+		if d.Severity == diag.Error {
+			result = append(result, d)
+		}
+	}
+	diag.Sort(result)
+	return result
+}
+
+// Set binds name to value in the global scope. value is converted by
+// ToObject; a func becomes a builtin like with Register.
+func (in *Interpreter) Set(name string, value any) error {
+	if !isIdentifier(name) {
+		return fmt.Errorf("invalid name %q", name)
+	}
+	obj, err := toObject(in.Context, name, reflect.ValueOf(value))
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	in.env.Set(name, obj)
+	return nil
+}
+
+// Get returns the value bound to name in the global scope, converted by
+// ToGo. ok is false if name is not bound.
+func (in *Interpreter) Get(name string) (value any, ok bool, err error) {
+	obj, ok := in.env.Get(name)
+	if !ok {
+		return nil, false, nil
+	}
+	value, err = ToGo(obj)
+	return value, true, err
+}
+
+// Register binds name to the Go func fn. The arguments of a call are
+// converted to the parameter types of fn and its result back to an object.
+// fn may return nothing, a value, an error, or a value and an error; a
+// non-nil error is thrown as an Error.
+func (in *Interpreter) Register(name string, fn any) error {
+	if v := reflect.ValueOf(fn); v.Kind() != reflect.Func || v.IsNil() {
+		return fmt.Errorf("%s: not a func: %T", name, fn)
+	}
+	return in.Set(name, fn)
+}
+
+func isIdentifier(name string) bool {
+	l := lexer.New(name)
+	tok := l.NextToken()
+	return tok.Type == token.IDENT && tok.Literal == name && l.NextToken().Type == token.EOF
+}
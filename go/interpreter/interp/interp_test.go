@@ -0,0 +1,349 @@
+package interp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"interpreter/eval"
+	"interpreter/object"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"1 + 2", int64(3)},
+		{"1.5 * 2.0", 3.0},
+		{`"a" + "b"`, "ab"},
+		{"1 < 2", true},
+		{"let x = 1;", nil},
+		{"[1, true, [\"a\"]]", []any{int64(1), true, []any{"a"}}},
+		{`{"a": 1, 2: false}`, map[any]any{"a": int64(1), int64(2): false}},
+	}
+
+	for _, tt := range tests {
+		result, err := New().Eval(context.Background(), tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: wrong result. expected=%#v, got=%#v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestEvalKeepsBindings(t *testing.T) {
+	in := New()
+	if _, err := in.Eval(context.Background(), "let double = fn(x) { x * 2 }; let rate = 3;"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	result, err := in.Eval(context.Background(), "double(rate)")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if result != int64(6) {
+		t.Errorf("wrong result. expected=6, got=%#v", result)
+	}
+	if rate, ok, err := in.Get("rate"); !ok || err != nil || rate != int64(3) {
+		t.Errorf("wrong value of rate. got=%#v, %t, %v", rate, ok, err)
+	}
+	if _, ok, _ := in.Get("missing"); ok {
+		t.Errorf("Get reported a missing name as bound")
+	}
+}
+
+func TestEvalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		check    func(error) bool
+		expected string
+	}{
+		{"let = 1", isError[*ParseError], "parse error: "},
+		{"missing + 1", isError[*CheckError], "1:1: error: undefined identifier: missing"},
+		{"1 / 0", isError[*RuntimeError], "1:3: ZeroDivisionError: division by zero"},
+		{`throw "boom"`, isError[*RuntimeError], "1:1: Error: boom"},
+	}
+
+	for _, tt := range tests {
+		_, err := New().Eval(context.Background(), tt.input)
+		if err == nil {
+			t.Errorf("%q: no error returned", tt.input)
+			continue
+		}
+		if !tt.check(err) {
+			t.Errorf("%q: wrong error type. got=%T", tt.input, err)
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("%q: wrong message. expected prefix %q, got=%q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestEvalCycles(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let a = [1]; a[0] = a; a`, "cannot convert an array that contains itself"},
+		{`let h = {}; h["self"] = [h]; h`, "cannot convert a hash that contains itself"},
+	}
+
+	for _, tt := range tests {
+		_, err := New().Eval(context.Background(), tt.input)
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+	}
+
+	result, err := New().Eval(context.Background(), `let a = [1]; [a, {"a": a}]`)
+	expected := []any{[]any{int64(1)}, map[any]any{"a": []any{int64(1)}}}
+	if err != nil || !reflect.DeepEqual(result, expected) {
+		t.Errorf("shared array not converted. got=%#v, %v", result, err)
+	}
+}
+
+func isError[T error](err error) bool {
+	var target T
+	return errors.As(err, &target)
+}
+
+func TestEvalContext(t *testing.T) {
+	in := New()
+	in.Context.Limits.Timeout = 20 * time.Millisecond
+	_, err := in.Eval(context.Background(), "let loop = fn(n) { loop(n + 1) }; loop(0)")
+
+	var runtimeErr *RuntimeError
+	if !errors.As(err, &runtimeErr) || runtimeErr.Err.Kind != object.TIMEOUT_ERROR {
+		t.Errorf("expected a TimeoutError. got=%v", err)
+	}
+}
+
+func TestEvalMacroLimits(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`let m = macro() { print("leak"); quote(1) }; m()`,
+			"1:47: error: macro m failed: permission denied: output",
+		},
+		{
+			`let m = macro() { let loop = fn(n) { loop(n + 1) }; loop(0) }; m()`,
+			"1:65: error: macro m failed: step limit of 1000 exceeded",
+		},
+	}
+
+	for _, tt := range tests {
+		var out strings.Builder
+		in := New()
+		in.Context.Capabilities = map[eval.Capability]bool{}
+		in.Context.Limits = eval.Limits{MaxSteps: 1000, Timeout: time.Second}
+		in.Context.Output = &out
+
+		_, err := in.Eval(context.Background(), tt.input)
+		if !isError[*CheckError](err) || err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. expected=%q, got=%v", tt.input, tt.expected, err)
+		}
+		if out.Len() != 0 {
+			t.Errorf("%q: macro wrote output %q", tt.input, out.String())
+		}
+	}
+}
+
+func TestConcurrentInterpreters(t *testing.T) {
+	var wg sync.WaitGroup
+	errs := make([]error, 4)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var out strings.Builder
+			in := New()
+			in.Context.Output = &out
+			in.Context.Limits.MaxDepth = 50 + i
+			src := fmt.Sprintf(`let n = %d;
+				let sum = fn(k) { if (k == 0) { 0 } else { k + sum(k - 1) } };
+				println(map(1..n + 1, fn(x) { x * n }) |> collect);
+				sum(n * 10)`, i+1)
+			result, err := in.Eval(context.Background(), src)
+			want := int64((i + 1) * 10 * ((i+1)*10 + 1) / 2)
+			switch {
+			case err != nil:
+				errs[i] = err
+			case result != want:
+				errs[i] = fmt.Errorf("wrong result. expected=%d, got=%#v", want, result)
+			case strings.Count(out.String(), "\n") != 1:
+				errs[i] = fmt.Errorf("output of other interpreters written. got=%q", out.String())
+			}
+		}()
+	}
+	wg.Wait()
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("interpreter %d: %s", i, err)
+		}
+	}
+}
+
+func TestParallelInstances(t *testing.T) {
+	for i := range 4 {
+		t.Run(fmt.Sprint(i), func(t *testing.T) {
+			t.Parallel()
+			in := New()
+			in.Context.Limits.Timeout = 5 * time.Second
+			in.Register("scale", func(f func(int) int, x int) int { return f(x) * 10 })
+			src := fmt.Sprintf(`let c = chan();
+				for (k in 0..10) { spawn fn() { send(c, scale(fn(x) { x + %d }, k)) } };
+				reduce(take(c, 10), fn(a, b) { a + b }, 0)`, i)
+			result, err := in.Eval(context.Background(), src)
+			if want := int64(450 + 100*i); err != nil || result != want {
+				t.Errorf("wrong result. expected=%d, got=%#v, %v", want, result, err)
+			}
+		})
+	}
+}
+
+func TestSet(t *testing.T) {
+	in := New()
+	values := map[string]any{
+		"i":     42,
+		"u":     uint8(7),
+		"f":     float32(0.5),
+		"b":     true,
+		"s":     "text",
+		"list":  []string{"x", "y"},
+		"table": map[string]int{"k": 1},
+		"ptr":   new(int),
+		"none":  nil,
+	}
+	for name, value := range values {
+		if err := in.Set(name, value); err != nil {
+			t.Fatalf("Set(%q): unexpected error: %s", name, err)
+		}
+	}
+
+	result, err := in.Eval(context.Background(),
+		`[i + u, f * 2.0, !b, s, list[1], table["k"], ptr, none]`)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []any{int64(49), 1.0, false, "text", "y", int64(1), int64(0), nil}
+	if !reflect.DeepEqual(result, expected) {
+		t.Errorf("wrong result. expected=%#v, got=%#v", expected, result)
+	}
+}
+
+func TestSetErrors(t *testing.T) {
+	in := New()
+	if err := in.Set("not a name", 1); err == nil {
+		t.Errorf("expected an error for an invalid name")
+	}
+	if err := in.Set("c", make(chan int)); err == nil {
+		t.Errorf("expected an error for a channel")
+	}
+	if err := in.Set("m", map[[2]int]int{{1, 2}: 3}); err == nil {
+		t.Errorf("expected an error for an unhashable key")
+	}
+	if err := in.Register("f", 1); err == nil {
+		t.Errorf("expected an error for registering a non-func")
+	}
+	if err := in.Register("f", func() (int, int) { return 1, 2 }); err == nil {
+		t.Errorf("expected an error for a func with two values")
+	}
+}
+
+func TestRegister(t *testing.T) {
+	in := New()
+	register := func(name string, fn any) {
+		if err := in.Register(name, fn); err != nil {
+			t.Fatalf("Register(%q): unexpected error: %s", name, err)
+		}
+	}
+	register("add", func(a, b int) int { return a + b })
+	register("half", func(x float64) float64 { return x / 2 })
+	register("join", func(sep string, parts ...string) string { return strings.Join(parts, sep) })
+	register("sum", func(xs []int) (n int) {
+		for _, x := range xs {
+			n += x
+		}
+		return n
+	})
+	register("keys", func(m map[string]any) int { return len(m) })
+	register("apply", func(f func(int) int, x int) int { return f(x) })
+	register("nothing", func() {})
+	register("check", func(ok bool) (string, error) {
+		if !ok {
+			return "", fmt.Errorf("check failed")
+		}
+		return "ok", nil
+	})
+	register("raw", func(obj object.Object) string { return string(obj.Type()) })
+
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"add(1, 2)", int64(3)},
+		{"half(3)", 1.5},
+		{`join("-", "a", "b", "c")`, "a-b-c"},
+		{`join(",")`, ""},
+		{"sum([1, 2, 3])", int64(6)},
+		{`keys({"a": 1, "b": [true]})`, int64(2)},
+		{"apply(fn(x) { x * 10 }, 4)", int64(40)},
+		{"nothing()", nil},
+		{"check(true)", "ok"},
+		{"raw(fn() { 1 })", "FUNCTION"},
+	}
+
+	for _, tt := range tests {
+		result, err := in.Eval(context.Background(), tt.input)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(result, tt.expected) {
+			t.Errorf("%q: wrong result. expected=%#v, got=%#v", tt.input, tt.expected, result)
+		}
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	in := New()
+	in.Register("add", func(a, b int) int { return a + b })
+	in.Register("small", func(x int8) int8 { return x })
+	in.Register("check", func() error { return fmt.Errorf("check failed") })
+	in.Register("call", func(f func() (int, error)) (int, error) { return f() })
+
+	tests := []struct {
+		input        string
+		expectedKind string
+		expected     string
+	}{
+		{"add(1)", object.ARGUMENT_ERROR, "wrong number of arguments: want=2, got=1"},
+		{`add(1, "2")`, object.TYPE_ERROR, "argument 2 of add: cannot use STRING as int"},
+		{"small(1000)", object.TYPE_ERROR, "argument 1 of small: cannot use INTEGER as int8"},
+		{"check()", object.THROWN_ERROR, "check failed"},
+		{"try { check() } catch (e) { throw e.message + \"!\" }", object.THROWN_ERROR, "check failed!"},
+		{"call(fn() { 1 / 0 })", object.ZERO_DIVISION_ERROR, "division by zero"},
+	}
+
+	for _, tt := range tests {
+		_, err := in.Eval(context.Background(), tt.input)
+		var runtimeErr *RuntimeError
+		if !errors.As(err, &runtimeErr) {
+			t.Errorf("%q: expected a runtime error. got=%v", tt.input, err)
+			continue
+		}
+		if runtimeErr.Err.Kind != tt.expectedKind || runtimeErr.Err.Message != tt.expected {
+			t.Errorf("%q: wrong error. expected=%s: %s, got=%s: %s", tt.input,
+				tt.expectedKind, tt.expected, runtimeErr.Err.Kind, runtimeErr.Err.Message)
+		}
+	}
+}
@@ -1,16 +1,25 @@
 package lexer
 
-import "interpreter/token"
+import (
+	"interpreter/token"
+	"strings"
+)
 
 type Lexer struct {
 	input        string
 	position     int
 	readPosition int
 	character    byte
+	line         int
+	column       int
+	comments     []token.Token
+	// interpolations holds the number of open braces inside each ${ } the
+	// lexer is in, the innermost last.
+	interpolations []int
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
@@ -19,6 +28,11 @@ func (l *Lexer) NextToken() token.Token {
 	var tok token.Token
 
 	l.skipWhitespace()
+	for l.character == '/' && l.peekChar() == '/' {
+		l.readComment()
+		l.skipWhitespace()
+	}
+	line, column := l.line, l.column
 
 	switch l.character {
 	case '=':
@@ -27,13 +41,25 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			literal := string(ch) + string(l.character)
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peekChar() == '>' {
+			ch := l.character
+			l.readChar()
+			literal := string(ch) + string(l.character)
+			tok = token.Token{Type: token.FAT_ARROW, Literal: literal}
 		} else {
 			tok = newToken(token.ASSIGN, l.character)
 		}
 	case '+':
 		tok = newToken(token.PLUS, l.character)
 	case '-':
-		tok = newToken(token.MINUS, l.character)
+		if l.peekChar() == '>' {
+			ch := l.character
+			l.readChar()
+			literal := string(ch) + string(l.character)
+			tok = token.Token{Type: token.ARROW, Literal: literal}
+		} else {
+			tok = newToken(token.MINUS, l.character)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.character
@@ -43,6 +69,15 @@ func (l *Lexer) NextToken() token.Token {
 		} else {
 			tok = newToken(token.BANG, l.character)
 		}
+	case '|':
+		if l.peekChar() == '>' {
+			ch := l.character
+			l.readChar()
+			literal := string(ch) + string(l.character)
+			tok = token.Token{Type: token.PIPE, Literal: literal}
+		} else {
+			tok = newToken(token.ILLEGAL, l.character)
+		}
 	case '/':
 		tok = newToken(token.SLASH, l.character)
 	case '*':
@@ -55,14 +90,34 @@ func (l *Lexer) NextToken() token.Token {
 		tok = newToken(token.SEMICOLON, l.character)
 	case ',':
 		tok = newToken(token.COMMA, l.character)
+	case ':':
+		tok = newToken(token.COLON, l.character)
 	case '{':
+		if n := len(l.interpolations); n > 0 {
+			l.interpolations[n-1]++
+		}
 		tok = newToken(token.LBRACE, l.character)
 	case '}':
-		tok = newToken(token.RBRACE, l.character)
+		if n := len(l.interpolations); n > 0 && l.interpolations[n-1] == 0 {
+			// the } closes an interpolation, the string goes on
+			l.interpolations = l.interpolations[:n-1]
+			tok.Literal, tok.Type = l.readString(true)
+		} else {
+			if n > 0 {
+				l.interpolations[n-1]--
+			}
+			tok = newToken(token.RBRACE, l.character)
+		}
+	case '[':
+		tok = newToken(token.LBRACKET, l.character)
+	case ']':
+		tok = newToken(token.RBRACKET, l.character)
 	case '(':
 		tok = newToken(token.LPAREN, l.character)
 	case ')':
 		tok = newToken(token.RPAREN, l.character)
+	case '"':
+		tok.Literal, tok.Type = l.readString(false)
 	case 0:
 		tok.Literal = ""
 		tok.Type = token.EOF
@@ -70,20 +125,52 @@ func (l *Lexer) NextToken() token.Token {
 		if isLetter(l.character) {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
+		} else if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else if strings.HasPrefix(l.input[l.position:], "..=") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.RANGE_INC, Literal: "..="}
+		} else if strings.HasPrefix(l.input[l.position:], "..") {
+			l.readChar()
+			tok = token.Token{Type: token.RANGE, Literal: ".."}
+		} else if l.character == '.' && !isDecimal(l.peekChar()) {
+			tok = newToken(token.DOT, l.character)
 		} else if isDigit(l.character) {
 			tok.Literal = l.readNumber()
 			tok.Type = token.LookupNumberType(tok.Literal)
+			tok.Line, tok.Column = line, column
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.character)
 		}
 	}
 
+	tok.Line, tok.Column = line, column
 	l.readChar()
 	return tok
 }
 
+// Comments returns the line comments read so far. They are not part of the
+// token stream, but tools like the linter look at them.
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+func (l *Lexer) readComment() {
+	tok := token.Token{Type: token.COMMENT, Line: l.line, Column: l.column}
+	position := l.position
+	for l.character != '\n' && l.character != 0 {
+		l.readChar()
+	}
+	tok.Literal = l.input[position:l.position]
+	l.comments = append(l.comments, tok)
+}
+
 func (l *Lexer) peekChar() byte {
 	if l.readPosition >= len(l.input) {
 		return 0
@@ -93,6 +180,11 @@ func (l *Lexer) peekChar() byte {
 }
 
 func (l *Lexer) readChar() {
+	if l.character == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
 	if l.readPosition >= len(l.input) {
 		l.character = 0
 	} else {
@@ -102,22 +194,71 @@ func (l *Lexer) readChar() {
 	l.readPosition += 1
 }
 
+// readIdentifier reads a letter followed by letters and digits, like atan2.
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for isLetter(l.character) {
+	for isLetter(l.character) || isDecimal(l.character) {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
+// readNumber reads an integer or a float. A .. after the digits is a range,
+// not a decimal point.
 func (l *Lexer) readNumber() string {
 	position := l.position
-	for isDigit(l.character) {
+	for isDigit(l.character) && !(l.character == '.' && l.peekChar() == '.') {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
+// readString reads a double quoted string, or with continued the rest of
+// one after the } of an interpolation, and returns its unescaped content. It
+// stops on the closing quote or on the { of the next ${, where the token is
+// a template token instead of STRING. \$ escapes the dollar sign. A string
+// without closing quote is ILLEGAL.
+func (l *Lexer) readString(continued bool) (string, token.TokenType) {
+	var out strings.Builder
+	for {
+		l.readChar()
+		switch l.character {
+		case '"':
+			if continued {
+				return out.String(), token.TEMPLATE_END
+			}
+			return out.String(), token.STRING
+		case '$':
+			if l.peekChar() != '{' {
+				out.WriteByte(l.character)
+				continue
+			}
+			l.readChar()
+			l.interpolations = append(l.interpolations, 0)
+			if continued {
+				return out.String(), token.TEMPLATE_MIDDLE
+			}
+			return out.String(), token.TEMPLATE_START
+		case 0:
+			return out.String(), token.ILLEGAL
+		case '\\':
+			l.readChar()
+			switch l.character {
+			case 'n':
+				out.WriteByte('\n')
+			case 't':
+				out.WriteByte('\t')
+			case 0:
+				return out.String(), token.ILLEGAL
+			default:
+				out.WriteByte(l.character)
+			}
+		default:
+			out.WriteByte(l.character)
+		}
+	}
+}
+
 func (l *Lexer) skipWhitespace() {
 	for l.character == ' ' || l.character == '\t' || l.character == '\n' || l.character == '\r' {
 		l.readChar()
@@ -130,6 +271,10 @@ func isDigit(character byte) bool {
 	return '0' <= character && character <= '9' || character == '.'
 }
 
+func isDecimal(character byte) bool {
+	return '0' <= character && character <= '9'
+}
+
 func isLetter(character byte) bool {
 	return 'a' <= character && character <= 'z' || 'A' <= character && character <= 'Z' || character == '_'
 }
@@ -208,6 +208,316 @@ func TestIllegalNumber(t *testing.T) {
 	checkTokenizedResult(input, tests, t)
 }
 
+func TestTypeAnnotations(t *testing.T) {
+	input := `let f = fn(a: int) -> int { a - 1 };`
+
+	tests := []TokenExpection{
+		{token.LET, "let"},
+		{token.IDENT, "f"},
+		{token.ASSIGN, "="},
+		{token.FUNCTION, "fn"},
+		{token.LPAREN, "("},
+		{token.IDENT, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "int"},
+		{token.RPAREN, ")"},
+		{token.ARROW, "->"},
+		{token.IDENT, "int"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "a"},
+		{token.MINUS, "-"},
+		{token.INT, "1"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let x = 5;\n  x == 10.5;"
+
+	tests := []struct {
+		expectedLiteral string
+		expectedLine    int
+		expectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"==", 2, 5},
+		{"10.5", 2, 8},
+		{";", 2, 12},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+		if tok.Line != tt.expectedLine || tok.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - position of %q wrong. expected=%d:%d, got=%d:%d",
+				i, tok.Literal, tt.expectedLine, tt.expectedColumn, tok.Line, tok.Column)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	input := `import "lib/math";
+export let x = math.pi + .5;
+"a\"b\n" "open`
+
+	tests := []TokenExpection{
+		{token.IMPORT, "import"},
+		{token.STRING, "lib/math"},
+		{token.SEMICOLON, ";"},
+		{token.EXPORT, "export"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "math"},
+		{token.DOT, "."},
+		{token.IDENT, "pi"},
+		{token.PLUS, "+"},
+		{token.FLOAT, ".5"},
+		{token.SEMICOLON, ";"},
+		{token.STRING, "a\"b\n"},
+		{token.ILLEGAL, "open"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestBrackets(t *testing.T) {
+	input := `[1, 2][0]; {"a": x}`
+
+	tests := []TokenExpection{
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.COMMA, ","},
+		{token.INT, "2"},
+		{token.RBRACKET, "]"},
+		{token.LBRACKET, "["},
+		{token.INT, "0"},
+		{token.RBRACKET, "]"},
+		{token.SEMICOLON, ";"},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.IDENT, "x"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestIdentifierWithDigits(t *testing.T) {
+	input := `atan2 x1y 2x`
+
+	tests := []TokenExpection{
+		{token.IDENT, "atan2"},
+		{token.IDENT, "x1y"},
+		{token.INT, "2"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestInterpolation(t *testing.T) {
+	input := `"a ${x} b ${ {"k": "${y}"}["k"] }" "\${z}" "${"open`
+
+	tests := []TokenExpection{
+		{token.TEMPLATE_START, "a "},
+		{token.IDENT, "x"},
+		{token.TEMPLATE_MIDDLE, " b "},
+		{token.LBRACE, "{"},
+		{token.STRING, "k"},
+		{token.COLON, ":"},
+		{token.TEMPLATE_START, ""},
+		{token.IDENT, "y"},
+		{token.TEMPLATE_END, ""},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "k"},
+		{token.RBRACKET, "]"},
+		{token.TEMPLATE_END, ""},
+		{token.STRING, "${z}"},
+		{token.TEMPLATE_START, ""},
+		{token.ILLEGAL, "open"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestPipe(t *testing.T) {
+	input := `xs |> f(1) | x`
+
+	tests := []TokenExpection{
+		{token.IDENT, "xs"},
+		{token.PIPE, "|>"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.INT, "1"},
+		{token.RPAREN, ")"},
+		{token.ILLEGAL, "|"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match (x) { 1 => a, _ => b }`
+
+	tests := []TokenExpection{
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.INT, "1"},
+		{token.FAT_ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.FAT_ARROW, "=>"},
+		{token.IDENT, "b"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestEllipsis(t *testing.T) {
+	input := `[a, ...rest] 1.5 x.y`
+
+	tests := []TokenExpection{
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.FLOAT, "1.5"},
+		{token.IDENT, "x"},
+		{token.DOT, "."},
+		{token.IDENT, "y"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestRangeAndFor(t *testing.T) {
+	input := `0..10; a..=b; 1.5..2; for (x in xs) {}`
+
+	tests := []TokenExpection{
+		{token.INT, "0"},
+		{token.RANGE, ".."},
+		{token.INT, "10"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.RANGE_INC, "..="},
+		{token.IDENT, "b"},
+		{token.SEMICOLON, ";"},
+		{token.FLOAT, "1.5"},
+		{token.RANGE, ".."},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.FOR, "for"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.IN, "in"},
+		{token.IDENT, "xs"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestStruct(t *testing.T) {
+	input := `struct Point { x, y }; p.x = 1`
+
+	tests := []TokenExpection{
+		{token.STRUCT, "struct"},
+		{token.IDENT, "Point"},
+		{token.LBRACE, "{"},
+		{token.IDENT, "x"},
+		{token.COMMA, ","},
+		{token.IDENT, "y"},
+		{token.RBRACE, "}"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "p"},
+		{token.DOT, "."},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestComments(t *testing.T) {
+	input := `// header
+let a = 5; // trailing
+// between
+a / 2;`
+
+	tests := []TokenExpection{
+		{token.LET, "let"},
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.INT, "5"},
+		{token.SEMICOLON, ";"},
+		{token.IDENT, "a"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType || tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - token wrong. expected=%q %q, got=%q %q",
+				i, tt.expectedType, tt.expectedLiteral, tok.Type, tok.Literal)
+		}
+	}
+
+	expectedComments := []token.Token{
+		{Type: token.COMMENT, Literal: "// header", Line: 1, Column: 1},
+		{Type: token.COMMENT, Literal: "// trailing", Line: 2, Column: 12},
+		{Type: token.COMMENT, Literal: "// between", Line: 3, Column: 1},
+	}
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. expected=%d, got=%d", len(expectedComments), len(comments))
+	}
+	for i, c := range comments {
+		if c != expectedComments[i] {
+			t.Errorf("comments[%d] wrong. expected=%+v, got=%+v", i, expectedComments[i], c)
+		}
+	}
+}
+
 func checkTokenizedResult(input string, tests []TokenExpection, t *testing.T) {
 	t.Helper()
 
@@ -227,3 +537,49 @@ func checkTokenizedResult(input string, tests []TokenExpection, t *testing.T) {
 		}
 	}
 }
+
+func TestYield(t *testing.T) {
+	input := `yield x;`
+
+	tests := []TokenExpection{
+		{token.YIELD, "yield"},
+		{token.IDENT, "x"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestSpawnAndSelect(t *testing.T) {
+	input := `spawn f(); select {}`
+
+	tests := []TokenExpection{
+		{token.SPAWN, "spawn"},
+		{token.IDENT, "f"},
+		{token.LPAREN, "("},
+		{token.RPAREN, ")"},
+		{token.SEMICOLON, ";"},
+		{token.SELECT, "select"},
+		{token.LBRACE, "{"},
+		{token.RBRACE, "}"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
+
+func TestConst(t *testing.T) {
+	input := `const x = 1;`
+
+	tests := []TokenExpection{
+		{token.CONST, "const"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "1"},
+		{token.SEMICOLON, ";"},
+		{token.EOF, ""},
+	}
+
+	checkTokenizedResult(input, tests, t)
+}
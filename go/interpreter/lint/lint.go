@@ -0,0 +1,135 @@
+package lint
+
+import (
+	"fmt"
+	"interpreter/ast"
+	"interpreter/diag"
+	"interpreter/resolve"
+	"interpreter/token"
+	"strings"
+)
+
+// SUPPRESS starts a comment that silences findings on its own line and on
+// the following one. Without rule IDs it silences all rules:
+//
+//	let unused = 1; // lint:ignore unused-let
+const SUPPRESS = "lint:ignore"
+
+type reportFunc func(node ast.Node, format string, a ...any)
+
+// Rule is a single check. Severity is the default and can be changed
+// through a Config.
+type Rule struct {
+	ID          string
+	Severity    diag.Severity
+	Description string
+	check       func(program *ast.Program, report reportFunc)
+}
+
+var Rules = []Rule{
+	{"unused-let", diag.Warning, "let binding is never used", checkUnusedLets},
+	{"unreachable-code", diag.Warning, "statement after return never runs", checkUnreachableCode},
+	{"constant-condition", diag.Warning, "if condition is always true or always false", checkConstantConditions},
+	{"self-comparison", diag.Warning, "value is compared with itself", checkSelfComparisons},
+	{"missing-return", diag.Warning, "function returns in some branches only", checkMissingReturns},
+	{"integer-division", diag.Warning, "integer division drops the remainder", checkIntegerDivisions},
+}
+
+// Config switches rules off or changes their severity, keyed by rule ID.
+type Config struct {
+	Disabled map[string]bool
+	Severity map[string]diag.Severity
+}
+
+// ParseConfig reads a comma separated list of settings like
+// "unused-let=off,integer-division=error".
+func ParseConfig(s string) (Config, error) {
+	config := Config{Disabled: map[string]bool{}, Severity: map[string]diag.Severity{}}
+	for _, setting := range strings.Split(s, ",") {
+		setting = strings.TrimSpace(setting)
+		if setting == "" {
+			continue
+		}
+		id, value, ok := strings.Cut(setting, "=")
+		if !ok {
+			return config, fmt.Errorf("invalid rule setting %q, want ID=off|warning|error", setting)
+		}
+		if _, ok := findRule(id); !ok {
+			return config, fmt.Errorf("unknown rule: %s", id)
+		}
+		if value == "off" {
+			config.Disabled[id] = true
+			continue
+		}
+		severity, ok := diag.ParseSeverity(value)
+		if !ok {
+			return config, fmt.Errorf("invalid severity %q for rule %s", value, id)
+		}
+		config.Severity[id] = severity
+	}
+	return config, nil
+}
+
+func findRule(id string) (Rule, bool) {
+	for _, rule := range Rules {
+		if rule.ID == id {
+			return rule, true
+		}
+	}
+	return Rule{}, false
+}
+
+// Lint runs all enabled rules on program. comments are the comments the
+// lexer collected and are searched for suppressions.
+func Lint(program *ast.Program, comments []token.Token, config Config) []diag.Diagnostic {
+	// The resolver annotations tell which let a name refers to.
+	resolve.Resolve(program, nil)
+	suppressed := suppressions(comments)
+
+	diagnostics := []diag.Diagnostic{}
+	for _, rule := range Rules {
+		if config.Disabled[rule.ID] {
+			continue
+		}
+		severity := rule.Severity
+		if s, ok := config.Severity[rule.ID]; ok {
+			severity = s
+		}
+
+		rule.check(program, func(node ast.Node, format string, a ...any) {
+			d := diag.At(node, severity, format, a...)
+			d.Code = rule.ID
+			if ids, ok := suppressed[d.Line]; ok && (ids[""] || ids[rule.ID]) {
+				return
+			}
+			diagnostics = append(diagnostics, d)
+		})
+	}
+
+	diag.Sort(diagnostics)
+	return diagnostics
+}
+
+// suppressions maps a line to the rule IDs silenced on it. The empty ID
+// stands for all rules.
+func suppressions(comments []token.Token) map[int]map[string]bool {
+	suppressed := map[int]map[string]bool{}
+	for _, c := range comments {
+		text := strings.TrimSpace(strings.TrimPrefix(c.Literal, "//"))
+		rest, ok := strings.CutPrefix(text, SUPPRESS)
+		if !ok || rest != "" && rest[0] != ' ' {
+			continue
+		}
+
+		ids := strings.Split(rest, ",")
+		for _, line := range []int{c.Line, c.Line + 1} {
+			if suppressed[line] == nil {
+				suppressed[line] = map[string]bool{}
+			}
+			for _, id := range ids {
+				suppressed[line][strings.TrimSpace(id)] = true
+			}
+		}
+	}
+	return suppressed
+}
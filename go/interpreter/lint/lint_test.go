@@ -0,0 +1,137 @@
+package lint
+
+import (
+	"interpreter/lexer"
+	"interpreter/parser"
+	"testing"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; a", nil},
+		{"let a = 1;", []string{"1:5: warning: a is declared but never used [unused-let]"}},
+		{"export let a = 1;", nil},
+		{
+			"let f = fn(x) { let y = x; x }; f(1)",
+			[]string{"1:21: warning: y is declared but never used [unused-let]"},
+		},
+		{"let f = fn(n) { f(n) }; f(1)", nil},
+		{
+			"let f = fn() { return 1; 2 }; f()",
+			[]string{"1:26: warning: unreachable code after return [unreachable-code]"},
+		},
+		{"if (true) { 1 }", []string{"1:5: warning: condition is always true [constant-condition]"}},
+		{"if (1 > 2) { 1 }", []string{"1:7: warning: condition is always false [constant-condition]"}},
+		{"if (!(1 == true)) { 1 }", []string{"1:5: warning: condition is always true [constant-condition]"}},
+		{"if (1 / 0 > 1) { 1 }", nil},
+		{
+			"let x = 1; x == x",
+			[]string{"1:14: warning: (x == x) compares a value with itself [self-comparison]"},
+		},
+		{"let f = fn() { 1 }; f() == f()", nil},
+		{
+			"let f = fn(x) { if (x > 0) { return 1; } }; f(1)",
+			[]string{"1:9: warning: function does not return a value in all branches [missing-return]"},
+		},
+		{"let f = fn(x) { if (x > 0) { return 1; } 0 }; f(1)", nil},
+		{"let f = fn(x) { yield x; if (x > 0) { return 0; } }; f(1)", nil},
+		{"let f = fn(x) { if (x > 0) { return 1; } else { return 2; } }; f(1)", nil},
+		{
+			"7 / 2",
+			[]string{"1:3: warning: integer division (7 / 2) truncates to 3 [integer-division]"},
+		},
+		{"8 / 2; 7.0 / 2; 1 / 0", nil},
+		{
+			"(3 + 4) / -2",
+			[]string{"1:9: warning: integer division ((3 + 4) / (-2)) truncates to -3 [integer-division]"},
+		},
+		{"let n = 1; let f = fn(a = n) { a }; f()", nil},
+		{"let [a, b] = [1, 2]; a", []string{"1:9: warning: b is declared but never used [unused-let]"}},
+		{"let total = 0; let xs = [1]; for (x in xs) { let y = x; total + x }", []string{
+			"1:50: warning: y is declared but never used [unused-let]",
+		}},
+		{"let c = chan(); let n = 1; select { x = recv(c) => n + x, _ => 0 }", nil},
+		{"struct P { x }; let a = 1; fn (p P) f() { let y = 1; p }", []string{
+			"1:21: warning: a is declared but never used [unused-let]",
+			"1:47: warning: y is declared but never used [unused-let]",
+		}},
+	}
+
+	for _, tt := range tests {
+		checkLint(t, tt.input, "", tt.expected)
+	}
+}
+
+func TestSuppression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; // lint:ignore unused-let", nil},
+		{"// lint:ignore\nlet a = 7 / 2;", nil},
+		{
+			"// lint:ignore unused-let\nlet a = 7 / 2;",
+			[]string{"2:11: warning: integer division (7 / 2) truncates to 3 [integer-division]"},
+		},
+		{
+			"let a = 1; // lint:ignore integer-division, unreachable-code\nlet b = 2;",
+			[]string{
+				"1:5: warning: a is declared but never used [unused-let]",
+				"2:5: warning: b is declared but never used [unused-let]",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		checkLint(t, tt.input, "", tt.expected)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	input := "let a = 7 / 2;"
+
+	checkLint(t, input, "unused-let=off", []string{
+		"1:11: warning: integer division (7 / 2) truncates to 3 [integer-division]",
+	})
+	checkLint(t, input, "unused-let=off, integer-division=error", []string{
+		"1:11: error: integer division (7 / 2) truncates to 3 [integer-division]",
+	})
+
+	for _, invalid := range []string{"unused-let", "no-such-rule=off", "unused-let=loud"} {
+		if _, err := ParseConfig(invalid); err == nil {
+			t.Errorf("ParseConfig(%q) returned no error", invalid)
+		}
+	}
+}
+
+// HELPER
+
+func checkLint(t *testing.T, input, config string, expected []string) {
+	t.Helper()
+
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	c, err := ParseConfig(config)
+	if err != nil {
+		t.Fatalf("ParseConfig(%q) failed: %s", config, err)
+	}
+
+	diagnostics := Lint(program, l.Comments(), c)
+	if len(diagnostics) != len(expected) {
+		t.Errorf("wrong number of findings for %q. expected=%v, got=%v",
+			input, expected, diagnostics)
+		return
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("wrong finding for %q. expected=%q, got=%q", input, expected[i], d.String())
+		}
+	}
+}
@@ -0,0 +1,231 @@
+package lint
+
+import (
+	"interpreter/ast"
+	"interpreter/optimize"
+)
+
+// checkUnusedLets reports let bindings whose slot is never read. It relies on
+// the depth and index the resolver put on every identifier.
+func checkUnusedLets(program *ast.Program, report reportFunc) {
+	type scope struct {
+		lets []*ast.Identifier
+		used map[int]bool
+	}
+	newScope := func() *scope { return &scope{used: map[int]bool{}} }
+	reportUnused := func(s *scope) {
+		for _, name := range s.lets {
+			if !s.used[name.Index] {
+				report(name, "%s is declared but never used", name.Value)
+			}
+		}
+	}
+
+	scopes := []*scope{newScope()}
+	var visit func(ast.Node) bool
+	visit = func(n ast.Node) bool {
+		current := scopes[len(scopes)-1]
+		switch n := n.(type) {
+		case *ast.FunctionLiteral:
+			scopes = append(scopes, newScope())
+			for _, def := range n.Defaults {
+				if def != nil {
+					ast.Walk(def, visit)
+				}
+			}
+			ast.Walk(n.Body, visit)
+			reportUnused(scopes[len(scopes)-1])
+			scopes = scopes[:len(scopes)-1]
+			return false
+		case *ast.ForExpression:
+			// the resolver gives the loop body a scope of its own
+			ast.Walk(n.Iterable, visit)
+			scopes = append(scopes, newScope())
+			ast.Walk(n.Pattern, visit)
+			ast.Walk(n.Body, visit)
+			reportUnused(scopes[len(scopes)-1])
+			scopes = scopes[:len(scopes)-1]
+			return false
+		case *ast.SelectCase:
+			// so does each case of a select
+			if n.Op != nil {
+				ast.Walk(n.Op, visit)
+			}
+			scopes = append(scopes, newScope())
+			ast.Walk(n.Body, visit)
+			reportUnused(scopes[len(scopes)-1])
+			scopes = scopes[:len(scopes)-1]
+			return false
+		case *ast.MethodDeclaration:
+			// the receiver belongs to the scope of the method
+			ast.Walk(n.Struct, visit)
+			ast.Walk(n.Function, visit)
+			return false
+		case *ast.LetStatement:
+			// exported lets are used by the files importing the module
+			for _, name := range n.Names() {
+				if name.Resolved && !n.Exported && !declaresSlot(current.lets, name.Index) {
+					current.lets = append(current.lets, name)
+				}
+			}
+			if n.Value != nil {
+				ast.Walk(n.Value, visit)
+			}
+			return false
+		case *ast.Identifier:
+			if n.Resolved && n.Depth < len(scopes) {
+				scopes[len(scopes)-1-n.Depth].used[n.Index] = true
+			}
+		}
+		return true
+	}
+	ast.Walk(program, visit)
+	reportUnused(scopes[0])
+}
+
+func declaresSlot(lets []*ast.Identifier, index int) bool {
+	for _, name := range lets {
+		if name.Index == index {
+			return true
+		}
+	}
+	return false
+}
+
+func checkUnreachableCode(program *ast.Program, report reportFunc) {
+	checkStatements := func(statements []ast.Statement) {
+		for i, stmt := range statements[:max(len(statements)-1, 0)] {
+			if _, ok := stmt.(*ast.ReturnStatement); ok {
+				report(statements[i+1], "unreachable code after return")
+				return
+			}
+		}
+	}
+
+	checkStatements(program.Statements)
+	ast.Walk(program, func(n ast.Node) bool {
+		if block, ok := n.(*ast.BlockStatement); ok {
+			checkStatements(block.Statements)
+		}
+		return true
+	})
+}
+
+func checkConstantConditions(program *ast.Program, report reportFunc) {
+	ast.Walk(program, func(n ast.Node) bool {
+		ifExp, ok := n.(*ast.IfExpression)
+		if !ok {
+			return true
+		}
+		value, ok := optimize.Value(ifExp.Condition)
+		if !ok {
+			return true
+		}
+		if b, ok := value.(bool); ok && !b {
+			report(ifExp.Condition, "condition is always false")
+		} else {
+			report(ifExp.Condition, "condition is always true")
+		}
+		return true
+	})
+}
+
+func checkSelfComparisons(program *ast.Program, report reportFunc) {
+	ast.Walk(program, func(n ast.Node) bool {
+		infix, ok := n.(*ast.InfixExpression)
+		if !ok || infix.Left == nil || infix.Right == nil {
+			return true
+		}
+		switch infix.Operator {
+		case "==", "!=", "<", ">":
+		default:
+			return true
+		}
+		if infix.Left.String() == infix.Right.String() && !hasCall(infix.Left) {
+			report(infix, "%s compares a value with itself", infix.String())
+		}
+		return true
+	})
+}
+
+// checkMissingReturns looks at functions that use an explicit return and
+// reports them if some path reaches the end of the body without a value.
+// In generators a return only ends the iteration.
+func checkMissingReturns(program *ast.Program, report reportFunc) {
+	ast.Walk(program, func(n ast.Node) bool {
+		fn, ok := n.(*ast.FunctionLiteral)
+		if ok && !fn.Generator && containsReturn(fn.Body) && !returnsValue(fn.Body) {
+			report(fn, "function does not return a value in all branches")
+		}
+		return true
+	})
+}
+
+func checkIntegerDivisions(program *ast.Program, report reportFunc) {
+	ast.Walk(program, func(n ast.Node) bool {
+		infix, ok := n.(*ast.InfixExpression)
+		if !ok || infix.Operator != "/" {
+			return true
+		}
+		left, _ := optimize.Value(infix.Left)
+		right, _ := optimize.Value(infix.Right)
+		l, leftOk := left.(int64)
+		r, rightOk := right.(int64)
+		if leftOk && rightOk && r != 0 && l%r != 0 {
+			report(infix, "integer division %s truncates to %d", infix.String(), l/r)
+		}
+		return true
+	})
+}
+
+func hasCall(node ast.Node) bool {
+	found := false
+	ast.Walk(node, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.CallExpression, *ast.PipeExpression:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// containsReturn looks for a return of the function itself, not of nested ones.
+func containsReturn(body *ast.BlockStatement) bool {
+	found := false
+	ast.Walk(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.FunctionLiteral:
+			return false
+		case *ast.ReturnStatement:
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// returnsValue reports whether every path through block ends in a return or
+// in an expression whose value becomes the result.
+func returnsValue(block *ast.BlockStatement) bool {
+	if block == nil || len(block.Statements) == 0 {
+		return false
+	}
+	for _, stmt := range block.Statements {
+		if _, ok := stmt.(*ast.ReturnStatement); ok {
+			return true
+		}
+	}
+
+	switch last := block.Statements[len(block.Statements)-1].(type) {
+	case *ast.ExpressionStatement:
+		if ifExp, ok := last.Expression.(*ast.IfExpression); ok {
+			return returnsValue(ifExp.Consequence) && returnsValue(ifExp.Alternative)
+		}
+		return true
+	case *ast.BlockStatement:
+		return returnsValue(last)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,158 @@
+package macro
+
+import (
+	"context"
+	"interpreter/ast"
+	"interpreter/diag"
+	"interpreter/eval"
+	"interpreter/object"
+)
+
+// Expand runs before the evaluation. It moves the macros bound by top-level
+// lets from program into env and replaces every call of a macro by the code
+// the macro returns. Macros get their arguments unevaluated as quotes, so
+// they can build control constructs:
+//
+//	let unless = macro(cond, cons, alt) {
+//	  quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) })
+//	};
+//
+// The bodies of the macros are evaluated with c, so its limits and
+// capabilities apply to them, and stop once ctx is done.
+func Expand(ctx context.Context, c *eval.Context, program *ast.Program, env *object.Environment) []diag.Diagnostic {
+	x := &expander{ctx: ctx, context: c, env: env}
+	x.defineMacros(program)
+	ast.Modify(program, x.expandCall)
+	x.checkQuotes(program, false)
+
+	diag.Sort(x.diagnostics)
+	return x.diagnostics
+}
+
+type expander struct {
+	ctx         context.Context
+	context     *eval.Context
+	env         *object.Environment
+	diagnostics []diag.Diagnostic
+}
+
+func (x *expander) report(node ast.Node, format string, a ...any) {
+	x.diagnostics = append(x.diagnostics, diag.At(node, diag.Error, format, a...))
+}
+
+func (x *expander) defineMacros(program *ast.Program) {
+	statements := []ast.Statement{}
+	for _, stmt := range program.Statements {
+		if let, ok := stmt.(*ast.LetStatement); ok {
+			if lit, ok := let.Value.(*ast.MacroLiteral); ok && let.Name != nil {
+				x.env.Set(let.Name.Value, &object.Macro{
+					Parameters: lit.Parameters,
+					Body:       lit.Body,
+					Env:        x.env,
+				})
+				continue
+			}
+		}
+		statements = append(statements, stmt)
+	}
+	program.Statements = statements
+
+	ast.Walk(program, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.MacroLiteral); ok {
+			x.report(lit, "macro literal outside of a top-level let")
+			return false
+		}
+		return true
+	})
+}
+
+// expandCall replaces a macro call by the code the macro returns.
+func (x *expander) expandCall(node ast.Node) ast.Node {
+	call, ok := node.(*ast.CallExpression)
+	if !ok {
+		return node
+	}
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return node
+	}
+	obj, ok := x.env.Get(ident.Value)
+	if !ok {
+		return node
+	}
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		return node
+	}
+
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.NamedArgument); ok {
+			x.report(arg, "macro %s does not take named arguments", ident.Value)
+			return node
+		}
+	}
+	if len(call.Arguments) != len(macro.Parameters) {
+		x.report(call, "wrong number of arguments for macro %s: want=%d, got=%d",
+			ident.Value, len(macro.Parameters), len(call.Arguments))
+		return node
+	}
+
+	evalEnv := object.NewEnclosedEnvironment(macro.Env)
+	for i, param := range macro.Parameters {
+		evalEnv.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+	}
+
+	evaluated := x.context.Run(x.ctx, macro.Body, evalEnv)
+	if returnValue, ok := evaluated.(*object.ReturnValue); ok {
+		evaluated = returnValue.Value
+	}
+
+	switch evaluated := evaluated.(type) {
+	case *object.Quote:
+		return evaluated.Node
+	case *object.Error:
+		x.report(call, "macro %s failed: %s", ident.Value, evaluated.Message)
+	case nil:
+		x.report(call, "macro %s must return a quote, got nothing", ident.Value)
+	default:
+		x.report(call, "macro %s must return a quote, got %s", ident.Value, evaluated.Type())
+	}
+	return node
+}
+
+// checkQuotes reports unquote calls outside of quote and wrong argument
+// counts of both.
+func (x *expander) checkQuotes(node ast.Node, inQuote bool) {
+	ast.Walk(node, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpression)
+		if !ok {
+			return true
+		}
+
+		name := ""
+		if ident, ok := call.Function.(*ast.Identifier); ok {
+			name = ident.Value
+		}
+		switch name {
+		case "quote":
+			if len(call.Arguments) != 1 {
+				x.report(call, "wrong number of arguments for quote: want=1, got=%d", len(call.Arguments))
+			}
+			for _, arg := range call.Arguments {
+				x.checkQuotes(arg, true)
+			}
+			return false
+		case "unquote":
+			if !inQuote {
+				x.report(call, "unquote outside of quote")
+			} else if len(call.Arguments) != 1 {
+				x.report(call, "wrong number of arguments for unquote: want=1, got=%d", len(call.Arguments))
+			}
+			for _, arg := range call.Arguments {
+				x.checkQuotes(arg, false)
+			}
+			return false
+		}
+		return true
+	})
+}
@@ -0,0 +1,165 @@
+package macro
+
+import (
+	"context"
+	"interpreter/ast"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"testing"
+)
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+
+	env := object.NewEnvironment()
+	program := testParseProgram(t, input)
+	diagnostics := Expand(context.Background(), eval.NewContext(), program, env)
+	if len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("wrong number of statements. got=%d", len(program.Statements))
+	}
+	if _, ok := env.Get("number"); ok {
+		t.Fatalf("number should not be defined")
+	}
+	if _, ok := env.Get("function"); ok {
+		t.Fatalf("function should not be defined")
+	}
+
+	obj, ok := env.Get("mymacro")
+	if !ok {
+		t.Fatalf("macro not in environment.")
+	}
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		t.Fatalf("object is not Macro. got=%T (%+v)", obj, obj)
+	}
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("wrong number of macro parameters. got=%d", len(macro.Parameters))
+	}
+	if macro.Body.String() != "(x + y)" {
+		t.Fatalf("body is not %q. got=%q", "(x + y)", macro.Body.String())
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`
+			let infixExpression = macro() { quote(1 + 2); };
+			infixExpression();
+			`,
+			`(1 + 2)`,
+		},
+		{
+			`
+			let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); };
+			reverse(2 + 2, 10 - 5);
+			`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`
+			let unless = macro(condition, consequence, alternative) {
+				quote(if (!(unquote(condition))) {
+					unquote(consequence);
+				} else {
+					unquote(alternative);
+				});
+			};
+			unless(10 > 5, 1, 2);
+			`,
+			`if (!(10 > 5)) { 1 } else { 2 }`,
+		},
+		{
+			`
+			let twice = macro(x) { quote(unquote(x) + unquote(x)); };
+			let f = fn(a) { twice(a * 2) };
+			`,
+			`let f = fn(a) { (a * 2) + (a * 2) };`,
+		},
+	}
+
+	for _, tt := range tests {
+		expected := testParseProgram(t, tt.expected)
+		program := testParseProgram(t, tt.input)
+
+		diagnostics := Expand(context.Background(), eval.NewContext(), program, object.NewEnvironment())
+		if len(diagnostics) != 0 {
+			t.Errorf("unexpected diagnostics for %q: %v", tt.input, diagnostics)
+			continue
+		}
+		if program.String() != expected.String() {
+			t.Errorf("not equal. want=%q, got=%q", expected.String(), program.String())
+		}
+	}
+}
+
+func TestExpandDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let m = macro(a) { quote(a) };\nm(1, 2)",
+			[]string{"2:2: error: wrong number of arguments for macro m: want=1, got=2"},
+		},
+		{
+			"let m = macro() { 1 };\nm()",
+			[]string{"2:2: error: macro m must return a quote, got INTEGER"},
+		},
+		{
+			"let m = macro() { 1 + true };\nm()",
+			[]string{"2:2: error: macro m failed: type mismatch: INTEGER + BOOLEAN"},
+		},
+		{
+			"let f = fn() { macro(x) { x } };",
+			[]string{"1:16: error: macro literal outside of a top-level let"},
+		},
+		{
+			"let m = macro(a) { quote(a) };\nm(a: 1)",
+			[]string{"2:3: error: macro m does not take named arguments"},
+		},
+		{"unquote(1)", []string{"1:8: error: unquote outside of quote"}},
+		{"quote(1, 2)", []string{"1:6: error: wrong number of arguments for quote: want=1, got=2"}},
+		{"quote(unquote())", []string{"1:14: error: wrong number of arguments for unquote: want=1, got=0"}},
+		{"quote(unquote(unquote(1)))", []string{"1:22: error: unquote outside of quote"}},
+	}
+
+	for _, tt := range tests {
+		program := testParseProgram(t, tt.input)
+		diagnostics := Expand(context.Background(), eval.NewContext(), program, object.NewEnvironment())
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. expected=%v, got=%v",
+				tt.input, tt.expected, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("wrong diagnostic for %q. expected=%q, got=%q",
+					tt.input, tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func testParseProgram(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
@@ -1,9 +1,22 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"interpreter/debugger"
+	"interpreter/diag"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/lint"
+	"interpreter/object"
+	"interpreter/parser"
+	"interpreter/pipeline"
 	"interpreter/repl"
 	"os"
+	"path/filepath"
+	"strings"
 )
 
 /*
@@ -48,9 +61,182 @@ ASCII Art: Pratt Parser
           +-------------------+
 */
 
-// It prompts the user to enter a line of code and then starts
-// the read-eval-print loop (REPL) using the standard input and output.
+const USAGE = `usage:
+  interpreter                   start the REPL
+  interpreter run [-ast] [-path DIRS] [-max-depth N] [-max-steps N]
+                  [-max-size N] [-timeout D] [-deny CAPS] FILE
+                                evaluate FILE, -ast prints the optimized AST first
+  interpreter debug [-path DIRS] FILE
+                                evaluate FILE in the debugger
+  interpreter lint [-format text|json] [-rules ID=off|warning|error,...] FILE
+                                check FILE with the linter
+
+Imports are looked up next to the importing file, then in the directories
+of -path, which are separated like in $PATH. The -max and -timeout flags
+limit the evaluation, -deny takes a comma separated list of capabilities
+the program may not use: import, output.
+`
+
+// Without arguments it prompts the user to enter a line of code and then
+// starts the read-eval-print loop (REPL) using the standard input and output.
+// The run and debug commands evaluate a whole file instead.
 func main() {
-	fmt.Printf("Bitte Programmzeile eingeben: \n")
-	repl.Start(os.Stdin, os.Stdout)
+	if len(os.Args) < 2 {
+		fmt.Printf("Bitte Programmzeile eingeben: \n")
+		repl.Start(os.Stdin, os.Stdout)
+		return
+	}
+
+	flags := flag.NewFlagSet(os.Args[1], flag.ExitOnError)
+	flags.Usage = usage
+
+	switch os.Args[1] {
+	case "run":
+		printAST := flags.Bool("ast", false, "print the optimized AST before evaluating")
+		searchPath := flags.String("path", "", "directories searched for imported modules")
+		c := eval.NewContext()
+		flags.IntVar(&c.Limits.MaxDepth, "max-depth", eval.DefaultMaxDepth, "maximum number of nested function calls")
+		flags.Int64Var(&c.Limits.MaxSteps, "max-steps", 0, "maximum number of evaluation steps, 0 for no limit")
+		flags.IntVar(&c.Limits.MaxSize, "max-size", 0, "maximum size of a string in bytes or of an array, hash or channel in elements, 0 for no limit")
+		flags.DurationVar(&c.Limits.Timeout, "timeout", 0, "maximum evaluation time, 0 for no limit")
+		deny := flags.String("deny", "", "comma separated capabilities the program may not use")
+		source := readSource(flags)
+		c.Loader = pipeline.NewLoader(flags.Arg(0), filepath.SplitList(*searchPath))
+		c.Capabilities = capabilities(*deny)
+		if !runFile(c, source, *printAST) {
+			os.Exit(1)
+		}
+	case "debug":
+		searchPath := flags.String("path", "", "directories searched for imported modules")
+		source := readSource(flags)
+		c := eval.NewContext()
+		c.Loader = pipeline.NewLoader(flags.Arg(0), filepath.SplitList(*searchPath))
+		debugger.Start(c, source, os.Stdin, os.Stdout)
+	case "lint":
+		format := flags.String("format", "text", "output format, text or json")
+		rules := flags.String("rules", "", "comma separated rule settings like unused-let=off")
+		source := readSource(flags)
+		if !lintFile(flags.Arg(0), source, *format, *rules) {
+			os.Exit(1)
+		}
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprint(os.Stderr, USAGE)
+	os.Exit(2)
+}
+
+// readSource parses the command line flags and reads the file named by the
+// only remaining argument.
+func readSource(flags *flag.FlagSet) string {
+	flags.Parse(os.Args[2:])
+	if flags.NArg() != 1 {
+		usage()
+	}
+
+	source, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	return string(source)
+}
+
+// runFile prepares and evaluates source and prints the result. It reports
+// false if the source could not be parsed, the static checks found errors or
+// the evaluation ended in an error.
+func runFile(c *eval.Context, source string, printAST bool) bool {
+	p := parser.New(lexer.New(source))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintln(os.Stderr, "\t"+msg)
+		}
+		return false
+	}
+
+	program, diagnostics := pipeline.Prepare(context.Background(), c, program, object.NewEnvironment(), eval.BuiltinNames())
+	for _, d := range diagnostics {
+		fmt.Fprintln(os.Stderr, d)
+	}
+	if diag.HasErrors(diagnostics) {
+		return false
+	}
+
+	if printAST {
+		for _, stmt := range program.Statements {
+			fmt.Println(stmt.String())
+		}
+	}
+
+	evaluated := c.Run(context.Background(), program, object.NewEnvironment())
+	if evaluated == nil {
+		return true
+	}
+	fmt.Println(evaluated.Inspect())
+	return evaluated.Type() != object.ERROR_OBJ
+}
+
+// capabilities returns the capability set without the comma separated
+// capabilities in deny. It is nil, which allows everything, if deny is empty.
+func capabilities(deny string) map[eval.Capability]bool {
+	if deny == "" {
+		return nil
+	}
+	allowed := eval.AllCapabilities()
+	for _, name := range strings.Split(deny, ",") {
+		name = strings.TrimSpace(name)
+		if !allowed[eval.Capability(name)] {
+			fmt.Fprintf(os.Stderr, "unknown capability: %s\n", name)
+			os.Exit(2)
+		}
+		delete(allowed, eval.Capability(name))
+	}
+	return allowed
+}
+
+// lintFile prints the findings of the linter for source. It reports false if
+// the source could not be parsed or a finding has the severity error.
+func lintFile(name, source, format, rules string) bool {
+	config, err := lint.ParseConfig(rules)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	l := lexer.New(source)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintln(os.Stderr, "\t"+msg)
+		}
+		return false
+	}
+
+	diagnostics := lint.Lint(program, l.Comments(), config)
+	switch format {
+	case "json":
+		type finding struct {
+			File string `json:"file"`
+			diag.Diagnostic
+		}
+		findings := []finding{}
+		for _, d := range diagnostics {
+			findings = append(findings, finding{name, d})
+		}
+		out, _ := json.MarshalIndent(findings, "", "  ")
+		fmt.Println(string(out))
+	case "text":
+		for _, d := range diagnostics {
+			fmt.Printf("%s:%s\n", name, d)
+		}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown format: %s\n", format)
+		os.Exit(2)
+	}
+	return !diag.HasErrors(diagnostics)
 }
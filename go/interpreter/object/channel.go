@@ -0,0 +1,20 @@
+package object
+
+import "strconv"
+
+// Channel passes values between tasks. C buffers up to cap(C) values, the
+// evaluator never blocks on it but hands values to the tasks waiting on the
+// channel itself. Closing the channel sets Closed, receives then get the
+// buffered values and then null.
+type Channel struct {
+	C      chan Object
+	Closed bool
+}
+
+// NewChannel returns an open channel with a buffer of size values.
+func NewChannel(size int) *Channel {
+	return &Channel{C: make(chan Object, size)}
+}
+
+func (ch *Channel) Type() ObjectType { return CHANNEL_OBJ }
+func (ch *Channel) Inspect() string  { return "chan(" + strconv.Itoa(cap(ch.C)) + ")" }
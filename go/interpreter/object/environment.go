@@ -0,0 +1,141 @@
+package object
+
+import "sync"
+
+// Environment holds the bindings of one scope. Function calls create an
+// enclosed environment whose outer scope is the one the function was
+// defined in. It is safe for concurrent use, e.g. by a host reading
+// bindings while tasks of the program run.
+//
+// Names the resolver gave a slot are bound with SetAt and looked up with
+// GetAt, which goes straight to the scope and slot instead of searching
+// the scopes by name. Both kinds of bindings can also be reached by name.
+type Environment struct {
+	mu    sync.RWMutex
+	store map[string]Object
+	slots []slot
+	// consts holds the names bound by a const in this scope.
+	consts map[string]bool
+	outer  *Environment
+}
+
+// slot is a binding made by SetAt. An unused slot has no value.
+type slot struct {
+	name  string
+	value Object
+}
+
+func NewEnvironment() *Environment {
+	return &Environment{store: make(map[string]Object)}
+}
+
+func NewEnclosedEnvironment(outer *Environment) *Environment {
+	env := NewEnvironment()
+	env.outer = outer
+	return env
+}
+
+// Get looks name up in this scope and then in the enclosing ones.
+func (e *Environment) Get(name string) (Object, bool) {
+	e.mu.RLock()
+	obj, ok := e.store[name]
+	if i := e.slotIndex(name); i >= 0 {
+		obj, ok = e.slots[i].value, true
+	}
+	e.mu.RUnlock()
+	if !ok && e.outer != nil {
+		obj, ok = e.outer.Get(name)
+	}
+	return obj, ok
+}
+
+// GetAt returns the value in slot index of the scope depth levels out from
+// this one. ok is false if the slot does not hold a binding of name, e.g.
+// because its let has not run yet.
+func (e *Environment) GetAt(depth, index int, name string) (Object, bool) {
+	for ; depth > 0 && e != nil; depth-- {
+		e = e.outer
+	}
+	if e == nil {
+		return nil, false
+	}
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if index >= len(e.slots) || e.slots[index].value == nil || e.slots[index].name != name {
+		return nil, false
+	}
+	return e.slots[index].value, true
+}
+
+// Set binds name in this scope.
+func (e *Environment) Set(name string, val Object) Object {
+	e.mu.Lock()
+	if i := e.slotIndex(name); i >= 0 {
+		e.slots[i].value = val
+	} else {
+		e.store[name] = val
+	}
+	e.mu.Unlock()
+	return val
+}
+
+// SetAt binds name in slot index of this scope.
+func (e *Environment) SetAt(index int, name string, val Object) Object {
+	e.mu.Lock()
+	if index >= len(e.slots) {
+		e.slots = append(e.slots, make([]slot, index+1-len(e.slots))...)
+	}
+	e.slots[index] = slot{name: name, value: val}
+	delete(e.store, name)
+	e.mu.Unlock()
+	return val
+}
+
+// slotIndex returns the slot bound to name or -1. mu must be held.
+func (e *Environment) slotIndex(name string) int {
+	for i, s := range e.slots {
+		if s.name == name && s.value != nil {
+			return i
+		}
+	}
+	return -1
+}
+
+// SetConst marks name as bound by a const in this scope.
+func (e *Environment) SetConst(name string) {
+	e.mu.Lock()
+	if e.consts == nil {
+		e.consts = make(map[string]bool)
+	}
+	e.consts[name] = true
+	e.mu.Unlock()
+}
+
+// IsConst reports whether name is bound by a const in this scope. Enclosing
+// scopes are not searched, as a name can always be shadowed.
+func (e *Environment) IsConst(name string) bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return e.consts[name]
+}
+
+// Outer returns the enclosing scope or nil for the global one.
+func (e *Environment) Outer() *Environment {
+	return e.outer
+}
+
+// Names returns the names bound directly in this scope.
+func (e *Environment) Names() []string {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	names := make([]string, 0, len(e.store)+len(e.slots))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	for _, s := range e.slots {
+		if s.value != nil {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
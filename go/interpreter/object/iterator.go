@@ -0,0 +1,159 @@
+package object
+
+import (
+	"math"
+	"strconv"
+	"unicode/utf8"
+)
+
+// Iterator yields the elements of a sequence one at a time. Next returns
+// false once there are no more elements. An *Error returned as element ends
+// the iteration with that error.
+type Iterator interface {
+	Object
+	Next() (Object, bool)
+}
+
+// Iterable is implemented by the values for-in and the sequence builtins
+// consume. Iterate returns an iterator positioned at the first element.
+type Iterable interface {
+	Iterate() Iterator
+}
+
+// Range is the integers from Start up to End, including End if Inclusive,
+// Step apart. A Step of 0 counts by 1, a negative one counts down. Its
+// elements are only created while it is iterated.
+type Range struct {
+	Start     int64
+	End       int64
+	Step      int64
+	Inclusive bool
+}
+
+// FuncIterator is an iterator whose elements are returned by Fn. Name
+// describes where the elements come from, e.g. range or map.
+type FuncIterator struct {
+	Name string
+	Fn   func() (Object, bool)
+}
+
+func (r *Range) Type() ObjectType { return RANGE_OBJ }
+
+// Inspect shows the range the way it was written, as start..end or as the
+// call of the range builtin if it has a step.
+func (r *Range) Inspect() string {
+	start, end := strconv.FormatInt(r.Start, 10), strconv.FormatInt(r.End, 10)
+	if r.step() != 1 {
+		return "range(" + start + ", " + end + ", " + strconv.FormatInt(r.Step, 10) + ")"
+	}
+	if r.Inclusive {
+		return start + "..=" + end
+	}
+	return start + ".." + end
+}
+
+// Len returns the number of integers in the range. ok is false if the
+// number is too large for an integer, e.g. for 0..=9223372036854775807.
+func (r *Range) Len() (n int64, ok bool) {
+	last, ok := r.last()
+	if !ok {
+		return 0, true
+	}
+	if last >= math.MaxInt64 {
+		return 0, false
+	}
+	return int64(last) + 1, true
+}
+
+func (r *Range) Iterate() Iterator {
+	last, ok := r.last()
+	i, done := uint64(0), !ok
+	return &FuncIterator{Name: "range", Fn: func() (Object, bool) {
+		if done {
+			return nil, false
+		}
+		// computed unsigned, where it wraps around like the int64 it ends
+		// up as, so a large step does not overflow
+		value := int64(uint64(r.Start) + i*uint64(r.step()))
+		done = i == last
+		i++
+		return &Integer{Value: value}, true
+	}}
+}
+
+func (r *Range) step() int64 {
+	if r.Step == 0 {
+		return 1
+	}
+	return r.Step
+}
+
+// last returns the index of the last element, false if the range is empty.
+// The distance is computed unsigned, it does not fit an int64 for ranges
+// spanning more than half the integers.
+func (r *Range) last() (uint64, bool) {
+	var d, step uint64
+	switch s := r.step(); {
+	case s > 0 && r.End >= r.Start:
+		d, step = uint64(r.End)-uint64(r.Start), uint64(s)
+	case s < 0 && r.End <= r.Start:
+		d, step = uint64(r.Start)-uint64(r.End), -uint64(s)
+	default:
+		return 0, false
+	}
+	if r.Inclusive {
+		return d / step, true
+	}
+	if d == 0 {
+		return 0, false
+	}
+	return (d - 1) / step, true
+}
+
+func (it *FuncIterator) Type() ObjectType     { return ITERATOR_OBJ }
+func (it *FuncIterator) Inspect() string      { return "iterator " + it.Name }
+func (it *FuncIterator) Next() (Object, bool) { return it.Fn() }
+
+// Iterate returns the iterator itself, so an iterator can be consumed only
+// once.
+func (it *FuncIterator) Iterate() Iterator { return it }
+
+// Iterate yields the elements of the array.
+func (a *Array) Iterate() Iterator {
+	i := 0
+	return &FuncIterator{Name: "array", Fn: func() (Object, bool) {
+		if i >= len(a.Elements) {
+			return nil, false
+		}
+		i++
+		return a.Elements[i-1], true
+	}}
+}
+
+// Iterate yields [key, value] arrays in insertion order.
+func (h *Hash) Iterate() Iterator {
+	i := 0
+	return &FuncIterator{Name: "hash", Fn: func() (Object, bool) {
+		for i < len(h.Keys) {
+			pair, ok := h.Pairs[h.Keys[i]]
+			i++
+			if ok {
+				return &Array{Elements: []Object{pair.Key, pair.Value}}, true
+			}
+		}
+		return nil, false
+	}}
+}
+
+// Iterate yields the characters of the string.
+func (s *String) Iterate() Iterator {
+	offset := 0
+	return &FuncIterator{Name: "string", Fn: func() (Object, bool) {
+		if offset >= len(s.Value) {
+			return nil, false
+		}
+		_, size := utf8.DecodeRuneInString(s.Value[offset:])
+		offset += size
+		return &String{Value: s.Value[offset-size : offset]}, true
+	}}
+}
@@ -1,6 +1,13 @@
 package object
 
-import "fmt"
+import (
+	"bytes"
+	"fmt"
+	"hash/fnv"
+	"interpreter/ast"
+	"strconv"
+	"strings"
+)
 
 type ObjectType string
 
@@ -21,41 +28,455 @@ type Boolean struct {
 	Value bool
 }
 
+type String struct {
+	Value string
+}
+
 type ReturnValue struct {
 	Value Object
 }
 
+// Error is a runtime error or a thrown value on its way up to the next
+// catch. Line and Column point at the node that caused it and stay 0 until
+// the evaluator knows that node. Value is the thrown value, nil for runtime
+// errors. Trace lists the function calls the error left, innermost first.
 type Error struct {
 	Message string
+	Kind    string
+	Value   Object
+	Trace   []Frame
+	Line    int
+	Column  int
+}
+
+// Frame is a call of the function Function at Line and Column.
+type Frame struct {
+	Function string
+	Line     int
+	Column   int
+}
+
+// ErrorValue is an error caught by catch. Unlike Error it is an ordinary
+// value and does not unwind the evaluation.
+type ErrorValue struct {
+	Err *Error
 }
 
 type Null struct {
 	Value any
 }
 
+// Function is a closure. Name is the name of the let it was bound by, if
+// any, and is only used in stack traces. Defaults, Rest and Generator are
+// taken over from the function literal.
+type Function struct {
+	Name       string
+	Parameters []*ast.Identifier
+	Defaults   []ast.Expression
+	Rest       *ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+	Generator  bool
+}
+
+// Quote wraps code returned by quote(...) without evaluating it.
+type Quote struct {
+	Node ast.Node
+}
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+// BuiltinFunction is a function implemented in Go.
+type BuiltinFunction func(args ...Object) Object
+
+type Builtin struct {
+	Name string
+	Fn   BuiltinFunction
+}
+
+// Array is a list of values. A frozen array cannot be changed, which lets
+// the arrays Append returns for it share its elements.
+type Array struct {
+	Elements []Object
+	Frozen   bool
+	// claimed is the length of the backing array of Elements taken by the
+	// frozen arrays sharing it, nil if it is not shared.
+	claimed *int
+}
+
+// Append returns a new array of the elements of a followed by values. The
+// elements of a mutable array are copied. For a frozen array the result is
+// frozen too and values go into the spare capacity behind the elements of
+// a, unless another array has already claimed it. Appending to the last
+// version of a frozen array therefore takes amortized constant time, while
+// all earlier versions stay unchanged.
+func (a *Array) Append(values ...Object) *Array {
+	n := len(a.Elements)
+	if !a.Frozen {
+		elements := make([]Object, n, n+len(values))
+		copy(elements, a.Elements)
+		return &Array{Elements: append(elements, values...)}
+	}
+
+	elements, claimed := a.Elements, a.claimed
+	if claimed == nil || *claimed != n || cap(elements)-n < len(values) {
+		elements = make([]Object, n, 2*(n+len(values)))
+		copy(elements, a.Elements)
+		claimed = new(int)
+	}
+	elements = append(elements, values...)
+	*claimed = len(elements)
+	return &Array{Elements: elements, Frozen: true, claimed: claimed}
+}
+
+// HashKey identifies a hashable value. Equal values have equal keys.
+type HashKey struct {
+	Type  ObjectType
+	Value uint64
+}
+
+// Hashable is implemented by the values usable as hash keys.
+type Hashable interface {
+	HashKey() HashKey
+}
+
+type HashPair struct {
+	Key   Object
+	Value Object
+}
+
+// Hash maps hash keys to the original key and the value. Keys lists the
+// hash keys in insertion order, which Inspect and iteration follow. A
+// frozen hash cannot be changed.
+type Hash struct {
+	Pairs  map[HashKey]HashPair
+	Keys   []HashKey
+	Frozen bool
+}
+
+// NewHash returns an empty hash.
+func NewHash() *Hash {
+	return &Hash{Pairs: make(map[HashKey]HashPair)}
+}
+
+// Set adds or replaces the value of key.
+func (h *Hash) Set(key Hashable, value Object) {
+	hashKey := key.HashKey()
+	if _, ok := h.Pairs[hashKey]; !ok {
+		h.Keys = append(h.Keys, hashKey)
+	}
+	h.Pairs[hashKey] = HashPair{Key: key.(Object), Value: value}
+}
+
+// Module is an evaluated file. Exports holds its exported bindings.
+type Module struct {
+	Name    string
+	Path    string
+	Exports map[string]Object
+}
+
+// RecordType is the constructor bound by a struct statement. Calling it
+// creates a Record. Methods holds the methods declared for the struct, with
+// the receiver as first parameter.
+type RecordType struct {
+	Name    string
+	Fields  []*ast.Identifier
+	Methods map[string]*Function
+}
+
+// BoundMethod is a method selected from a record. Calling it passes
+// Receiver as the first argument.
+type BoundMethod struct {
+	Receiver *Record
+	Method   *Function
+}
+
+// Record is an instance of a struct. Values is parallel to the fields of
+// Struct.
+type Record struct {
+	Struct *RecordType
+	Values []Object
+}
+
 const (
-	INTEGER_OBJ = "INTEGER"
-	FLOAT_OBJ   = "FLOAT"
-	BOOLEAN_OBJ = "BOOLEAN"
-	RETURN_OBJ  = "RETURN"
-	ERROR_OBJ   = "ERROR"
-	NULL_OBJ    = "NULL"
+	INTEGER_OBJ   = "INTEGER"
+	FLOAT_OBJ     = "FLOAT"
+	BOOLEAN_OBJ   = "BOOLEAN"
+	RETURN_OBJ    = "RETURN"
+	ERROR_OBJ     = "ERROR"
+	NULL_OBJ      = "NULL"
+	FUNCTION_OBJ  = "FUNCTION"
+	QUOTE_OBJ     = "QUOTE"
+	MACRO_OBJ     = "MACRO"
+	STRING_OBJ    = "STRING"
+	MODULE_OBJ    = "MODULE"
+	BUILTIN_OBJ   = "BUILTIN"
+	ARRAY_OBJ     = "ARRAY"
+	HASH_OBJ      = "HASH"
+	STRUCT_OBJ    = "STRUCT"
+	RECORD_OBJ    = "RECORD"
+	METHOD_OBJ    = "METHOD"
+	RANGE_OBJ     = "RANGE"
+	ITERATOR_OBJ  = "ITERATOR"
+	GENERATOR_OBJ = "GENERATOR"
+	CHANNEL_OBJ   = "CHANNEL"
+
+	ERROR_VALUE_OBJ = "ERROR_VALUE"
+)
+
+// Error kinds. Values thrown by the program have the kind THROWN_ERROR.
+const (
+	THROWN_ERROR         = "Error"
+	TYPE_ERROR           = "TypeError"
+	ARGUMENT_ERROR       = "ArgumentError"
+	ZERO_DIVISION_ERROR  = "ZeroDivisionError"
+	NAME_ERROR           = "NameError"
+	IMPORT_ERROR         = "ImportError"
+	SYNTAX_ERROR         = "SyntaxError"
+	STACK_OVERFLOW_ERROR = "StackOverflowError"
+	STEP_LIMIT_ERROR     = "StepLimitError"
+	TIMEOUT_ERROR        = "TimeoutError"
+	CANCELED_ERROR       = "CanceledError"
+	SIZE_LIMIT_ERROR     = "SizeLimitError"
+	PERMISSION_ERROR     = "PermissionError"
+	MATCH_ERROR          = "MatchError"
+	CHANNEL_ERROR        = "ChannelError"
+	INDEX_ERROR          = "IndexError"
+	FROZEN_ERROR         = "FrozenError"
+	DEADLOCK_ERROR       = "DeadlockError"
+	PANIC_ERROR          = "PanicError"
 )
 
 func (i *Integer) Inspect() string  { return fmt.Sprintf("%d", i.Value) }
 func (i *Integer) Type() ObjectType { return INTEGER_OBJ }
 
-func (f *Float) Inspect() string  { return fmt.Sprintf("%f", f.Value) }
 func (f *Float) Type() ObjectType { return FLOAT_OBJ }
 
+// Inspect formats f with the fewest digits that read back to the same
+// value, in exponent notation for very large and small values. Integral
+// values keep a ".0" so they do not read like integers.
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if strings.ContainsAny(s, ".eIN") {
+		return s
+	}
+	return s + ".0"
+}
+
 func (b *Boolean) Inspect() string  { return fmt.Sprintf("%t", b.Value) }
 func (b *Boolean) Type() ObjectType { return BOOLEAN_OBJ }
 
+func (s *String) Inspect() string  { return s.Value }
+func (s *String) Type() ObjectType { return STRING_OBJ }
+
+func (i *Integer) HashKey() HashKey {
+	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
+}
+
+func (b *Boolean) HashKey() HashKey {
+	var value uint64
+	if b.Value {
+		value = 1
+	}
+	return HashKey{Type: b.Type(), Value: value}
+}
+
+func (s *String) HashKey() HashKey {
+	h := fnv.New64a()
+	h.Write([]byte(s.Value))
+	return HashKey{Type: s.Type(), Value: h.Sum64()}
+}
+
+func (b *Builtin) Inspect() string  { return "builtin " + b.Name }
+func (b *Builtin) Type() ObjectType { return BUILTIN_OBJ }
+
+func (a *Array) Type() ObjectType { return ARRAY_OBJ }
+func (a *Array) Inspect() string  { return a.inspect(map[Object]bool{}) }
+
+func (a *Array) inspect(path map[Object]bool) string {
+	if path[a] {
+		return "[...]"
+	}
+	path[a] = true
+	defer delete(path, a)
+
+	elements := []string{}
+	for _, e := range a.Elements {
+		elements = append(elements, inspectElement(e, path))
+	}
+	return "[" + strings.Join(elements, ", ") + "]"
+}
+
+func (h *Hash) Type() ObjectType { return HASH_OBJ }
+func (h *Hash) Inspect() string  { return h.inspect(map[Object]bool{}) }
+
+func (h *Hash) inspect(path map[Object]bool) string {
+	if path[h] {
+		return "{...}"
+	}
+	path[h] = true
+	defer delete(path, h)
+
+	pairs := []string{}
+	for _, key := range h.Keys {
+		pair := h.Pairs[key]
+		pairs = append(pairs, inspectElement(pair.Key, path)+": "+inspectElement(pair.Value, path))
+	}
+	return "{" + strings.Join(pairs, ", ") + "}"
+}
+
+// inspectElement quotes strings inside collections, so ["a, b"] and
+// ["a", "b"] can be told apart. path holds the collections and records the
+// element is inside of; one that contains itself is shown as [...], {...}
+// or Name{...} there.
+func inspectElement(obj Object, path map[Object]bool) string {
+	switch obj := obj.(type) {
+	case *String:
+		return strconv.Quote(obj.Value)
+	case *Array:
+		return obj.inspect(path)
+	case *Hash:
+		return obj.inspect(path)
+	case *Record:
+		return obj.inspect(path)
+	default:
+		return obj.Inspect()
+	}
+}
+
+func (m *Module) Inspect() string { return "module " + m.Name }
+
+func (rt *RecordType) Type() ObjectType { return STRUCT_OBJ }
+func (rt *RecordType) Inspect() string {
+	fields := []string{}
+	for _, f := range rt.Fields {
+		fields = append(fields, f.Value)
+	}
+	return "struct " + rt.Name + " { " + strings.Join(fields, ", ") + " }"
+}
+
+// FieldIndex returns the index of the field name or -1.
+func (rt *RecordType) FieldIndex(name string) int {
+	for i, f := range rt.Fields {
+		if f.Value == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *Record) Type() ObjectType { return RECORD_OBJ }
+func (r *Record) Inspect() string  { return r.inspect(map[Object]bool{}) }
+
+func (r *Record) inspect(path map[Object]bool) string {
+	if path[r] {
+		return r.Struct.Name + "{...}"
+	}
+	path[r] = true
+	defer delete(path, r)
+
+	fields := []string{}
+	for i, f := range r.Struct.Fields {
+		fields = append(fields, f.Value+": "+inspectElement(r.Values[i], path))
+	}
+	return r.Struct.Name + "{" + strings.Join(fields, ", ") + "}"
+}
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+
+func (bm *BoundMethod) Type() ObjectType { return METHOD_OBJ }
+func (bm *BoundMethod) Inspect() string  { return "method " + bm.Method.Name }
+
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 func (rv *ReturnValue) Type() ObjectType { return RETURN_OBJ }
 
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("ERROR: ")
+	if e.Line > 0 {
+		fmt.Fprintf(&out, "%d:%d: ", e.Line, e.Column)
+	}
+	if e.Kind != "" {
+		out.WriteString(e.Kind + ": ")
+	}
+	out.WriteString(e.Message)
+	for _, f := range e.Trace {
+		out.WriteString("\n\tin " + f.String())
+	}
+	return out.String()
+}
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
 
+// String omits the position of calls not made by a call expression, e.g.
+// of callbacks made by builtins.
+func (f Frame) String() string {
+	if f.Line == 0 {
+		return f.Function
+	}
+	return fmt.Sprintf("%s called at %d:%d", f.Function, f.Line, f.Column)
+}
+
+func (ev *ErrorValue) Type() ObjectType { return ERROR_VALUE_OBJ }
+func (ev *ErrorValue) Inspect() string  { return ev.Err.Kind + ": " + ev.Err.Message }
+
 func (n *Null) Inspect() string  { return "null" }
 func (n *Null) Type() ObjectType { return NULL_OBJ }
+
+func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
+func (f *Function) Inspect() string {
+	var out bytes.Buffer
+	out.WriteString("fn(")
+	out.WriteString(f.ParameterList())
+	out.WriteString(") {\n")
+	out.WriteString(f.Body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
+
+// Signature returns the name and parameters of the function, e.g.
+// range(from, to = 10, ...rest), for error messages.
+func (f *Function) Signature() string {
+	name := f.Name
+	if name == "" {
+		name = "fn"
+	}
+	return name + "(" + f.ParameterList() + ")"
+}
+
+// ParameterList returns the parameters of the function as written, with
+// their defaults.
+func (f *Function) ParameterList() string {
+	params := []string{}
+	for i, p := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+f.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
+	return strings.Join(params, ", ")
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string  { return "QUOTE(" + q.Node.String() + ")" }
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+	out.WriteString("macro(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
@@ -0,0 +1,127 @@
+package optimize
+
+import "interpreter/ast"
+
+// Value computes a constant expression, one built from integer, float and
+// boolean literals with prefix and infix operators, without evaluating it.
+// The value is an int64, a float64 or a bool. It reports false if exp is not
+// constant or if evaluating it would fail, e.g. on a division by zero or a
+// type mismatch.
+func Value(exp ast.Expression) (any, bool) {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return exp.Value, true
+	case *ast.FloatLiteral:
+		return exp.Value, true
+	case *ast.Boolean:
+		return exp.Value, true
+	case *ast.PrefixExpression:
+		right, ok := Value(exp.Right)
+		if !ok {
+			return nil, false
+		}
+		return prefixValue(exp.Operator, right)
+	case *ast.InfixExpression:
+		left, ok := Value(exp.Left)
+		if !ok {
+			return nil, false
+		}
+		right, ok := Value(exp.Right)
+		if !ok {
+			return nil, false
+		}
+		return infixValue(exp.Operator, left, right)
+	default:
+		return nil, false
+	}
+}
+
+func prefixValue(operator string, right any) (any, bool) {
+	switch operator {
+	case "!":
+		b, ok := right.(bool)
+		return ok && !b, true
+	case "-":
+		switch right := right.(type) {
+		case int64:
+			return -right, true
+		case float64:
+			return -right, true
+		}
+	}
+	return nil, false
+}
+
+func infixValue(operator string, left, right any) (any, bool) {
+	l, leftIsBool := left.(bool)
+	r, rightIsBool := right.(bool)
+	switch {
+	case !leftIsBool && !rightIsBool:
+		return numberValue(operator, left, right)
+	case operator == "==":
+		return leftIsBool && rightIsBool && l == r, true
+	case operator == "!=":
+		return !leftIsBool || !rightIsBool || l != r, true
+	default:
+		return nil, false
+	}
+}
+
+// numberValue applies operator to two numbers. Integers stay integers
+// unless the other operand is a float.
+func numberValue(operator string, left, right any) (any, bool) {
+	l, leftIsInt := left.(int64)
+	r, rightIsInt := right.(int64)
+	if leftIsInt && rightIsInt {
+		switch operator {
+		case "+":
+			return l + r, true
+		case "-":
+			return l - r, true
+		case "*":
+			return l * r, true
+		case "/":
+			if r == 0 {
+				return nil, false
+			}
+			return l / r, true
+		case "<":
+			return l < r, true
+		case ">":
+			return l > r, true
+		case "==":
+			return l == r, true
+		case "!=":
+			return l != r, true
+		}
+		return nil, false
+	}
+
+	lf, rf := toFloat(left), toFloat(right)
+	switch operator {
+	case "+":
+		return lf + rf, true
+	case "-":
+		return lf - rf, true
+	case "*":
+		return lf * rf, true
+	case "/":
+		return lf / rf, true
+	case "<":
+		return lf < rf, true
+	case ">":
+		return lf > rf, true
+	case "==":
+		return lf == rf, true
+	case "!=":
+		return lf != rf, true
+	}
+	return nil, false
+}
+
+func toFloat(n any) float64 {
+	if i, ok := n.(int64); ok {
+		return float64(i)
+	}
+	return n.(float64)
+}
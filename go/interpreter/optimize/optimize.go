@@ -0,0 +1,270 @@
+package optimize
+
+import (
+	"interpreter/ast"
+	"interpreter/token"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Optimize rewrites program in place before it is evaluated. It folds
+// constant prefix and infix expressions, prunes if expressions with a
+// constant condition and drops statements that follow a return.
+func Optimize(program *ast.Program) *ast.Program {
+	program.Statements = optimizeStatements(program.Statements)
+	return program
+}
+
+// optimizeStatements optimizes a statement list and removes everything that
+// can never run because of an earlier return.
+func optimizeStatements(statements []ast.Statement) []ast.Statement {
+	result := []ast.Statement{}
+	for i, stmt := range statements {
+		stmt = optimizeStatement(stmt)
+
+		isLast := i == len(statements)-1
+		if spliced, ok := pruneIfStatement(stmt, isLast); ok {
+			result = append(result, spliced...)
+		} else {
+			result = append(result, stmt)
+		}
+
+		if endsInReturn(result) {
+			break
+		}
+	}
+	return result
+}
+
+func optimizeStatement(stmt ast.Statement) ast.Statement {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		stmt.Value = optimizeExpression(stmt.Value)
+	case *ast.ReturnStatement:
+		stmt.ReturnValue = optimizeExpression(stmt.ReturnValue)
+	case *ast.ExpressionStatement:
+		stmt.Expression = optimizeExpression(stmt.Expression)
+	case *ast.BlockStatement:
+		optimizeBlock(stmt)
+	}
+	return stmt
+}
+
+func optimizeBlock(block *ast.BlockStatement) {
+	if block != nil {
+		block.Statements = optimizeStatements(block.Statements)
+	}
+}
+
+func optimizeExpression(exp ast.Expression) ast.Expression {
+	switch exp := exp.(type) {
+	case *ast.PrefixExpression:
+		exp.Right = optimizeExpression(exp.Right)
+		if isConstant(exp.Right) {
+			return fold(exp)
+		}
+	case *ast.InfixExpression:
+		exp.Left = optimizeExpression(exp.Left)
+		exp.Right = optimizeExpression(exp.Right)
+		if isConstant(exp.Left) && isConstant(exp.Right) {
+			return fold(exp)
+		}
+	case *ast.IfExpression:
+		exp.Condition = optimizeExpression(exp.Condition)
+		optimizeBlock(exp.Consequence)
+		optimizeBlock(exp.Alternative)
+		return pruneIfExpression(exp)
+	case *ast.FunctionLiteral:
+		for i, def := range exp.Defaults {
+			if def != nil {
+				exp.Defaults[i] = optimizeExpression(def)
+			}
+		}
+		optimizeBlock(exp.Body)
+	case *ast.MethodDeclaration:
+		optimizeExpression(exp.Function)
+	case *ast.NamedArgument:
+		exp.Value = optimizeExpression(exp.Value)
+	case *ast.AssignExpression:
+		exp.Target = optimizeExpression(exp.Target)
+		exp.Value = optimizeExpression(exp.Value)
+	case *ast.SelectorExpression:
+		exp.Left = optimizeExpression(exp.Left)
+	case *ast.ArrayLiteral:
+		for i, el := range exp.Elements {
+			exp.Elements[i] = optimizeExpression(el)
+		}
+	case *ast.InterpolatedString:
+		for i, part := range exp.Parts {
+			exp.Parts[i] = optimizeExpression(part)
+		}
+	case *ast.HashLiteral:
+		for i, key := range exp.Keys {
+			exp.Keys[i] = optimizeExpression(key)
+			exp.Values[i] = optimizeExpression(exp.Values[i])
+		}
+	case *ast.IndexExpression:
+		exp.Left = optimizeExpression(exp.Left)
+		exp.Index = optimizeExpression(exp.Index)
+	case *ast.ThrowExpression:
+		exp.Value = optimizeExpression(exp.Value)
+	case *ast.YieldExpression:
+		exp.Value = optimizeExpression(exp.Value)
+	case *ast.SpawnExpression:
+		exp.Value = optimizeExpression(exp.Value)
+	case *ast.SelectExpression:
+		for _, c := range exp.Cases {
+			if c.Op != nil {
+				for i, arg := range c.Op.Arguments {
+					c.Op.Arguments[i] = optimizeExpression(arg)
+				}
+			}
+			c.Body = optimizeExpression(c.Body)
+		}
+	case *ast.TryExpression:
+		optimizeBlock(exp.Body)
+		optimizeBlock(exp.Handler)
+	case *ast.MatchExpression:
+		exp.Value = optimizeExpression(exp.Value)
+		for _, arm := range exp.Arms {
+			if arm.Guard != nil {
+				arm.Guard = optimizeExpression(arm.Guard)
+			}
+			arm.Body = optimizeExpression(arm.Body)
+		}
+	case *ast.RangeExpression:
+		exp.Start = optimizeExpression(exp.Start)
+		exp.End = optimizeExpression(exp.End)
+	case *ast.ForExpression:
+		exp.Iterable = optimizeExpression(exp.Iterable)
+		optimizeBlock(exp.Body)
+	case *ast.PipeExpression:
+		exp.Left = optimizeExpression(exp.Left)
+		exp.Right = optimizeExpression(exp.Right)
+	case *ast.CallExpression:
+		exp.Function = optimizeExpression(exp.Function)
+		for i, arg := range exp.Arguments {
+			exp.Arguments[i] = optimizeExpression(arg)
+		}
+	}
+	return exp
+}
+
+// fold computes a constant expression and replaces it by a literal. If the
+// computation fails, e.g. on a division by zero, the expression is kept so
+// the error is still raised at runtime at its original position.
+func fold(exp ast.Expression) ast.Expression {
+	value, ok := Value(exp)
+	if !ok {
+		return exp
+	}
+	span := ast.NodeSpan(exp)
+	tok := token.Token{Line: span.StartLine, Column: span.StartColumn}
+
+	switch value := value.(type) {
+	case int64:
+		tok.Type, tok.Literal = token.INT, strconv.FormatInt(value, 10)
+		return &ast.IntegerLiteral{Token: tok, Value: value}
+	case float64:
+		if math.IsInf(value, 0) || math.IsNaN(value) {
+			return exp
+		}
+		tok.Type, tok.Literal = token.FLOAT, formatFloat(value)
+		return &ast.FloatLiteral{Token: tok, Value: value}
+	default:
+		tok.Type, tok.Literal = token.FALSE, "false"
+		if value.(bool) {
+			tok.Type, tok.Literal = token.TRUE, "true"
+		}
+		return &ast.Boolean{Token: tok, Value: value.(bool)}
+	}
+}
+
+// formatFloat keeps a decimal point so the literal still reads as a float.
+func formatFloat(f float64) string {
+	literal := strconv.FormatFloat(f, 'f', -1, 64)
+	if !strings.Contains(literal, ".") {
+		literal += ".0"
+	}
+	return literal
+}
+
+// pruneIfExpression drops the branch that can never run. A remaining branch
+// made of a single expression replaces the whole if expression.
+func pruneIfExpression(exp *ast.IfExpression) ast.Expression {
+	taken, ok := constantBranch(exp)
+	if !ok || taken == nil {
+		return exp
+	}
+	if len(taken.Statements) == 1 {
+		if stmt, ok := taken.Statements[0].(*ast.ExpressionStatement); ok {
+			return stmt.Expression
+		}
+	}
+	exp.Condition = &ast.Boolean{Token: constantTrue(exp.Condition), Value: true}
+	exp.Consequence = taken
+	exp.Alternative = nil
+	return exp
+}
+
+// pruneIfStatement replaces an if statement with a constant condition by the
+// statements of the branch that runs. Blocks share the scope of the enclosing
+// code, so this does not change any bindings. An empty branch in last position
+// is kept because it decides the value of the surrounding block.
+func pruneIfStatement(stmt ast.Statement, isLast bool) ([]ast.Statement, bool) {
+	es, ok := stmt.(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+	exp, ok := es.Expression.(*ast.IfExpression)
+	if !ok {
+		return nil, false
+	}
+	taken, ok := constantBranch(exp)
+	if !ok {
+		return nil, false
+	}
+	if taken == nil || len(taken.Statements) == 0 {
+		if isLast {
+			return nil, false
+		}
+		return []ast.Statement{}, true
+	}
+	return taken.Statements, true
+}
+
+// constantBranch returns the branch an if expression with a constant
+// condition always takes. The branch is nil for a false condition without
+// an else block.
+func constantBranch(exp *ast.IfExpression) (*ast.BlockStatement, bool) {
+	if !isConstant(exp.Condition) {
+		return nil, false
+	}
+	if b, ok := exp.Condition.(*ast.Boolean); ok && !b.Value {
+		return exp.Alternative, true
+	}
+	return exp.Consequence, true
+}
+
+func constantTrue(condition ast.Expression) token.Token {
+	line, column := ast.Pos(condition)
+	return token.Token{Type: token.TRUE, Literal: "true", Line: line, Column: column}
+}
+
+func isConstant(exp ast.Expression) bool {
+	switch exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.Boolean:
+		return true
+	default:
+		return false
+	}
+}
+
+func endsInReturn(statements []ast.Statement) bool {
+	if len(statements) == 0 {
+		return false
+	}
+	_, ok := statements[len(statements)-1].(*ast.ReturnStatement)
+	return ok
+}
@@ -0,0 +1,159 @@
+package optimize
+
+import (
+	"interpreter/ast"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"testing"
+)
+
+func TestConstantFolding(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"2 * 3 + x", "(6 + x)"},
+		{"x + 2 * 3", "(x + 6)"},
+		{"-(2 + 3)", "-5"},
+		{"1.5 * 2", "3.0"},
+		{"1 + 0.25", "1.25"},
+		{"1 < 2", "true"},
+		{"!(1 == 2)", "true"},
+		{"let a = 10 / 2 - 1;", "let a = 4;"},
+		{"fn(x) { x * (2 + 2) }", "fn(x) (x * 4)"},
+		{"f(1 + 1, x)", "f(2, x)"},
+		{"true + 1", "(true + 1)"},
+	}
+	for _, tt := range tests {
+		program := testOptimize(t, tt.input)
+		if program.String() != tt.expected {
+			t.Errorf("optimized %q wrong. expected=%q, got=%q",
+				tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestIfPruning(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"if (true) { 10 } else { 20 }", "10"},
+		{"if (1 > 2) { 10 } else { 20 }", "20"},
+		{"if (false) { 10 }; 5", "5"},
+		{"if (true) { let a = 1; a }", "let a = 1;a"},
+		{"let b = if (2 > 1) { x } else { y };", "let b = x;"},
+		{"let b = if (false) { x } else { let c = 1; c };", "let b = iftrue let c = 1;c;"},
+		{"if (x) { 10 } else { 20 }", "ifx 10else 20"},
+		{"if (false) { 10 }", "iffalse 10"},
+	}
+	for _, tt := range tests {
+		program := testOptimize(t, tt.input)
+		if program.String() != tt.expected {
+			t.Errorf("optimized %q wrong. expected=%q, got=%q",
+				tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestDeadCodeAfterReturn(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn() { return 1; 2; 3 }", "fn() return 1;"},
+		{"fn() { if (true) { return 1; } 2 }", "fn() return 1;"},
+		{"fn() { if (x) { return 1; 2 } 3 }", "fn() ifx return 1;3"},
+	}
+	for _, tt := range tests {
+		program := testOptimize(t, tt.input)
+		if program.String() != tt.expected {
+			t.Errorf("optimized %q wrong. expected=%q, got=%q",
+				tt.input, tt.expected, program.String())
+		}
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected any
+	}{
+		{"7 / 2", int64(3)},
+		{"-(1 + 2) * 2", int64(-6)},
+		{"1 + 0.5", 1.5},
+		{"1 / 4.0", 0.25},
+		{"3 > 2.5", true},
+		{"!1", false},
+		{"!false == true", true},
+		{"1 != false", true},
+		{"1 / 0", nil},
+		{"true + true", nil},
+		{"-false", nil},
+		{"x + 1", nil},
+	}
+	for _, tt := range tests {
+		stmt := parse(t, tt.input).Statements[0].(*ast.ExpressionStatement)
+		value, ok := Value(stmt.Expression)
+		if ok != (tt.expected != nil) || value != tt.expected {
+			t.Errorf("wrong value for %q. expected=%v, got=%v (%t)", tt.input, tt.expected, value, ok)
+		}
+	}
+}
+
+func TestFoldingKeepsRuntimeErrors(t *testing.T) {
+	input := "let a = 1;\nlet b = 2 * (4 / 0);"
+
+	program := testOptimize(t, input)
+	if program.String() != "let a = 1;let b = (2 * (4 / 0));" {
+		t.Fatalf("division by zero was folded. got=%q", program.String())
+	}
+
+	evaluated := eval.Eval(program, object.NewEnvironment())
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "division by zero" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+	if errObj.Line != 2 || errObj.Column != 16 {
+		t.Errorf("wrong error position. expected=2:16, got=%d:%d", errObj.Line, errObj.Column)
+	}
+}
+
+func TestOptimizedProgramEvaluatesSame(t *testing.T) {
+	tests := []string{
+		"let f = fn(x) { if (1 < 2) { return x * (3 - 1); } 0 }; f(21)",
+		"let a = 2 * 3; if (a > 5) { a + 0.5 } else { 0 }",
+		"if (false) { 1 } else { -(-7) }",
+		"[!5, !!true, 1 == true, 1 != true, true == !false, 7 / 2, 1 + 2.5 > 3, 2 == 2.0]",
+	}
+	for _, input := range tests {
+		expected := eval.Eval(parse(t, input), object.NewEnvironment()).Inspect()
+		got := eval.Eval(testOptimize(t, input), object.NewEnvironment()).Inspect()
+		if got != expected {
+			t.Errorf("optimized %q evaluates differently. expected=%s, got=%s",
+				input, expected, got)
+		}
+	}
+}
+
+// HELPER
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func testOptimize(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	return Optimize(parse(t, input))
+}
@@ -5,29 +5,42 @@ import (
 	"interpreter/ast"
 	"interpreter/lexer"
 	"interpreter/token"
+	"path"
 	"strconv"
+	"strings"
 )
 
 const (
 	LOWEST int = iota
+	ASSIGN
+	PIPE
 	EQUAL
 	LESSORGREATER
+	RANGE
 	SUM
 	PRODUCT
 	PREFIX
 	CALL
+	INDEX
+	MEMBER
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUAL,
-	token.NOT_EQ:   EQUAL,
-	token.LT:       LESSORGREATER,
-	token.GT:       LESSORGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
+	token.ASSIGN:    ASSIGN,
+	token.PIPE:      PIPE,
+	token.EQ:        EQUAL,
+	token.NOT_EQ:    EQUAL,
+	token.LT:        LESSORGREATER,
+	token.GT:        LESSORGREATER,
+	token.RANGE:     RANGE,
+	token.RANGE_INC: RANGE,
+	token.PLUS:      SUM,
+	token.MINUS:     SUM,
+	token.SLASH:     PRODUCT,
+	token.ASTERISK:  PRODUCT,
+	token.LPAREN:    CALL,
+	token.LBRACKET:  INDEX,
+	token.DOT:       MEMBER,
 }
 
 type (
@@ -42,6 +55,13 @@ type Parser struct {
 	curToken       token.Token
 	peekToken      token.Token
 	errors         []string
+	// inPattern is set while a pattern is parsed, the only place a rest
+	// pattern may appear.
+	inPattern bool
+	// inFunction is set while the body of a function is parsed, the only
+	// place a yield may appear. yielded records that it did.
+	inFunction bool
+	yielded    bool
 }
 
 // New creates a new Parser instance with the given lexer
@@ -54,6 +74,8 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
 	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
+	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE_START, p.parseInterpolatedString)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
@@ -61,6 +83,17 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
+	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefix(token.THROW, p.parseThrowExpression)
+	p.registerPrefix(token.YIELD, p.parseYieldExpression)
+	p.registerPrefix(token.SPAWN, p.parseSpawnExpression)
+	p.registerPrefix(token.SELECT, p.parseSelectExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.FOR, p.parseForExpression)
+	p.registerPrefix(token.ELLIPSIS, p.parseRestPattern)
 
 	// Register Infix Parse Functions
 	p.infixParseFns = make(map[token.TokenType]infixParseFn)
@@ -72,7 +105,13 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.PIPE, p.parsePipeExpression)
+	p.registerInfix(token.RANGE, p.parseRangeExpression)
+	p.registerInfix(token.RANGE_INC, p.parseRangeExpression)
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
+	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfix(token.DOT, p.parseSelectorExpression)
 
 	// Initialize current and peek token
 	p.nextToken()
@@ -116,6 +155,33 @@ func (p *Parser) parseFloatLiteral() ast.Expression {
 	return lit
 }
 
+// parseStringLiteral parses a string literal and returns its AST node.
+func (p *Parser) parseStringLiteral() ast.Expression {
+	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
+}
+
+// parseInterpolatedString parses a string with ${ } interpolations. Its
+// text becomes string literal parts, empty text is left out.
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+	for {
+		if p.curToken.Literal != "" {
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+		}
+		if p.curTokenIs(token.TEMPLATE_END) {
+			return str
+		}
+
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(LOWEST))
+		if p.peekTokenIs(token.TEMPLATE_MIDDLE) {
+			p.nextToken()
+		} else if !p.expectPeek(token.TEMPLATE_END) {
+			return nil
+		}
+	}
+}
+
 // parseBoolean parses a boolean literal and returns its AST node.
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
@@ -158,12 +224,395 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseThrowExpression parses a throw expression and returns its AST node.
+func (p *Parser) parseThrowExpression() ast.Expression {
+	exp := &ast.ThrowExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parseYieldExpression parses yield value and marks the enclosing function
+// as a generator.
+func (p *Parser) parseYieldExpression() ast.Expression {
+	exp := &ast.YieldExpression{Token: p.curToken}
+	if !p.inFunction {
+		p.errors = append(p.errors, "yield outside of a function body")
+		return nil
+	}
+	p.yielded = true
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parseSpawnExpression parses spawn call or spawn function.
+func (p *Parser) parseSpawnExpression() ast.Expression {
+	exp := &ast.SpawnExpression{Token: p.curToken}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parseSelectExpression parses select { case, ... }.
+func (p *Parser) parseSelectExpression() ast.Expression {
+	exp := &ast.SelectExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	fallback := false
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		c := p.parseSelectCase()
+		if c == nil {
+			return nil
+		}
+		if c.Op == nil {
+			if fallback {
+				p.errors = append(p.errors, "select has more than one default case")
+				return nil
+			}
+			fallback = true
+		}
+		exp.Cases = append(exp.Cases, c)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return exp
+}
+
+// parseSelectCase parses name = recv(channel) => body, send(channel, value)
+// => body or _ => body. The operations look like calls of the builtins of
+// the same name, but only these forms are allowed.
+func (p *Parser) parseSelectCase() *ast.SelectCase {
+	c := &ast.SelectCase{Token: p.curToken}
+	if !p.curTokenIs(token.IDENT) || p.curToken.Literal != ast.Wildcard || !p.peekTokenIs(token.FAT_ARROW) {
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.ASSIGN) {
+			c.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.nextToken()
+			p.nextToken()
+		}
+		op := p.parseExpression(LOWEST)
+		if op == nil {
+			return nil
+		}
+		call, ok := op.(*ast.CallExpression)
+		if !ok || !isSelectOperation(call, c.Name != nil) {
+			p.errors = append(p.errors, fmt.Sprintf("select case must be recv(channel) or send(channel, value), got %s", op))
+			return nil
+		}
+		c.Op = call
+	}
+	if !p.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+	p.nextToken()
+	c.Body = p.parseExpression(LOWEST)
+	return c
+}
+
+// isSelectOperation reports whether call is recv(channel), or, unless the
+// case binds a name, send(channel, value).
+func isSelectOperation(call *ast.CallExpression, named bool) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return false
+	}
+	for _, arg := range call.Arguments {
+		if _, ok := arg.(*ast.NamedArgument); ok {
+			return false
+		}
+	}
+	switch ident.Value {
+	case "recv":
+		return len(call.Arguments) == 1
+	case "send":
+		return len(call.Arguments) == 2 && !named
+	default:
+		return false
+	}
+}
+
+// parseTryExpression parses a try expression and returns its AST node.
+func (p *Parser) parseTryExpression() ast.Expression {
+	exp := &ast.TryExpression{Token: p.curToken}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Body = p.parseBlockStatement()
+	if !p.expectPeek(token.CATCH) {
+		return nil
+	}
+	if p.peekTokenIs(token.LPAREN) {
+		p.nextToken()
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		exp.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if !p.expectPeek(token.RPAREN) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Handler = p.parseBlockStatement()
+	return exp
+}
+
+// parseMatchExpression parses match (value) { pattern if guard => body, ... }.
+func (p *Parser) parseMatchExpression() ast.Expression {
+	exp := &ast.MatchExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		exp.Arms = append(exp.Arms, arm)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return exp
+}
+
+// parseForExpression parses for (pattern in iterable) { body }. The pattern
+// is one of a let.
+func (p *Parser) parseForExpression() ast.Expression {
+	exp := &ast.ForExpression{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Pattern = p.parsePattern(false)
+	if exp.Pattern == nil || !p.expectPeek(token.IN) {
+		return nil
+	}
+	p.nextToken()
+	exp.Iterable = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	exp.Body = p.parseBlockStatement()
+	return exp
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Token: p.curToken}
+	arm.Pattern = p.parsePattern(true)
+	if arm.Pattern == nil {
+		return nil
+	}
+	if p.peekTokenIs(token.IF) {
+		p.nextToken()
+		p.nextToken()
+		arm.Guard = p.parseExpression(LOWEST)
+	}
+	if !p.expectPeek(token.FAT_ARROW) {
+		return nil
+	}
+	p.nextToken()
+	arm.Body = p.parseExpression(LOWEST)
+	return arm
+}
+
+// parsePattern parses a pattern starting at the current token. Patterns are
+// parsed as expressions and then checked. Only the patterns of match arms may
+// contain literals, the patterns of lets have to bind every value.
+func (p *Parser) parsePattern(literals bool) ast.Expression {
+	p.inPattern = true
+	pattern := p.parseExpression(ASSIGN)
+	p.inPattern = false
+	if !p.checkPattern(pattern, literals) {
+		return nil
+	}
+	return pattern
+}
+
+// checkPattern reports an error if exp, parsed as an expression, is not a
+// valid pattern.
+func (p *Parser) checkPattern(exp ast.Expression, literals bool) bool {
+	switch exp := exp.(type) {
+	case nil:
+		return false
+	case *ast.Identifier:
+		return true
+	case *ast.ArrayLiteral:
+		for i, el := range exp.Elements {
+			if _, ok := el.(*ast.RestPattern); ok {
+				if i == len(exp.Elements)-1 {
+					continue
+				}
+				p.errors = append(p.errors, fmt.Sprintf("rest pattern must be last: %s", el))
+				return false
+			}
+			if !p.checkPattern(el, literals) {
+				return false
+			}
+		}
+		return true
+	case *ast.CallExpression:
+		if !isConstructor(exp.Function) {
+			p.errors = append(p.errors, fmt.Sprintf("invalid pattern: %s", exp))
+			return false
+		}
+		for _, arg := range exp.Arguments {
+			if named, ok := arg.(*ast.NamedArgument); ok {
+				arg = named.Value
+			}
+			if !p.checkPattern(arg, literals) {
+				return false
+			}
+		}
+		return true
+	case *ast.HashLiteral:
+		for i, key := range exp.Keys {
+			if !isLiteralPattern(key) {
+				p.errors = append(p.errors, fmt.Sprintf("invalid hash pattern key: %s", key))
+				return false
+			}
+			if !p.checkPattern(exp.Values[i], literals) {
+				return false
+			}
+		}
+		return true
+	default:
+		if literals && isLiteralPattern(exp) {
+			return true
+		}
+		p.errors = append(p.errors, fmt.Sprintf("invalid pattern: %s", exp))
+		return false
+	}
+}
+
+// parseRestPattern parses ...name inside an array pattern.
+func (p *Parser) parseRestPattern() ast.Expression {
+	if !p.inPattern {
+		p.errors = append(p.errors, "rest pattern outside of a pattern")
+		return nil
+	}
+	rest := &ast.RestPattern{Token: p.curToken}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	rest.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return rest
+}
+
+// isConstructor reports whether exp names a struct constructor in a
+// pattern: a name or a member of a module.
+func isConstructor(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return true
+	case *ast.SelectorExpression:
+		_, ok := exp.Left.(*ast.Identifier)
+		return ok
+	}
+	return false
+}
+
+// isLiteralPattern reports whether exp is a literal or a negative number.
+func isLiteralPattern(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		switch exp.Right.(type) {
+		case *ast.IntegerLiteral, *ast.FloatLiteral:
+			return exp.Operator == "-"
+		}
+	}
+	return false
+}
+
 // parseFunctionLiteral parses a function literal and returns its AST node.
 func (p *Parser) parseFunctionLiteral() ast.Expression {
+	inFunction, yielded := p.inFunction, p.yielded
+	defer func() { p.inFunction, p.yielded = inFunction, yielded }()
+	// defaults are evaluated by the caller, so they cannot yield
+	p.inFunction = false
+
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 	if !p.expectPeek(token.LPAREN) {
 		return nil
 	}
+	// fn (p Point) starts a method, a parameter is never followed by a name
+	var first *ast.Identifier
+	if p.peekTokenIs(token.IDENT) {
+		p.nextToken()
+		first = p.parseTypedIdentifier()
+		if first.Type == nil && p.peekTokenIs(token.IDENT) {
+			return p.parseMethodDeclaration(lit, first)
+		}
+	}
+	if !p.parseParameters(lit, first) {
+		return nil
+	}
+	return p.parseFunctionBody(lit)
+}
+
+// parseMethodDeclaration parses the rest of fn (receiver Struct) name(params)
+// { body } after the receiver.
+func (p *Parser) parseMethodDeclaration(lit *ast.FunctionLiteral, receiver *ast.Identifier) ast.Expression {
+	decl := &ast.MethodDeclaration{Token: lit.Token, Receiver: receiver}
+	p.nextToken()
+	decl.Struct = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.RPAREN) || !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	decl.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if !p.expectPeek(token.LPAREN) || !p.parseParameters(lit, nil) || p.parseFunctionBody(lit) == nil {
+		return nil
+	}
+	lit.Name = decl.Struct.Value + "." + decl.Name.Value
+	decl.Function = lit
+	return decl
+}
+
+// parseFunctionBody parses the optional return type and the body of a
+// function literal.
+func (p *Parser) parseFunctionBody(lit *ast.FunctionLiteral) ast.Expression {
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		lit.ReturnType = p.parseTypeAnnotation()
+		if lit.ReturnType == nil {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	p.inFunction, p.yielded = true, false
+	lit.Body = p.parseBlockStatement()
+	lit.Generator = p.yielded
+	return lit
+}
+
+// parseMacroLiteral parses a macro literal and returns its AST node.
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
 	lit.Parameters = p.parseFunctionParameters()
 	if !p.expectPeek(token.LBRACE) {
 		return nil
@@ -180,13 +629,11 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		return identifiers
 	}
 	p.nextToken()
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	identifiers = append(identifiers, p.parseTypedIdentifier())
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
+		identifiers = append(identifiers, p.parseTypedIdentifier())
 	}
 	if !p.expectPeek(token.RPAREN) {
 		return nil
@@ -194,6 +641,74 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 	return identifiers
 }
 
+// parseParameters parses the parameters of a function literal. Parameters
+// with a default value must follow those without, a rest parameter comes
+// last. first is the first parameter if the caller already parsed it.
+func (p *Parser) parseParameters(lit *ast.FunctionLiteral, first *ast.Identifier) bool {
+	lit.Parameters = []*ast.Identifier{}
+	defaults := []ast.Expression{}
+	hasDefaults := false
+	for first != nil || !p.peekTokenIs(token.RPAREN) {
+		param := first
+		first = nil
+		if param == nil {
+			if len(lit.Parameters) > 0 && !p.expectPeek(token.COMMA) {
+				return false
+			}
+			if p.peekTokenIs(token.ELLIPSIS) {
+				p.nextToken()
+				if !p.expectPeek(token.IDENT) {
+					return false
+				}
+				lit.Rest = p.parseTypedIdentifier()
+				if p.peekTokenIs(token.COMMA) {
+					p.errors = append(p.errors, fmt.Sprintf("rest parameter ...%s must be last", lit.Rest))
+					return false
+				}
+				break
+			}
+			if !p.expectPeek(token.IDENT) {
+				return false
+			}
+			param = p.parseTypedIdentifier()
+		}
+		var def ast.Expression
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(LOWEST)
+			hasDefaults = true
+		} else if hasDefaults {
+			p.errors = append(p.errors, fmt.Sprintf("parameter %s without default follows a parameter with default", param))
+			return false
+		}
+		lit.Parameters = append(lit.Parameters, param)
+		defaults = append(defaults, def)
+	}
+	if hasDefaults {
+		lit.Defaults = defaults
+	}
+	return p.expectPeek(token.RPAREN)
+}
+
+// parseTypedIdentifier parses the current identifier and its optional type annotation.
+func (p *Parser) parseTypedIdentifier() *ast.Identifier {
+	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		ident.Type = p.parseTypeAnnotation()
+	}
+	return ident
+}
+
+// parseTypeAnnotation parses the type name following a colon or arrow.
+func (p *Parser) parseTypeAnnotation() *ast.TypeAnnotation {
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	return &ast.TypeAnnotation{Token: p.curToken, Name: p.curToken.Literal}
+}
+
 // parsePrefixExpression parses a prefix expression and returns its AST node.
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
@@ -215,24 +730,115 @@ func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	return exp
 }
 
-// parseCallArguments parses call arguments and returns a slice of expressions.
+// parseCallArguments parses call arguments and returns a slice of
+// expressions. Named arguments come after the positional ones.
 func (p *Parser) parseCallArguments() []ast.Expression {
 	args := []ast.Expression{}
-	if p.peekTokenIs(token.RPAREN) {
+	named := map[string]bool{}
+	for !p.peekTokenIs(token.RPAREN) {
+		if len(args) > 0 && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+		p.nextToken()
+		if !p.curTokenIs(token.IDENT) || !p.peekTokenIs(token.COLON) {
+			arg := p.parseExpression(LOWEST)
+			if len(named) > 0 {
+				p.errors = append(p.errors, fmt.Sprintf("positional argument %s follows named arguments", arg))
+				return nil
+			}
+			args = append(args, arg)
+			continue
+		}
+
+		arg := &ast.NamedArgument{Token: p.curToken}
+		arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if named[arg.Name.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate named argument: %s", arg.Name))
+			return nil
+		}
+		named[arg.Name.Value] = true
+		p.nextToken()
 		p.nextToken()
-		return args
+		arg.Value = p.parseExpression(LOWEST)
+		args = append(args, arg)
 	}
 	p.nextToken()
-	args = append(args, p.parseExpression(LOWEST))
+	return args
+}
+
+// parseExpressionList parses comma separated expressions up to end.
+func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
+	list := []ast.Expression{}
+	if p.peekTokenIs(end) {
+		p.nextToken()
+		return list
+	}
+	p.nextToken()
+	list = append(list, p.parseExpression(LOWEST))
 	for p.peekTokenIs(token.COMMA) {
 		p.nextToken()
 		p.nextToken()
-		args = append(args, p.parseExpression(LOWEST))
+		list = append(list, p.parseExpression(LOWEST))
 	}
-	if !p.expectPeek(token.RPAREN) {
+	if !p.expectPeek(end) {
 		return nil
 	}
-	return args
+	return list
+}
+
+// parseArrayLiteral parses an array literal and returns its AST node.
+func (p *Parser) parseArrayLiteral() ast.Expression {
+	array := &ast.ArrayLiteral{Token: p.curToken}
+	array.Elements = p.parseExpressionList(token.RBRACKET)
+	if array.Elements == nil {
+		return nil
+	}
+	return array
+}
+
+// parseHashLiteral parses a hash literal and returns its AST node.
+func (p *Parser) parseHashLiteral() ast.Expression {
+	hash := &ast.HashLiteral{Token: p.curToken}
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+		key := p.parseExpression(LOWEST)
+		if !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		value := p.parseExpression(LOWEST)
+		hash.Keys = append(hash.Keys, key)
+		hash.Values = append(hash.Values, value)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	return hash
+}
+
+// parseIndexExpression parses an index expression and returns its AST node.
+func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
+	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	exp.Index = p.parseExpression(LOWEST)
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	return exp
+}
+
+// parseSelectorExpression parses a member access like lib.name.
+func (p *Parser) parseSelectorExpression(left ast.Expression) ast.Expression {
+	exp := &ast.SelectorExpression{Token: p.curToken, Left: left}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	return exp
 }
 
 // parseInfixExpression parses an infix expression and returns its AST node.
@@ -250,6 +856,44 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+// parseAssignExpression parses the value of target = value. Fields of
+// records and elements of arrays and hashes can be assigned. Assignments
+// are right associative, so a.x = b.x = 1 assigns 1 to both.
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	switch target.(type) {
+	case *ast.SelectorExpression, *ast.IndexExpression:
+	default:
+		p.errors = append(p.errors, fmt.Sprintf("invalid assignment target: %s", target))
+		return nil
+	}
+	exp := &ast.AssignExpression{Token: p.curToken, Target: target}
+	p.nextToken()
+	exp.Value = p.parseExpression(LOWEST)
+	return exp
+}
+
+// parsePipeExpression parses the stage after |>. Pipes are left
+// parseRangeExpression parses start..end and start..=end. Ranges do not
+// chain, a..b..c is an error.
+func (p *Parser) parseRangeExpression(start ast.Expression) ast.Expression {
+	exp := &ast.RangeExpression{Token: p.curToken, Start: start, Inclusive: p.curTokenIs(token.RANGE_INC)}
+	p.nextToken()
+	exp.End = p.parseExpression(RANGE)
+	if p.peekTokenIs(token.RANGE) || p.peekTokenIs(token.RANGE_INC) {
+		p.errors = append(p.errors, fmt.Sprintf("ranges do not chain: %s%s", exp, p.peekToken.Literal))
+		return nil
+	}
+	return exp
+}
+
+// associative, so a |> f |> g is g(f(a)).
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	exp := &ast.PipeExpression{Token: p.curToken, Left: left}
+	p.nextToken()
+	exp.Right = p.parseExpression(PIPE)
+	return exp
+}
+
 // parseBlockStatement parses a block statement and returns its AST node.
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
@@ -289,33 +933,142 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 // parseStatement parses a single statement based on the current token.
 func (p *Parser) parseStatement() ast.Statement {
 	switch p.curToken.Type {
-	case token.LET:
+	case token.LET, token.CONST:
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
+	case token.STRUCT:
+		return p.parseStructStatement()
+	case token.EXPORT:
+		if p.peekTokenIs(token.STRUCT) {
+			p.nextToken()
+			stmt := p.parseStructStatement()
+			if stmt == nil {
+				return nil
+			}
+			stmt.(*ast.StructStatement).Exported = true
+			return stmt
+		}
+		if p.peekTokenIs(token.CONST) {
+			p.nextToken()
+		} else if !p.expectPeek(token.LET) {
+			return nil
+		}
+		stmt := p.parseLetStatement()
+		if stmt == nil {
+			return nil
+		}
+		stmt.Exported = true
+		return stmt
 	default:
 		return p.parseExpressionStatement()
 	}
 }
 
-// parseLetStatement parses a let statement and returns its AST node.
+// parseLetStatement parses a let or const statement and returns its AST
+// node.
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		stmt.Pattern = p.parsePattern(false)
+		if stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		stmt.Name = p.parseTypedIdentifier()
+	}
+	if !p.expectPeek(token.ASSIGN) {
+		return nil
+	}
+	p.nextToken()
+	stmt.Value = p.parseExpression(LOWEST)
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fn.Name = stmt.Name.Value
+	}
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parseStructStatement parses struct Name { field, ... }. A trailing comma
+// after the last field is allowed.
+func (p *Parser) parseStructStatement() ast.Statement {
+	stmt := &ast.StructStatement{Token: p.curToken}
 	if !p.expectPeek(token.IDENT) {
 		return nil
 	}
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	if !p.expectPeek(token.ASSIGN) {
+	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
+
+	stmt.Fields = []*ast.Identifier{}
+	seen := map[string]bool{}
+	for !p.peekTokenIs(token.RBRACE) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		field := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[field.Value] {
+			p.errors = append(p.errors, fmt.Sprintf("duplicate field %s in struct %s", field, stmt.Name))
+			return nil
+		}
+		seen[field.Value] = true
+		stmt.Fields = append(stmt.Fields, field)
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
 	p.nextToken()
-	stmt.Value = p.parseExpression(LOWEST)
+
 	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 	return stmt
 }
 
+// parseImportStatement parses an import statement and returns its AST node.
+// The module is bound to the last element of the path without extension.
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = p.curToken.Literal
+
+	name := strings.TrimSuffix(path.Base(stmt.Path), path.Ext(stmt.Path))
+	if token.LookupIdent(name) != token.IDENT || !isIdentifier(name) {
+		msg := fmt.Sprintf("import path %q does not end in a valid name", stmt.Path)
+		p.errors = append(p.errors, msg)
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: name}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func isIdentifier(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
 // parseReturnStatement parses a return statement and returns its AST node.
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"interpreter/ast"
 	"interpreter/lexer"
+	"strings"
 	"testing"
 )
 
@@ -715,6 +716,171 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestTypeAnnotationParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x: int = 5;", "let x: int = 5;"},
+		{"let y = 5;", "let y = 5;"},
+		{"fn(a: int, b: float) -> float { a + b }", "fn(a: int, b: float) -> float (a + b)"},
+		{"fn(a, b: bool) { a }", "fn(a, b: bool) a"},
+		{"fn() -> int { 1 }", "fn() -> int 1"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	l := lexer.New("let x: = 5;")
+	p := New(l)
+	p.ParseProgram()
+	if len(p.Errors()) == 0 {
+		t.Errorf("missing type name was not reported")
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T", program.Statements[0])
+	}
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d", len(macro.Parameters))
+	}
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statement. got=%d", len(macro.Body.Statements))
+	}
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T", macro.Body.Statements[0])
+	}
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestModuleParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/math"`, `import "lib/math";`},
+		{`import "util.mk"; util.f(1)`, `import "util.mk";util.f(1)`},
+		{`export let x = 5;`, `export let x = 5;`},
+		{`a.b.c + 1`, `(a.b.c + 1)`},
+		{`-m.x`, `(-m.x)`},
+		{`"hello" + "world"`, `("hello" + "world")`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	stmt := parseSingleImport(t, `import "path/to/lib"`)
+	if stmt.Path != "path/to/lib" || stmt.Name.Value != "lib" {
+		t.Errorf("wrong import. path=%q, name=%q", stmt.Path, stmt.Name.Value)
+	}
+
+	for _, input := range []string{`import ""`, `import "my-lib"`, `import "if"`, `import lib`, `export 5`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("no parser error for %q", input)
+		}
+	}
+}
+
+func TestTryThrowParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw 1 + 2`, `throw (1 + 2)`},
+		{`try { f() } catch (e) { e }`, `try f() catch (e) e`},
+		{`try { f() } catch { 0 }`, `try f() catch 0`},
+		{`let x = try { throw "a" } catch (e) { 1 } + 1;`, `let x = (try throw "a" catch (e) 1 + 1);`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{`try { 1 }`, `try { 1 } catch (1) { 2 }`, `try 1 catch { 2 }`, `throw`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("no parser error for %q", input)
+		}
+	}
+}
+
+func TestFunctionLiteralName(t *testing.T) {
+	p := New(lexer.New(`let add = fn(a, b) { a + b };`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	let := program.Statements[0].(*ast.LetStatement)
+	fn, ok := let.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("let.Value is not ast.FunctionLiteral. got=%T", let.Value)
+	}
+	if fn.Name != "add" {
+		t.Errorf("function literal name wrong. want=%q, got=%q", "add", fn.Name)
+	}
+}
+
+func parseSingleImport(t *testing.T, input string) *ast.ImportStatement {
+	t.Helper()
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+	stmt, ok := program.Statements[0].(*ast.ImportStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ImportStatement. got=%T", program.Statements[0])
+	}
+	return stmt
+}
+
 func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	if s.TokenLiteral() != "let" {
 		t.Errorf("s.TokenLiteral not 'let'. got=%q", s.TokenLiteral())
@@ -860,3 +1026,533 @@ func checkParserErrors(t *testing.T, p *Parser) {
 	}
 	t.FailNow()
 }
+
+func TestCollectionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`[]`, `[]`},
+		{`[1, 2 * 2, "a"]`, `[1, (2 * 2), "a"]`},
+		{`{}`, `{}`},
+		{`{"one": 1, two: 1 + 1}`, `{"one": 1, two: (1 + 1)}`},
+		{`a[1 + 1]`, `(a[(1 + 1)])`},
+		{`a * [1, 2][b * c] * d`, `((a * ([1, 2][(b * c)])) * d)`},
+		{`add(a * b[2], b[1])`, `add((a * (b[2])), (b[1]))`},
+		{`f(x)[0]`, `(f(x)[0])`},
+		{`m.list[0]`, `(m.list[0])`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	for _, input := range []string{`[1, 2`, `{1 2}`, `a[1`, `a[]`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("no parser error for %q", input)
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		parts    int
+	}{
+		{`"Hallo ${name}, du hast ${n * 2} Punkte"`, `"Hallo ${name}, du hast ${(n * 2)} Punkte"`, 5},
+		{`"${x}"`, `"${x}"`, 1},
+		{`"${f("a")}${[1][0]}"`, `"${f("a")}${([1][0])}"`, 2},
+		{`"a\n${x}\${y}"`, `"a\n${x}\${y}"`, 3},
+		{`"${ "${x}" } done"`, `"${"${x}"} done"`, 2},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		str, ok := stmt.Expression.(*ast.InterpolatedString)
+		if !ok {
+			t.Errorf("exp not *ast.InterpolatedString. got=%T", stmt.Expression)
+			continue
+		}
+		if len(str.Parts) != tt.parts {
+			t.Errorf("%s: wrong number of parts. want=%d, got=%d", tt.input, tt.parts, len(str.Parts))
+		}
+	}
+
+	for _, input := range []string{`"${}"`, `"${x"`, `"${x y}"`, `"${x}`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("no parser error for %q", input)
+		}
+	}
+}
+
+func TestMatchParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { 1 => "one", _ => "many" }`, `match (x) { 1 => "one", _ => "many" }`},
+		{`match (p) { [a, b] if a > b => a, [a, b] => b, }`, `match (p) { [a, b] if (a > b) => a, [a, b] => b }`},
+		{`match (h) { {"x": x, "y": -1} => x }`, `match (h) { {"x": x, "y": (-1)} => x }`},
+		{`let r = match (n) { 0 => 1, n => n * 2 };`, `let r = match (n) { 0 => 1, n => (n * 2) };`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`match (x) { a + 1 => a }`, "invalid pattern: (a + 1)"},
+		{`match (x) { f(a)(b) => a }`, "invalid pattern: f(a)(b)"},
+		{`match (x) { {k: 1} => 1 }`, "invalid hash pattern key: k"},
+		{`match (x) { 1 -> 2 }`, "expected next token to be =>, got -> instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestDestructuringLetParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, b] = pair;`, `let [a, b] = pair;`},
+		{`let {"x": x, "y": y} = point;`, `let {"x": x, "y": y} = point;`},
+		{`let [head, ...tail] = xs;`, `let [head, ...tail] = xs;`},
+		{`let [[a, _], {"k": [b]}] = v;`, `let [[a, _], {"k": [b]}] = v;`},
+		{`export let [q, r] = divmod(7, 2);`, `export let [q, r] = divmod(7, 2);`},
+		{`match (xs) { [x, ...rest] => rest }`, `match (xs) { [x, ...rest] => rest }`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`let [a, 1] = xs;`, "invalid pattern: 1"},
+		{`let [...a, b] = xs;`, "rest pattern must be last: ...a"},
+		{`let [a + b] = xs;`, "invalid pattern: (a + b)"},
+		{`let x = [...xs];`, "rest pattern outside of a pattern"},
+		{`let [...1] = xs;`, "expected next token to be IDENT, got INT instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestParameterAndArgumentParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(x, step = 1) { x }`, `fn(x, step = 1) x`},
+		{`fn(first, ...others) { first }`, `fn(first, ...others) first`},
+		{`fn(a: int = 1 + 2, ...rest: int) { a }`, `fn(a: int = (1 + 2), ...rest: int) a`},
+		{`fn(...all) { all }`, `fn(...all) all`},
+		{`plot(f, from: -10, to: 10)`, `plot(f, from: (-10), to: 10)`},
+		{`f(a: {"k": 1}, b: g(c: 2))`, `f(a: {"k": 1}, b: g(c: 2))`},
+		{`x |> f(by: 2)`, `(x |> f(by: 2))`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New(`fn(a, b = 2, ...c) { a }`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	fn := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(fn.Parameters) != 2 || fn.Default(0) != nil || fn.Default(1).String() != "2" || fn.Rest.Value != "c" {
+		t.Errorf("wrong parameters. got=%s", fn)
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`fn(a = 1, b) { a }`, "parameter b without default follows a parameter with default"},
+		{`fn(...a, b) { a }`, "rest parameter ...a must be last"},
+		{`fn(...a = 1) { a }`, "expected next token to be ), got = instead"},
+		{`f(a: 1, 2)`, "positional argument 2 follows named arguments"},
+		{`f(a: 1, a: 2)`, "duplicate named argument: a"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestStructParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, y }`, `struct Point { x, y }`},
+		{`struct Empty {}`, `struct Empty {  }`},
+		{"export struct Line {\n  from,\n  to,\n}", `export struct Line { from, to }`},
+		{`p.x = 1`, `(p.x = 1)`},
+		{`a.x = b.y = 1 + 2`, `(a.x = (b.y = (1 + 2)))`},
+		{`p.x = p.x |> f`, `(p.x = (p.x |> f))`},
+		{`match (p) { Point(0, y: y) => y, geo.Point(x, _) => x }`, `match (p) { Point(0, y: y) => y, geo.Point(x, _) => x }`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`struct Point { x, x }`, "duplicate field x in struct Point"},
+		{`struct Point { x y }`, "expected next token to be ,, got IDENT instead"},
+		{`x = 1`, "invalid assignment target: x"},
+		{`f(a) = 1`, "invalid assignment target: f(a)"},
+		{`match (p) { f(1)(2) => 1 }`, "invalid pattern: f(1)(2)"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestMethodParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`fn (p Point) norm() { p.x }`, `fn (p Point) norm() p.x`},
+		{`fn (p Point) scale(k: int, d = 0) -> Point { p }`, `fn (p Point) scale(k: int, d = 0) -> Point p`},
+		{`fn (a V) __add__(b) { a }`, `fn (a V) __add__(b) a`},
+		{`fn (a) { a }`, `fn(a) a`},
+		{`fn (a, b) { a }`, `fn(a, b) a`},
+		{`fn (a: int) { a }`, `fn(a: int) a`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New(`fn (p Point) norm() { p }`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	decl, ok := stmt.Expression.(*ast.MethodDeclaration)
+	if !ok {
+		t.Fatalf("expression is not *ast.MethodDeclaration. got=%T", stmt.Expression)
+	}
+	if decl.Receiver.Value != "p" || decl.Struct.Value != "Point" || decl.Name.Value != "norm" {
+		t.Errorf("wrong method declaration: %s", decl)
+	}
+	if decl.Function.Name != "Point.norm" || len(decl.Function.Parameters) != 0 {
+		t.Errorf("wrong method function: name=%q, parameters=%d", decl.Function.Name, len(decl.Function.Parameters))
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`fn (p Point norm() { p }`, "expected next token to be ), got IDENT instead"},
+		{`fn (p Point) () { p }`, "expected next token to be IDENT, got ( instead"},
+		{`fn (p Point) norm { p }`, "expected next token to be (, got { instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestRangeAndForParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`0..10`, `(0..10)`},
+		{`a..=b`, `(a..=b)`},
+		{`n - 1..n + 1`, `((n - 1)..(n + 1))`},
+		{`0..n < m`, `((0..n) < m)`},
+		{`0..10 |> map(f)`, `((0..10) |> map(f))`},
+		{`for (x in xs) { x }`, `for (x in xs) x`},
+		{`for ([k, v] in h) { k }`, `for ([k, v] in h) k`},
+		{`for (x in 0..=3) { f(x); }`, `for (x in (0..=3)) f(x)`},
+		{`let total = for (x in xs) {};`, `let total = for (x in xs) ;`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`0..1..2`, "ranges do not chain: (0..1).."},
+		{`for (x of xs) { x }`, "expected next token to be IN, got IDENT instead"},
+		{`for (1 in xs) { x }`, "invalid pattern: 1"},
+		{`for (x in xs) x`, "expected next token to be {, got IDENT instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestPipeExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`xs |> f`, `(xs |> f)`},
+		{`xs |> map(f) |> filter(g)`, `((xs |> map(f)) |> filter(g))`},
+		{`a + 1 |> f(b * 2)`, `((a + 1) |> f((b * 2)))`},
+		{`a |> f == b |> g`, `((a |> (f == b)) |> g)`},
+		{`x |> m.f(1)[0]`, `(x |> (m.f(1)[0]))`},
+		{`let y = x |> fn(v) { v } |> g;`, `let y = ((x |> fn(v) v) |> g);`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	p := New(lexer.New(`x |> f(a)`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+	pipe, ok := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.PipeExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.PipeExpression. got=%T", program.Statements[0])
+	}
+	if call := pipe.Call(); call.String() != "f(x, a)" {
+		t.Errorf("wrong desugared call. want=%q, got=%q", "f(x, a)", call.String())
+	}
+}
+
+func TestYieldParsing(t *testing.T) {
+	tests := []struct {
+		input     string
+		expected  string
+		generator bool
+	}{
+		{`fn() { yield 1 }`, `fn() yield 1`, true},
+		{`fn(x) { let y = yield x + 1; y }`, `fn(x) let y = yield (x + 1);y`, true},
+		{`fn() { if (true) { yield 1 } }`, `fn() iftrue yield 1`, true},
+		{`fn() { fn() { yield 1 } }`, `fn() fn() yield 1`, false},
+		{`fn() { 1 }`, `fn() 1`, false},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		fn := stmt.Expression.(*ast.FunctionLiteral)
+		if fn.Generator != tt.generator {
+			t.Errorf("%q: Generator=%t, want %t", tt.input, fn.Generator, tt.generator)
+		}
+	}
+
+	for _, input := range []string{`yield 1`, `let f = fn(x = yield 1) { x };`, `if (true) { yield 1 }`} {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 || !strings.Contains(p.Errors()[0], "yield outside of a function body") {
+			t.Errorf("%q: expected yield error, got=%v", input, p.Errors())
+		}
+	}
+}
+
+func TestSpawnAndSelectParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`spawn f(1, 2)`, `spawn f(1, 2)`},
+		{`spawn fn() { x }`, `spawn fn() x`},
+		{`let t = spawn worker(c);`, `let t = spawn worker(c);`},
+		{`select { x = recv(a) => x, send(b, 1) => 2 }`, `select { x = recv(a) => x, send(b, 1) => 2 }`},
+		{`select { recv(after(10)) => "timeout", _ => 0 }`, `select { recv(after(10)) => "timeout", _ => 0 }`},
+		{`select { x = recv(a) => x + 1 } * 2`, `(select { x = recv(a) => (x + 1) } * 2)`},
+		{`select {}`, `select {  }`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	errors := []struct {
+		input    string
+		expected string
+	}{
+		{`select { f(a) => 1 }`, "select case must be recv(channel) or send(channel, value), got f(a)"},
+		{`select { x = send(a, 1) => x }`, "select case must be recv(channel) or send(channel, value), got send(a, 1)"},
+		{`select { recv(a, b) => 1 }`, "select case must be recv(channel) or send(channel, value), got recv(a, b)"},
+		{`select { _ => 1, _ => 2 }`, "select has more than one default case"},
+		{`select { recv(a) => 1 recv(b) => 2 }`, "expected next token to be ,, got IDENT instead"},
+	}
+
+	for _, tt := range errors {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		if len(p.Errors()) == 0 {
+			t.Errorf("%q: expected a parser error", tt.input)
+			continue
+		}
+		if !strings.Contains(p.Errors()[0], tt.expected) {
+			t.Errorf("%q: wrong error. expected=%q, got=%q", tt.input, tt.expected, p.Errors()[0])
+		}
+	}
+}
+
+func TestConstAndIndexAssignmentParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`const x = 1;`, `const x = 1;`},
+		{`const [a, ...b] = xs;`, `const [a, ...b] = xs;`},
+		{`export const limit = 10;`, `export const limit = 10;`},
+		{`xs[0] = 1`, `((xs[0]) = 1)`},
+		{`h["a"][i + 1] = h.b = 2`, `(((h["a"])[(i + 1)]) = (h.b = 2))`},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+
+	program := New(lexer.New(`const x = 1; let y = 2;`)).ParseProgram()
+	for i, expected := range []bool{true, false} {
+		if got := program.Statements[i].(*ast.LetStatement).Const(); got != expected {
+			t.Errorf("statement %d: Const() = %t, want %t", i, got, expected)
+		}
+	}
+}
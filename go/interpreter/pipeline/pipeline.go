@@ -0,0 +1,43 @@
+// Package pipeline runs the passes between parsing and evaluation. The
+// program given on the command line, the modules it imports and the sources
+// evaluated through interp all go through the same passes.
+package pipeline
+
+import (
+	"context"
+	"interpreter/ast"
+	"interpreter/diag"
+	"interpreter/eval"
+	"interpreter/macro"
+	"interpreter/object"
+	"interpreter/optimize"
+	"interpreter/resolve"
+	"interpreter/types"
+)
+
+// Prepare expands the macros of program with c, keeping their definitions
+// in macros, resolves its names with globals bound before it starts and
+// checks its types. Unless these report errors, it optimizes the program.
+// It returns the program to evaluate and the sorted diagnostics.
+func Prepare(ctx context.Context, c *eval.Context, program *ast.Program, macros *object.Environment, globals []string) (*ast.Program, []diag.Diagnostic) {
+	diagnostics := macro.Expand(ctx, c, program, macros)
+	if !diag.HasErrors(diagnostics) {
+		diagnostics = append(diagnostics, resolve.Resolve(program, globals)...)
+		diagnostics = append(diagnostics, types.Check(program)...)
+	}
+	diag.Sort(diagnostics)
+	if diag.HasErrors(diagnostics) {
+		return program, diagnostics
+	}
+	return optimize.Optimize(program), diagnostics
+}
+
+// NewLoader returns a loader like eval.NewLoader that prepares every module
+// it loads. Each module has macros of its own and sees the builtins only.
+func NewLoader(file string, searchPath []string) *eval.Loader {
+	l := eval.NewLoader(file, searchPath)
+	l.Prepare = func(ctx context.Context, c *eval.Context, program *ast.Program) (*ast.Program, []diag.Diagnostic) {
+		return Prepare(ctx, c, program, object.NewEnvironment(), eval.BuiltinNames())
+	}
+	return l
+}
@@ -0,0 +1,99 @@
+package pipeline
+
+import (
+	"context"
+	"interpreter/ast"
+	"interpreter/eval"
+	"interpreter/lexer"
+	"interpreter/object"
+	"interpreter/parser"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestPrepare(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+		errors   []string
+	}{
+		{`let unless = macro(c, a, b) { quote(if (!(unquote(c))) { unquote(a) } else { unquote(b) }) }; unless(1 > 2, "a", "b")`, "a", nil},
+		{"let x = 1; x + true", "", []string{"1:14: error: type mismatch: int + bool"}},
+		{"y + 1", "", []string{"1:1: error: undefined identifier: y"}},
+	}
+
+	for _, tt := range tests {
+		program, diagnostics := Prepare(context.Background(), eval.NewContext(), parse(t, tt.input), object.NewEnvironment(), eval.BuiltinNames())
+		if len(diagnostics) != len(tt.errors) {
+			t.Errorf("wrong diagnostics for %q. expected=%v, got=%v", tt.input, tt.errors, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.errors[i] {
+				t.Errorf("wrong diagnostic for %q. expected=%q, got=%q", tt.input, tt.errors[i], d.String())
+			}
+		}
+		if tt.errors != nil {
+			continue
+		}
+		evaluated := eval.Eval(program, object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+func TestLoaderPreparesModules(t *testing.T) {
+	dir := t.TempDir()
+	writeModule(t, dir, "control.mk", `
+		let unless = macro(cond, cons, alt) {
+			quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) })
+		};
+		export let sign = fn(n) { unless(n < 0, "+", "-") };
+	`)
+	writeModule(t, dir, "broken.mk", `export let f = fn() { 1 + true };`)
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "control"; [control.sign(2), control.sign(-2)]`, `["+", "-"]`},
+		{
+			`import "broken"`,
+			"ERROR: 1:1: ImportError: cannot check module broken: 1:25: error: type mismatch: int + bool",
+		},
+	}
+
+	for _, tt := range tests {
+		c := eval.NewContext()
+		c.Loader = NewLoader(filepath.Join(dir, "main.mk"), nil)
+		evaluated := c.Run(context.Background(), parse(t, tt.input), object.NewEnvironment())
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong result for %q. expected=%q, got=%q", tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
+// HELPER
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func writeModule(t *testing.T, dir, name, source string) {
+	t.Helper()
+	path := filepath.Join(dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(source), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,10 +2,14 @@ package repl
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"interpreter/diag"
 	"interpreter/eval"
 	"interpreter/lexer"
+	"interpreter/object"
 	"interpreter/parser"
+	"interpreter/pipeline"
 	"io"
 )
 
@@ -13,6 +17,11 @@ const PROMPT = ">>  "
 
 func Start(in io.Reader, out io.Writer) {
 	scanner := bufio.NewScanner(in)
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
+	c := eval.NewContext()
+	c.Loader = pipeline.NewLoader("", nil)
+	c.Output = out
 
 	for {
 		fmt.Fprint(out, PROMPT)
@@ -31,7 +40,16 @@ func Start(in io.Reader, out io.Writer) {
 			continue
 		}
 
-		evaluated := eval.Eval(program)
+		globals := append(eval.BuiltinNames(), env.Names()...)
+		program, diagnostics := pipeline.Prepare(context.Background(), c, program, macroEnv, globals)
+		for _, d := range diagnostics {
+			io.WriteString(out, "\t"+d.String()+"\n")
+		}
+		if diag.HasErrors(diagnostics) {
+			continue
+		}
+
+		evaluated := c.Run(context.Background(), program, env)
 		if evaluated != nil {
 			io.WriteString(out, evaluated.Inspect())
 			io.WriteString(out, "\n")
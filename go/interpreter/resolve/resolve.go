@@ -0,0 +1,449 @@
+package resolve
+
+import (
+	"interpreter/ast"
+	"interpreter/diag"
+)
+
+// binding is one slot of a scope. decl is nil for predeclared globals.
+type binding struct {
+	index int
+	decl  *ast.Identifier
+}
+
+// scope holds the bindings of the program, of one function call, of one
+// match arm, select case or catch handler or of the body of a for-in loop.
+// Other blocks do not open a scope of their own, they share the one they
+// are written in.
+type scope struct {
+	outer    *scope
+	slots    map[string]*binding
+	declared map[string]bool
+	// consts holds the names bound by a const, which cannot be bound again.
+	consts map[string]bool
+	// arm is set for the scope of a match arm, a select case, a catch
+	// handler or a loop body, which, unlike a function body, runs right
+	// where it is written.
+	arm bool
+}
+
+func newScope(outer *scope) *scope {
+	return &scope{
+		outer:    outer,
+		slots:    make(map[string]*binding),
+		declared: make(map[string]bool),
+		consts:   make(map[string]bool),
+	}
+}
+
+func (s *scope) add(ident *ast.Identifier) *binding {
+	if b, ok := s.slots[ident.Value]; ok {
+		return b
+	}
+	b := &binding{index: len(s.slots), decl: ident}
+	s.slots[ident.Value] = b
+	return b
+}
+
+type resolver struct {
+	scope       *scope
+	diagnostics []diag.Diagnostic
+}
+
+// Resolve checks the scopes of program before it runs and annotates every
+// identifier with the depth and slot of its binding. globals are names that
+// are bound before the program starts, e.g. by an earlier REPL line.
+//
+// Inside the current scope a name must be bound by a let before it is used.
+// Function bodies only run when they are called, so from there every
+// binding of an enclosing scope counts, which allows recursion.
+func Resolve(program *ast.Program, globals []string) []diag.Diagnostic {
+	r := &resolver{scope: newScope(nil)}
+	for _, name := range globals {
+		r.scope.slots[name] = &binding{index: len(r.scope.slots)}
+		r.scope.declared[name] = true
+	}
+
+	r.hoist(program)
+	r.resolveStatements(program.Statements)
+
+	diag.Sort(r.diagnostics)
+	return r.diagnostics
+}
+
+// hoist reserves the slots for all lets of the current scope up front, so
+// later uses from nested functions can be resolved.
+func (r *resolver) hoist(node ast.Node) {
+	ast.Walk(node, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral, *ast.MatchArm:
+			return false
+		case *ast.ForExpression:
+			r.hoist(n.Iterable)
+			return false
+		case *ast.SelectCase:
+			if n.Op != nil {
+				r.hoist(n.Op)
+			}
+			return false
+		case *ast.LetStatement:
+			for _, name := range n.Names() {
+				r.scope.add(name)
+			}
+		case *ast.ImportStatement:
+			r.scope.add(n.Name)
+		case *ast.StructStatement:
+			r.scope.add(n.Name)
+		case *ast.TryExpression:
+			r.hoist(n.Body)
+			return false
+		}
+		return true
+	})
+}
+
+func (r *resolver) resolveStatements(statements []ast.Statement) {
+	for _, stmt := range statements {
+		r.resolve(stmt)
+	}
+}
+
+func (r *resolver) resolve(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.BlockStatement:
+		r.resolveStatements(node.Statements)
+	case *ast.LetStatement:
+		if node.Exported && r.scope.outer != nil {
+			r.report(node, diag.Error, "export inside a function")
+		}
+		r.resolveExpression(node.Value)
+		r.resolveConstructors(node.Pattern)
+		r.declareAll(node.Names())
+		if node.Const() {
+			for _, name := range node.Names() {
+				r.scope.consts[name.Value] = true
+			}
+		}
+	case *ast.StructStatement:
+		if node.Exported && r.scope.outer != nil {
+			r.report(node, diag.Error, "export inside a function")
+		}
+		r.declare(node.Name)
+	case *ast.AssignExpression:
+		r.resolveExpression(node.Target)
+		r.resolveExpression(node.Value)
+	case *ast.ImportStatement:
+		r.declare(node.Name)
+	case *ast.ReturnStatement:
+		r.resolveExpression(node.ReturnValue)
+	case *ast.ExpressionStatement:
+		r.resolveExpression(node.Expression)
+	case *ast.Identifier:
+		r.resolveIdentifier(node)
+	case *ast.PrefixExpression:
+		r.resolveExpression(node.Right)
+	case *ast.InfixExpression:
+		r.resolveExpression(node.Left)
+		r.resolveExpression(node.Right)
+	case *ast.IfExpression:
+		r.resolveExpression(node.Condition)
+		r.resolve(node.Consequence)
+		if node.Alternative != nil {
+			r.resolve(node.Alternative)
+		}
+	case *ast.SelectorExpression:
+		r.resolveExpression(node.Left)
+	case *ast.ArrayLiteral:
+		for _, el := range node.Elements {
+			r.resolveExpression(el)
+		}
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			r.resolveExpression(part)
+		}
+	case *ast.HashLiteral:
+		for i, key := range node.Keys {
+			r.resolveExpression(key)
+			r.resolveExpression(node.Values[i])
+		}
+	case *ast.IndexExpression:
+		r.resolveExpression(node.Left)
+		r.resolveExpression(node.Index)
+	case *ast.ThrowExpression:
+		r.resolveExpression(node.Value)
+	case *ast.YieldExpression:
+		r.resolveExpression(node.Value)
+	case *ast.SpawnExpression:
+		r.resolveExpression(node.Value)
+	case *ast.SelectExpression:
+		for _, c := range node.Cases {
+			r.resolveSelectCase(c)
+		}
+	case *ast.TryExpression:
+		r.resolve(node.Body)
+		r.resolveHandler(node)
+	case *ast.FunctionLiteral:
+		r.resolveFunction(node, nil)
+	case *ast.MethodDeclaration:
+		r.resolveIdentifier(node.Struct)
+		r.resolveFunction(node.Function, node.Receiver)
+	case *ast.MatchExpression:
+		r.resolveExpression(node.Value)
+		for _, arm := range node.Arms {
+			r.resolveArm(arm)
+		}
+	case *ast.RangeExpression:
+		r.resolveExpression(node.Start)
+		r.resolveExpression(node.End)
+	case *ast.ForExpression:
+		r.resolveExpression(node.Iterable)
+		r.resolveLoop(node)
+	case *ast.NamedArgument:
+		r.resolveExpression(node.Value)
+	case *ast.PipeExpression:
+		r.resolveExpression(node.Left)
+		r.resolveExpression(node.Right)
+	case *ast.CallExpression:
+		if isCallTo(node, "quote") {
+			r.resolveUnquotes(node)
+			return
+		}
+		r.resolveExpression(node.Function)
+		for _, arg := range node.Arguments {
+			r.resolveExpression(arg)
+		}
+	}
+}
+
+func (r *resolver) resolveExpression(exp ast.Expression) {
+	if exp != nil {
+		r.resolve(exp)
+	}
+}
+
+// resolveUnquotes skips the quoted code, which is data, and only resolves
+// the arguments of unquote calls, which are evaluated in the current scope.
+func (r *resolver) resolveUnquotes(quote *ast.CallExpression) {
+	for _, arg := range quote.Arguments {
+		ast.Walk(arg, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpression)
+			if !ok || !isCallTo(call, "unquote") {
+				return true
+			}
+			for _, a := range call.Arguments {
+				r.resolveExpression(a)
+			}
+			return false
+		})
+	}
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// resolveFunction resolves a function literal. The receiver of a method,
+// if any, is bound before the parameters.
+func (r *resolver) resolveFunction(fn *ast.FunctionLiteral, receiver *ast.Identifier) {
+	r.scope = newScope(r.scope)
+	defer func() { r.scope = r.scope.outer }()
+
+	// Defaults run before the body, so they may only use the parameters
+	// before them.
+	params := fn.Parameters
+	if fn.Rest != nil {
+		params = append(params[:len(params):len(params)], fn.Rest)
+	}
+	if receiver != nil {
+		r.scope.add(receiver)
+	}
+	for _, param := range params {
+		r.scope.add(param)
+	}
+	r.hoist(fn.Body)
+	if receiver != nil {
+		r.declare(receiver)
+	}
+	for i, param := range params {
+		if r.scope.declared[param.Value] {
+			r.report(param, diag.Error, "duplicate parameter: %s", param.Value)
+			continue
+		}
+		r.resolveExpression(fn.Default(i))
+		r.declare(param)
+	}
+
+	r.resolve(fn.Body)
+}
+
+// resolveArm resolves a match arm in a scope of its own, which holds the
+// names bound by the pattern.
+func (r *resolver) resolveArm(arm *ast.MatchArm) {
+	r.scope = newScope(r.scope)
+	r.scope.arm = true
+	defer func() { r.scope = r.scope.outer }()
+
+	r.resolveConstructors(arm.Pattern)
+	r.declareAll(ast.PatternNames(arm.Pattern))
+	r.hoist(arm.Guard)
+	r.hoist(arm.Body)
+	r.resolveExpression(arm.Guard)
+	r.resolveExpression(arm.Body)
+}
+
+// resolveLoop resolves the body of a for-in loop in a scope of its own,
+// which holds the names bound by the pattern.
+func (r *resolver) resolveLoop(loop *ast.ForExpression) {
+	r.scope = newScope(r.scope)
+	r.scope.arm = true
+	defer func() { r.scope = r.scope.outer }()
+
+	r.resolveConstructors(loop.Pattern)
+	r.declareAll(ast.PatternNames(loop.Pattern))
+	r.hoist(loop.Body)
+	r.resolve(loop.Body)
+}
+
+// resolveSelectCase resolves the channel operation of a select case in the
+// current scope and its body in a scope of its own, which holds the name
+// bound to the received value.
+func (r *resolver) resolveSelectCase(c *ast.SelectCase) {
+	if c.Op != nil {
+		for _, arg := range c.Op.Arguments {
+			r.resolveExpression(arg)
+		}
+	}
+
+	r.scope = newScope(r.scope)
+	r.scope.arm = true
+	defer func() { r.scope = r.scope.outer }()
+
+	if c.Name != nil && c.Name.Value != ast.Wildcard {
+		r.declare(c.Name)
+	}
+	r.hoist(c.Body)
+	r.resolveExpression(c.Body)
+}
+
+// resolveHandler resolves the handler of a catch in a scope of its own,
+// which holds the parameter bound to the error.
+func (r *resolver) resolveHandler(exp *ast.TryExpression) {
+	r.scope = newScope(r.scope)
+	r.scope.arm = true
+	defer func() { r.scope = r.scope.outer }()
+
+	if exp.Param != nil {
+		r.declare(exp.Param)
+	}
+	r.hoist(exp.Handler)
+	r.resolve(exp.Handler)
+}
+
+// resolveConstructors resolves the struct constructors a pattern refers to.
+func (r *resolver) resolveConstructors(pattern ast.Expression) {
+	if pattern == nil {
+		return
+	}
+	ast.Walk(pattern, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpression); ok {
+			r.resolveExpression(call.Function)
+		}
+		return true
+	})
+}
+
+// declareAll declares the names bound by one let or pattern, each of which
+// may only appear once.
+func (r *resolver) declareAll(names []*ast.Identifier) {
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name.Value] {
+			r.report(name, diag.Error, "duplicate binding in pattern: %s", name.Value)
+			continue
+		}
+		seen[name.Value] = true
+		r.declare(name)
+	}
+}
+
+// declare marks a let name or parameter as bound from here on.
+func (r *resolver) declare(ident *ast.Identifier) {
+	if ident == nil {
+		return
+	}
+	if r.scope.consts[ident.Value] {
+		r.report(ident, diag.Error, "cannot reassign constant %s", ident.Value)
+	}
+	if !r.scope.declared[ident.Value] {
+		r.checkShadowing(ident)
+	}
+
+	b := r.scope.add(ident)
+	r.scope.declared[ident.Value] = true
+	annotate(ident, 0, b)
+}
+
+func (r *resolver) checkShadowing(ident *ast.Identifier) {
+	for s := r.scope.outer; s != nil; s = s.outer {
+		b, ok := s.slots[ident.Value]
+		if !ok {
+			continue
+		}
+		if b.decl == nil {
+			r.report(ident, diag.Warning, "%s shadows a global", ident.Value)
+		} else {
+			r.report(ident, diag.Warning, "%s shadows the binding from %d:%d",
+				ident.Value, b.decl.Token.Line, b.decl.Token.Column)
+		}
+		return
+	}
+}
+
+func (r *resolver) resolveIdentifier(ident *ast.Identifier) {
+	// The current scope and the function or program scope around the
+	// match arms it is nested in run right away, so there the name must be
+	// bound already.
+	depth := 0
+	s := r.scope
+	for {
+		if s.declared[ident.Value] {
+			annotate(ident, depth, s.slots[ident.Value])
+			return
+		}
+		if !s.arm {
+			break
+		}
+		s = s.outer
+		depth++
+	}
+
+	for s = s.outer; s != nil; s = s.outer {
+		depth++
+		if b, ok := s.slots[ident.Value]; ok {
+			annotate(ident, depth, b)
+			return
+		}
+	}
+
+	for s = r.scope; s != nil; s = s.outer {
+		if _, ok := s.slots[ident.Value]; ok {
+			r.report(ident, diag.Error, "%s used before its let", ident.Value)
+			return
+		}
+		if !s.arm {
+			break
+		}
+	}
+	r.report(ident, diag.Error, "undefined identifier: %s", ident.Value)
+}
+
+func annotate(ident *ast.Identifier, depth int, b *binding) {
+	ident.Resolved = true
+	ident.Depth = depth
+	ident.Index = b.index
+}
+
+func (r *resolver) report(node ast.Node, severity diag.Severity, format string, a ...any) {
+	r.diagnostics = append(r.diagnostics, diag.At(node, severity, format, a...))
+}
@@ -0,0 +1,179 @@
+package resolve
+
+import (
+	"fmt"
+	"interpreter/ast"
+	"interpreter/diag"
+	"interpreter/lexer"
+	"interpreter/parser"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = 1; a + 1;", nil},
+		{"let f = fn(n) { if (n < 1) { 0 } else { f(n - 1) } }; f(3);", nil},
+		{"let a = fn() { b() }; let b = fn() { 1 };", nil},
+		{"let a = 1; if (a) { let b = 2; } b;", nil},
+		{"x + 1", []string{"1:1: error: undefined identifier: x"}},
+		{"fn(a) { a + c }", []string{"1:13: error: undefined identifier: c"}},
+		{"y; let y = 1;", []string{"1:1: error: y used before its let"}},
+		{"let z = z + 1;", []string{"1:9: error: z used before its let"}},
+		{"fn(a, b, a) { a }", []string{"1:10: error: duplicate parameter: a"}},
+		{"fn(a, ...a) { a }", []string{"1:10: error: duplicate parameter: a"}},
+		{"let n = 1; fn(a, b = a + n, ...c) { [a, b, c] }", nil},
+		{"fn(a = b, b = 1) { a }", []string{"1:8: error: b used before its let"}},
+		{"fn(a = x) { let x = 1; x }", []string{"1:8: error: x used before its let"}},
+		{"let f = fn(a) { a }; f(a: y)", []string{"1:27: error: undefined identifier: y"}},
+		{"let p = Point(1, 2); struct Point { x, y }", []string{"1:9: error: Point used before its let"}},
+		{"struct Point { x, y }; let p = Point(1, 2); p.x = p.y", nil},
+		{"match (1) { Point(x) => x }", []string{"1:13: error: undefined identifier: Point"}},
+		{"let f = fn() { export struct P {} };", []string{"1:23: error: export inside a function"}},
+		{"struct P { x }; fn (p P) f(d = p.x) { p.x + d }", nil},
+		{"fn (p Q) f() { p }", []string{"1:7: error: undefined identifier: Q"}},
+		{"let xs = [1]; let n = 0; for (x in xs) { let y = x + n; y }", nil},
+		{"for (x in [1]) { let y = x; }; y", []string{"1:32: error: undefined identifier: y"}},
+		{"for ([a, a] in []) { a }", []string{"1:10: error: duplicate binding in pattern: a"}},
+		{"for (x in x..3) { x }", []string{"1:11: error: undefined identifier: x"}},
+		{"struct P { x }; fn (p P) f(p) { p }", []string{"1:28: error: duplicate parameter: p"}},
+		{
+			"let x = 1;\nlet f = fn(x) { let y = 2; fn() { let y = x; y } };",
+			[]string{
+				"2:12: warning: x shadows the binding from 1:5",
+				"2:39: warning: y shadows the binding from 2:21",
+			},
+		},
+		{"puts(1)", []string{"1:1: error: undefined identifier: puts"}},
+		{"quote(a + b)", nil},
+		{`import "lib"; lib.f(lib.x)`, nil},
+		{"try { 1 } catch (e) { e.message }", nil},
+		{"let f = fn() { err }; try { 1 } catch (err) { f() }", []string{"1:16: error: undefined identifier: err"}},
+		{"try { 1 } catch (e) { 2 }; e", []string{"1:28: error: undefined identifier: e"}},
+		{"throw y", []string{"1:7: error: undefined identifier: y"}},
+		{"let g = fn() { yield y }", []string{"1:22: error: undefined identifier: y"}},
+		{"spawn f(x)", []string{"1:7: error: undefined identifier: f", "1:9: error: undefined identifier: x"}},
+		{"let f = fn(c) { select { x = recv(c) => x, send(c, 1) => 2, _ => 3 } }", nil},
+		{"let f = fn(c) { select { x = recv(c) => x }; x }", []string{"1:46: error: undefined identifier: x"}},
+		{"let f = fn(c) { select { recv(d) => 1 } }", []string{"1:31: error: undefined identifier: d"}},
+		{"let x = 1; let f = fn(c) { select { x = recv(c) => x } }", []string{"1:37: warning: x shadows the binding from 1:5"}},
+		{"m.x", []string{"1:1: error: undefined identifier: m"}},
+		{"let f = fn() { export let x = 1; };", []string{"1:23: error: export inside a function"}},
+		{"quote(a + unquote(b))", []string{"1:19: error: undefined identifier: b"}},
+		{"match (1) { [a, b] if a > b => a, {\"k\": v} => v, _ => 0 }", nil},
+		{"match (1) { x => x }; x", []string{"1:23: error: undefined identifier: x"}},
+		{"match (1) { [a, a] => a }", []string{"1:17: error: duplicate binding in pattern: a"}},
+		{"match (1) { _ => y }; let y = 1;", []string{"1:18: error: y used before its let"}},
+		{"let f = fn() { match (1) { _ => g() } }; let g = fn() { 1 };", nil},
+		{"match (1) { x => match (x) { y => x + y } }", nil},
+		{"let [a, {\"b\": [b, ...c]}] = [1, {\"b\": [2]}]; [a, b, c]", nil},
+		{"let [a, a] = [1, 2];", []string{"1:9: error: duplicate binding in pattern: a"}},
+		{"let [a, b] = [b, 1];", []string{"1:15: error: b used before its let"}},
+		{"let f = fn() { let [x] = [1]; x }; x", []string{"1:36: error: undefined identifier: x"}},
+		{"const a = 1; let a = 2;", []string{"1:18: error: cannot reassign constant a"}},
+		{"const [a, b] = [1, 2]; struct b {}", []string{"1:31: error: cannot reassign constant b"}},
+		{"const a = 1; let f = fn(a) { let a = 2; a }; let g = fn() { const a = 3; a }", []string{
+			"1:25: warning: a shadows the binding from 1:7",
+			"1:67: warning: a shadows the binding from 1:7",
+		}},
+		{"let a = [1]; const b = a; a[0] = b[0]", nil},
+		{"xs[0] = 1", []string{"1:1: error: undefined identifier: xs"}},
+	}
+
+	for _, tt := range tests {
+		diagnostics := Resolve(parse(t, tt.input), nil)
+		checkDiagnostics(t, tt.input, diagnostics, tt.expected)
+	}
+}
+
+func TestGlobals(t *testing.T) {
+	input := "let print = fn(x) { puts(x) }; print(answer)"
+
+	diagnostics := Resolve(parse(t, input), []string{"puts", "answer"})
+	checkDiagnostics(t, input, diagnostics, nil)
+}
+
+func TestAnnotations(t *testing.T) {
+	input := `let a = 1;
+let f = fn(x, y) {
+  let z = x;
+  fn() { a + y + z + f }
+};`
+
+	program := parse(t, input)
+	if diagnostics := Resolve(program, nil); len(diagnostics) != 0 {
+		t.Fatalf("unexpected diagnostics: %v", diagnostics)
+	}
+
+	annotations := map[string][2]int{}
+	ast.Walk(program, func(n ast.Node) bool {
+		ident, ok := n.(*ast.Identifier)
+		if !ok {
+			return true
+		}
+		if !ident.Resolved {
+			t.Errorf("identifier %s at %d:%d not resolved",
+				ident.Value, ident.Token.Line, ident.Token.Column)
+		}
+		key := fmt.Sprintf("%s@%d", ident.Value, ident.Token.Line)
+		annotations[key] = [2]int{ident.Depth, ident.Index}
+		return true
+	})
+
+	tests := []struct {
+		key   string
+		depth int
+		index int
+	}{
+		{"a@1", 0, 0},
+		{"f@2", 0, 1},
+		{"x@2", 0, 0},
+		{"y@2", 0, 1},
+		{"z@3", 0, 2},
+		{"x@3", 0, 0},
+		{"a@4", 2, 0},
+		{"y@4", 1, 1},
+		{"z@4", 1, 2},
+		{"f@4", 2, 1},
+	}
+	for _, tt := range tests {
+		got, ok := annotations[tt.key]
+		if !ok {
+			t.Errorf("identifier %s not found", tt.key)
+			continue
+		}
+		if got != [2]int{tt.depth, tt.index} {
+			t.Errorf("identifier %s annotated wrong. expected depth=%d index=%d, got depth=%d index=%d",
+				tt.key, tt.depth, tt.index, got[0], got[1])
+		}
+	}
+}
+
+// HELPER
+
+func parse(t *testing.T, input string) *ast.Program {
+	t.Helper()
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors for %q: %v", input, p.Errors())
+	}
+	return program
+}
+
+func checkDiagnostics(t *testing.T, input string, diagnostics []diag.Diagnostic, expected []string) {
+	t.Helper()
+	if len(diagnostics) != len(expected) {
+		t.Errorf("wrong number of diagnostics for %q. expected=%v, got=%v",
+			input, expected, diagnostics)
+		return
+	}
+	for i, d := range diagnostics {
+		if d.String() != expected[i] {
+			t.Errorf("wrong diagnostic for %q. expected=%q, got=%q",
+				input, expected[i], d.String())
+		}
+	}
+}
@@ -6,19 +6,37 @@ import (
 
 type TokenType string
 
+// Token is a single lexeme of the source. Line and Column are 1-based and
+// point at the first character of the literal.
 type Token struct {
 	Type    TokenType
 	Literal string
+	Line    int
+	Column  int
 }
 
 var keywords = map[string]TokenType{
 	"fn":     FUNCTION,
 	"let":    LET,
+	"const":  CONST,
 	"true":   TRUE,
 	"false":  FALSE,
 	"if":     IF,
 	"else":   ELSE,
 	"return": RETURN,
+	"macro":  MACRO,
+	"import": IMPORT,
+	"export": EXPORT,
+	"throw":  THROW,
+	"try":    TRY,
+	"catch":  CATCH,
+	"match":  MATCH,
+	"struct": STRUCT,
+	"for":    FOR,
+	"in":     IN,
+	"yield":  YIELD,
+	"spawn":  SPAWN,
+	"select": SELECT,
 }
 
 func LookupIdent(ident string) TokenType {
@@ -40,10 +58,19 @@ func LookupNumberType(ident string) TokenType {
 const (
 	ILLEGAL = "ILLEGAL"
 	EOF     = "EOF"
+	COMMENT = "COMMENT"
 
-	IDENT = "IDENT"
-	INT   = "INT"
-	FLOAT = "FLOAT"
+	IDENT  = "IDENT"
+	INT    = "INT"
+	FLOAT  = "FLOAT"
+	STRING = "STRING"
+
+	// A string with interpolations like "a ${x} b ${y} c" is lexed as
+	// TEMPLATE_START "a ", the tokens of x, TEMPLATE_MIDDLE " b ", the
+	// tokens of y and TEMPLATE_END " c".
+	TEMPLATE_START  = "TEMPLATE_START"
+	TEMPLATE_MIDDLE = "TEMPLATE_MIDDLE"
+	TEMPLATE_END    = "TEMPLATE_END"
 
 	ASSIGN   = "="
 	PLUS     = "+"
@@ -60,17 +87,41 @@ const (
 
 	COMMA     = ","
 	SEMICOLON = ";"
+	COLON     = ":"
+	ARROW     = "->"
+	FAT_ARROW = "=>"
+	PIPE      = "|>"
+	DOT       = "."
+	ELLIPSIS  = "..."
+	RANGE     = ".."
+	RANGE_INC = "..="
 
-	LPAREN = "("
-	RPAREN = ")"
-	LBRACE = "{"
-	RBRACE = "}"
+	LPAREN   = "("
+	RPAREN   = ")"
+	LBRACE   = "{"
+	RBRACE   = "}"
+	LBRACKET = "["
+	RBRACKET = "]"
 
 	FUNCTION = "FUNCTION"
 	LET      = "LET"
+	CONST    = "CONST"
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	IF       = "IF"
 	ELSE     = "ELSE"
 	RETURN   = "RETURN"
+	MACRO    = "MACRO"
+	IMPORT   = "IMPORT"
+	EXPORT   = "EXPORT"
+	THROW    = "THROW"
+	TRY      = "TRY"
+	CATCH    = "CATCH"
+	MATCH    = "MATCH"
+	STRUCT   = "STRUCT"
+	FOR      = "FOR"
+	IN       = "IN"
+	YIELD    = "YIELD"
+	SPAWN    = "SPAWN"
+	SELECT   = "SELECT"
 )
@@ -0,0 +1,521 @@
+package types
+
+import (
+	"fmt"
+	"interpreter/ast"
+	"interpreter/diag"
+)
+
+// scope holds the types of the names bound in the program or in a function.
+// A name that is bound more than once may change its type at runtime, so
+// references to it are Dynamic.
+type scope struct {
+	outer      *scope
+	vars       map[string]Type
+	rebound    map[string]bool
+	returnType Type
+}
+
+func newScope(outer *scope, statements []ast.Statement, params []*ast.Identifier) *scope {
+	s := &scope{outer: outer, vars: map[string]Type{}, rebound: map[string]bool{}}
+
+	counts := map[string]int{}
+	for _, p := range params {
+		counts[p.Value]++
+	}
+	var count func(ast.Node) bool
+	count = func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.FunctionLiteral, *ast.MatchArm:
+			return false
+		case *ast.ForExpression:
+			ast.Walk(n.Iterable, count)
+			return false
+		case *ast.SelectCase:
+			if n.Op != nil {
+				ast.Walk(n.Op, count)
+			}
+			return false
+		case *ast.LetStatement:
+			for _, name := range n.Names() {
+				counts[name.Value]++
+			}
+		case *ast.ImportStatement:
+			counts[n.Name.Value]++
+		case *ast.StructStatement:
+			counts[n.Name.Value]++
+		case *ast.TryExpression:
+			ast.Walk(n.Body, count)
+			return false
+		}
+		return true
+	}
+	for _, stmt := range statements {
+		ast.Walk(stmt, count)
+	}
+	for name, count := range counts {
+		s.rebound[name] = count > 1
+	}
+	return s
+}
+
+func (s *scope) bind(name string, t Type) {
+	if s.rebound[name] {
+		t = Dynamic
+	}
+	s.vars[name] = t
+}
+
+func (s *scope) lookup(name string) Type {
+	for ; s != nil; s = s.outer {
+		if t, ok := s.vars[name]; ok {
+			return t
+		}
+	}
+	return Dynamic
+}
+
+type checker struct {
+	scope       *scope
+	diagnostics []diag.Diagnostic
+}
+
+func (c *checker) report(node ast.Node, format string, a ...any) {
+	c.diagnostics = append(c.diagnostics, diag.At(node, diag.Error, format, a...))
+}
+
+// annotation turns a type annotation into a type. Missing annotations are Dynamic.
+func (c *checker) annotation(ta *ast.TypeAnnotation) Type {
+	if ta == nil {
+		return Dynamic
+	}
+	t, ok := named[ta.Name]
+	if !ok {
+		c.report(ta, "unknown type: %s", ta.Name)
+		return Dynamic
+	}
+	return t
+}
+
+// checkStatements checks a statement list and returns the type of its value.
+func (c *checker) checkStatements(statements []ast.Statement) Type {
+	var result Type = Null
+	for _, stmt := range statements {
+		result = c.checkStatement(stmt)
+	}
+	return result
+}
+
+func (c *checker) checkStatement(stmt ast.Statement) Type {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		c.checkLet(stmt)
+		return Null
+	case *ast.ReturnStatement:
+		c.checkReturn(stmt)
+		return never
+	case *ast.ImportStatement:
+		c.scope.bind(stmt.Name.Value, Dynamic)
+		return Null
+	case *ast.StructStatement:
+		c.scope.bind(stmt.Name.Value, Dynamic)
+		return Null
+	case *ast.ExpressionStatement:
+		return c.checkExpression(stmt.Expression)
+	case *ast.BlockStatement:
+		return c.checkStatements(stmt.Statements)
+	default:
+		return Dynamic
+	}
+}
+
+func (c *checker) checkLet(let *ast.LetStatement) {
+	value := c.checkExpression(let.Value)
+	if let.Pattern != nil {
+		for _, name := range let.Names() {
+			c.scope.bind(name.Value, Dynamic)
+		}
+		return
+	}
+	if let.Name == nil {
+		return
+	}
+
+	if let.Name.Type == nil {
+		c.scope.bind(let.Name.Value, value)
+		return
+	}
+	declared := c.annotation(let.Name.Type)
+	if !Compatible(value, declared) {
+		c.report(let.Value, "cannot assign %s to %s of type %s", value, let.Name.Value, declared)
+	}
+	c.scope.bind(let.Name.Value, declared)
+}
+
+func (c *checker) checkReturn(ret *ast.ReturnStatement) {
+	value := c.checkExpression(ret.ReturnValue)
+	if c.scope.returnType == nil || Compatible(value, c.scope.returnType) {
+		return
+	}
+	c.report(ret.ReturnValue, "cannot return %s from function returning %s", value, c.scope.returnType)
+}
+
+func (c *checker) checkExpression(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return Int
+	case *ast.FloatLiteral:
+		return Float
+	case *ast.Boolean:
+		return Bool
+	case *ast.StringLiteral:
+		return String
+	case *ast.Identifier:
+		return c.scope.lookup(exp.Value)
+	case *ast.PrefixExpression:
+		return c.checkPrefix(exp)
+	case *ast.InfixExpression:
+		return c.checkInfix(exp)
+	case *ast.IfExpression:
+		c.checkExpression(exp.Condition)
+		consequence := c.checkStatement(exp.Consequence)
+		var alternative Type = Null
+		if exp.Alternative != nil {
+			alternative = c.checkStatement(exp.Alternative)
+		}
+		return join(consequence, alternative)
+	case *ast.FunctionLiteral:
+		return c.checkFunction(exp, nil)
+	case *ast.MethodDeclaration:
+		c.checkExpression(exp.Struct)
+		c.checkFunction(exp.Function, exp.Receiver)
+		return Null
+	case *ast.PipeExpression:
+		return c.checkCall(exp.Call())
+	case *ast.MatchExpression:
+		return c.checkMatch(exp)
+	case *ast.RangeExpression:
+		for _, bound := range []ast.Expression{exp.Start, exp.End} {
+			if t := c.checkExpression(bound); t != Int && t != Dynamic {
+				c.report(bound, "range bounds must be int, got %s", t)
+			}
+		}
+		return Dynamic
+	case *ast.ForExpression:
+		return c.checkFor(exp)
+	case *ast.CallExpression:
+		return c.checkCall(exp)
+	case *ast.SelectorExpression:
+		c.checkExpression(exp.Left)
+		return Dynamic
+	case *ast.AssignExpression:
+		c.checkExpression(exp.Target)
+		return c.checkExpression(exp.Value)
+	case *ast.ArrayLiteral:
+		for _, el := range exp.Elements {
+			c.checkExpression(el)
+		}
+		return Dynamic
+	case *ast.InterpolatedString:
+		for _, part := range exp.Parts {
+			c.checkExpression(part)
+		}
+		return String
+	case *ast.HashLiteral:
+		for i, key := range exp.Keys {
+			c.checkExpression(key)
+			c.checkExpression(exp.Values[i])
+		}
+		return Dynamic
+	case *ast.IndexExpression:
+		c.checkExpression(exp.Left)
+		c.checkExpression(exp.Index)
+		return Dynamic
+	case *ast.ThrowExpression:
+		c.checkExpression(exp.Value)
+		return never
+	case *ast.YieldExpression:
+		c.checkExpression(exp.Value)
+		return Null
+	case *ast.SpawnExpression:
+		c.checkExpression(exp.Value)
+		return Dynamic
+	case *ast.SelectExpression:
+		return c.checkSelect(exp)
+	case *ast.TryExpression:
+		body := c.checkStatement(exp.Body)
+		var names []*ast.Identifier
+		if exp.Param != nil {
+			names = append(names, exp.Param)
+		}
+		s := newScope(c.scope, exp.Handler.Statements, names)
+		s.returnType = c.scope.returnType
+		for _, name := range names {
+			s.bind(name.Value, Dynamic)
+		}
+
+		c.scope = s
+		handler := c.checkStatement(exp.Handler)
+		c.scope = s.outer
+		return join(body, handler)
+	default:
+		return Dynamic
+	}
+}
+
+func (c *checker) checkPrefix(exp *ast.PrefixExpression) Type {
+	right := c.checkExpression(exp.Right)
+	switch {
+	case exp.Operator == "!":
+		return Bool
+	case exp.Operator == "-" && (isNumber(right) || right == Dynamic):
+		return right
+	default:
+		c.report(exp, "unknown operator: %s%s", exp.Operator, right)
+		return Dynamic
+	}
+}
+
+func (c *checker) checkInfix(exp *ast.InfixExpression) Type {
+	left := c.checkExpression(exp.Left)
+	right := c.checkExpression(exp.Right)
+
+	switch exp.Operator {
+	case "==", "!=":
+		return Bool
+	}
+
+	var result Type
+	switch exp.Operator {
+	case "<", ">":
+		result = Bool
+	case "+", "-", "*", "/":
+		result = Int
+		if left == Float || right == Float {
+			result = Float
+		}
+	}
+
+	switch {
+	case left == Dynamic || right == Dynamic:
+		if result == Bool {
+			return Bool
+		}
+		return Dynamic
+	case isNumber(left) && isNumber(right) && result != nil:
+		return result
+	case left == String && right == String && exp.Operator == "+":
+		return String
+	case left.String() != right.String():
+		c.report(exp, "type mismatch: %s %s %s", left, exp.Operator, right)
+	default:
+		c.report(exp, "unknown operator: %s %s %s", left, exp.Operator, right)
+	}
+	return Dynamic
+}
+
+// checkFunction checks a function literal and returns its type. The
+// receiver of a method, if any, is bound as Dynamic and is not part of the
+// type.
+func (c *checker) checkFunction(fn *ast.FunctionLiteral, receiver *ast.Identifier) Type {
+	t := &Function{Return: c.annotation(fn.ReturnType)}
+	for i, p := range fn.Parameters {
+		t.Params = append(t.Params, c.annotation(p.Type))
+		t.Names = append(t.Names, p.Value)
+		if fn.Default(i) != nil {
+			t.Optional++
+		}
+	}
+	params := fn.Parameters
+	if fn.Rest != nil {
+		t.Rest = c.annotation(fn.Rest.Type)
+		t.Names = append(t.Names, fn.Rest.Value)
+		params = append(params[:len(params):len(params)], fn.Rest)
+	}
+	if receiver != nil {
+		params = append([]*ast.Identifier{receiver}, params...)
+	}
+
+	c.scope = newScope(c.scope, fn.Body.Statements, params)
+	defer func() { c.scope = c.scope.outer }()
+
+	if receiver != nil {
+		c.scope.bind(receiver.Value, Dynamic)
+	}
+	for i, p := range fn.Parameters {
+		if def := fn.Default(i); def != nil {
+			if value := c.checkExpression(def); !Compatible(value, t.Params[i]) {
+				c.report(def, "cannot use %s as default of %s of type %s", value, p.Value, t.Params[i])
+			}
+		}
+		c.scope.bind(p.Value, t.Params[i])
+	}
+	if fn.Rest != nil {
+		c.scope.bind(fn.Rest.Value, Dynamic)
+	}
+	if fn.Generator {
+		// A generator returns an iterator; its body only yields the values.
+		if fn.ReturnType != nil {
+			c.report(fn.ReturnType, "generator %s cannot declare a return type", fn.Name)
+		}
+		c.checkStatements(fn.Body.Statements)
+		t.Return = Dynamic
+		return t
+	}
+	if fn.ReturnType != nil {
+		c.scope.returnType = t.Return
+	}
+
+	result := c.checkStatements(fn.Body.Statements)
+	if fn.ReturnType != nil && !Compatible(result, t.Return) {
+		c.report(fn, "function returns %s, want %s", result, t.Return)
+	}
+	return t
+}
+
+// checkMatch checks the arms of a match, each in a scope that binds the
+// names of its pattern. The result is the join of the arm types.
+func (c *checker) checkMatch(match *ast.MatchExpression) Type {
+	c.checkExpression(match.Value)
+	var result Type
+	for _, arm := range match.Arms {
+		names := ast.PatternNames(arm.Pattern)
+		statements := []ast.Statement{&ast.ExpressionStatement{Expression: arm.Body}}
+		s := newScope(c.scope, statements, names)
+		s.returnType = c.scope.returnType
+		for _, name := range names {
+			s.bind(name.Value, Dynamic)
+		}
+
+		c.scope = s
+		if arm.Guard != nil {
+			c.checkExpression(arm.Guard)
+		}
+		body := c.checkExpression(arm.Body)
+		c.scope = s.outer
+
+		if result == nil {
+			result = body
+		} else {
+			result = join(result, body)
+		}
+	}
+	if result == nil {
+		return Dynamic
+	}
+	return result
+}
+
+// checkSelect checks the operations of a select and its cases, each in a
+// scope that binds the received name.
+func (c *checker) checkSelect(sel *ast.SelectExpression) Type {
+	var result Type
+	for _, sc := range sel.Cases {
+		if sc.Op != nil {
+			for _, arg := range sc.Op.Arguments {
+				c.checkExpression(arg)
+			}
+		}
+		var names []*ast.Identifier
+		if sc.Name != nil && sc.Name.Value != ast.Wildcard {
+			names = append(names, sc.Name)
+		}
+		statements := []ast.Statement{&ast.ExpressionStatement{Expression: sc.Body}}
+		s := newScope(c.scope, statements, names)
+		s.returnType = c.scope.returnType
+		for _, name := range names {
+			s.bind(name.Value, Dynamic)
+		}
+
+		c.scope = s
+		body := c.checkExpression(sc.Body)
+		c.scope = s.outer
+
+		if result == nil {
+			result = body
+		} else {
+			result = join(result, body)
+		}
+	}
+	if result == nil {
+		return Dynamic
+	}
+	return result
+}
+
+// checkFor checks the body of a for-in loop in a scope that binds the names
+// of its pattern. A loop evaluates to null.
+func (c *checker) checkFor(loop *ast.ForExpression) Type {
+	c.checkExpression(loop.Iterable)
+	names := ast.PatternNames(loop.Pattern)
+	s := newScope(c.scope, loop.Body.Statements, names)
+	s.returnType = c.scope.returnType
+	for _, name := range names {
+		s.bind(name.Value, Dynamic)
+	}
+
+	c.scope = s
+	c.checkStatements(loop.Body.Statements)
+	c.scope = s.outer
+	return Null
+}
+
+func (c *checker) checkCall(call *ast.CallExpression) Type {
+	// quoted code is data and is not checked
+	if ident, ok := call.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+		return Dynamic
+	}
+
+	callee := c.checkExpression(call.Function)
+	args := []Type{}
+	named := false
+	for _, arg := range call.Arguments {
+		// named arguments are not matched to their parameters here
+		if arg, ok := arg.(*ast.NamedArgument); ok {
+			c.checkExpression(arg.Value)
+			named = true
+			continue
+		}
+		args = append(args, c.checkExpression(arg))
+	}
+
+	fn, ok := callee.(*Function)
+	if !ok {
+		if callee != Dynamic {
+			c.report(call, "not a function: %s", callee)
+		}
+		return Dynamic
+	}
+
+	required := len(fn.Params) - fn.Optional
+	if len(args) > len(fn.Params) && fn.Rest == nil || len(args) < required && !named {
+		c.report(call, "wrong number of arguments for %s: want%s, got=%d",
+			fn.Signature(call.Function.String()), arity(fn), len(args))
+		return fn.Return
+	}
+	for i, arg := range args {
+		param := fn.Rest
+		if i < len(fn.Params) {
+			param = fn.Params[i]
+		}
+		if !Compatible(arg, param) {
+			c.report(call.Arguments[i], "argument %d of %s: cannot use %s as %s",
+				i+1, call.Function.String(), arg, param)
+		}
+	}
+	return fn.Return
+}
+
+// arity describes the number of arguments fn takes, e.g. "=2" or
+// " at least 1".
+func arity(fn *Function) string {
+	required := len(fn.Params) - fn.Optional
+	switch {
+	case fn.Rest != nil:
+		return fmt.Sprintf(" at least %d", required)
+	case fn.Optional > 0:
+		return fmt.Sprintf(" %d to %d", required, len(fn.Params))
+	default:
+		return fmt.Sprintf("=%d", required)
+	}
+}
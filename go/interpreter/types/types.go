@@ -0,0 +1,155 @@
+package types
+
+import (
+	"interpreter/ast"
+	"interpreter/diag"
+	"strings"
+)
+
+// Type is a static type. Code without annotations has the type Dynamic,
+// which is compatible with every other type and is never checked.
+type Type interface {
+	String() string
+}
+
+type Basic struct {
+	name string
+}
+
+func (b *Basic) String() string { return b.name }
+
+// Function is the type of a function literal. Unannotated parameters and
+// return values are Dynamic. Optional is the number of trailing parameters
+// with a default value, Rest the type of the arguments a rest parameter
+// takes or nil.
+type Function struct {
+	Params   []Type
+	Optional int
+	Rest     Type
+	Return   Type
+	// Names are the names of the parameters, followed by the rest
+	// parameter, for error messages. They are not part of the type.
+	Names []string
+}
+
+func (f *Function) String() string {
+	params := []string{}
+	for i, p := range f.Params {
+		if i >= len(f.Params)-f.Optional {
+			params = append(params, p.String()+"?")
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.Return.String()
+}
+
+// Signature returns name with the parameters of f, e.g.
+// f(a: int, b: int?, ...rest), for error messages. Parameters without a
+// name are shown by their type and dynamic ones by their name alone.
+func (f *Function) Signature(name string) string {
+	param := func(i int, t Type) string {
+		switch {
+		case i >= len(f.Names):
+			return t.String()
+		case t == Dynamic:
+			return f.Names[i]
+		default:
+			return f.Names[i] + ": " + t.String()
+		}
+	}
+	params := []string{}
+	for i, p := range f.Params {
+		if i >= len(f.Params)-f.Optional {
+			params = append(params, param(i, p)+"?")
+		} else {
+			params = append(params, param(i, p))
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+param(len(f.Params), f.Rest))
+	}
+	return name + "(" + strings.Join(params, ", ") + ")"
+}
+
+var (
+	Int     = &Basic{"int"}
+	Float   = &Basic{"float"}
+	Bool    = &Basic{"bool"}
+	String  = &Basic{"string"}
+	Null    = &Basic{"null"}
+	Dynamic = &Basic{"dynamic"}
+
+	// never is the type of a block that always leaves through a return. It
+	// does not contribute to the type of an if expression.
+	never = &Basic{"never"}
+)
+
+// named maps the names usable in annotations to their types.
+var named = map[string]Type{
+	"int":    Int,
+	"float":  Float,
+	"bool":   Bool,
+	"string": String,
+}
+
+// Compatible reports whether a value of type value may be used where target
+// is expected. An int may be used as float, arithmetic promotes it.
+func Compatible(value, target Type) bool {
+	if value == Dynamic || target == Dynamic || value == never {
+		return true
+	}
+	if value == Int && target == Float {
+		return true
+	}
+
+	valueFn, ok := value.(*Function)
+	targetFn, ok2 := target.(*Function)
+	if !ok || !ok2 {
+		return value == target
+	}
+	if len(valueFn.Params) != len(targetFn.Params) || valueFn.Optional != targetFn.Optional ||
+		(valueFn.Rest == nil) != (targetFn.Rest == nil) {
+		return false
+	}
+	if valueFn.Rest != nil && !Compatible(targetFn.Rest, valueFn.Rest) {
+		return false
+	}
+	for i := range valueFn.Params {
+		if !Compatible(targetFn.Params[i], valueFn.Params[i]) {
+			return false
+		}
+	}
+	return Compatible(valueFn.Return, targetFn.Return)
+}
+
+// join is the type of an expression that is either a or b.
+func join(a, b Type) Type {
+	switch {
+	case a == never:
+		return b
+	case b == never:
+		return a
+	case a == b:
+		return a
+	default:
+		return Dynamic
+	}
+}
+
+func isNumber(t Type) bool {
+	return t == Int || t == Float
+}
+
+// Check infers the types of program and reports every mismatch that would
+// be a runtime error. Only annotated names and literals have a known type,
+// so unannotated code stays dynamically typed.
+func Check(program *ast.Program) []diag.Diagnostic {
+	c := &checker{scope: newScope(nil, program.Statements, nil)}
+	c.checkStatements(program.Statements)
+	diag.Sort(c.diagnostics)
+	return c.diagnostics
+}
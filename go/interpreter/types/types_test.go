@@ -0,0 +1,147 @@
+package types
+
+import (
+	"interpreter/lexer"
+	"interpreter/parser"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		// unannotated code stays dynamic
+		{"let f = fn(a, b) { a + b }; f(1, true)", nil},
+		{"let x = 1; let x = true; x + 1", nil},
+		{"let x: int = 5; let y: float = 2.5; x * y", nil},
+		{"quote(1 + true)", nil},
+		{`let s: string = "a" + "b"; s`, nil},
+		{`"a" + 1`, []string{"1:5: error: type mismatch: string + int"}},
+		{`"a" - "b"`, []string{"1:5: error: unknown operator: string - string"}},
+		{`let f = fn(a: int, b: int) -> int { a + b }; let x: int = 1 |> f(2);`, nil},
+		{`let f = fn(a: int, b: int) -> int { a + b }; 1 |> f`, []string{"1:48: error: wrong number of arguments for f(a: int, b: int): want=2, got=1"}},
+		{`let f = fn(a: int) -> int { a }; "a" |> f`, []string{"1:34: error: argument 1 of f: cannot use string as int"}},
+		{`let n = 1; let s: string = "n=${n}"; s`, nil},
+		{`"${1}" + 1`, []string{"1:8: error: type mismatch: string + int"}},
+		{`"${1 + true}"`, []string{"1:6: error: type mismatch: int + bool"}},
+		{`import "lib"; lib.f(1) + true`, nil},
+		{"let x: int = try { 1 } catch (e) { throw e };", nil},
+		{"let x: bool = try { 1 } catch (e) { 2 };", []string{"1:15: error: cannot assign int to x of type bool"}},
+		{"let f = fn() -> int { throw 1 };", nil},
+		{"let f = fn(a: int, b: float) -> float { a + b }; f(1, 2.0)", nil},
+
+		{"let x: int = true;", []string{"1:14: error: cannot assign bool to x of type int"}},
+		{"let x: float = 1;", nil},
+		{"let x: int = 1.5;", []string{"1:14: error: cannot assign float to x of type int"}},
+		{"let f = fn(a: int, b: float) -> float { a + b }; f(1, 2)", nil},
+		{"let f = fn(a: int) -> float { a }; f(1)", nil},
+		{"let f = fn(a: float) -> int { a }", []string{"1:9: error: function returns float, want int"}},
+		{"let x: text = 1;", []string{"1:8: error: unknown type: text"}},
+		{"5 + true", []string{"1:3: error: type mismatch: int + bool"}},
+		{"true * false", []string{"1:6: error: unknown operator: bool * bool"}},
+		{"-true", []string{"1:1: error: unknown operator: -bool"}},
+		{"let b: bool = 1 < 2; b + 1", []string{"1:24: error: type mismatch: bool + int"}},
+		{"let x = if (true) { 1 } else { 2 }; x + true", []string{"1:39: error: type mismatch: int + bool"}},
+		{"let x = if (true) { 1 } else { false }; x + true", nil},
+		{
+			"let f = fn(a: int, b: float) -> float { a + b }; f(1, true)",
+			[]string{"1:55: error: argument 2 of f: cannot use bool as float"},
+		},
+		{
+			"let f = fn(a: int) -> int { a }; f(1, 2)",
+			[]string{"1:35: error: wrong number of arguments for f(a: int): want=1, got=2"},
+		},
+		{
+			"let f = fn(a: int) -> int { a }; f(2) + true",
+			[]string{"1:39: error: type mismatch: int + bool"},
+		},
+		{"let f = fn(a: int) -> bool { a }", []string{"1:9: error: function returns int, want bool"}},
+		{
+			"let f = fn(a: int) -> bool { if (a > 0) { return a; } false }",
+			[]string{"1:50: error: cannot return int from function returning bool"},
+		},
+		{"let f = fn(a: int) -> int { if (a > 0) { return 1; } else { return 2; } }", nil},
+		{"let x: int = 1; x(2)", []string{"1:18: error: not a function: int"}},
+		{"struct P { x }; let p = P(1); p.x = 2; p.x + true", nil},
+		{"struct P { x }; let p = P(1); (p.x = 2) + true", []string{"1:41: error: type mismatch: int + bool"}},
+		{"struct P { x }; fn (p P) f(a: int) -> int { p.x + a }; P(1).f(2) + true", nil},
+		{"let n: int = 3; for (x in 0..n) { x + true }", nil},
+		{"0..true", []string{"1:4: error: range bounds must be int, got bool"}},
+		{"for (x in [1]) { let y: int = 1; y + true }", []string{"1:36: error: type mismatch: int + bool"}},
+		{"let g = fn() { yield 1; return 0; }; g() + 1", nil},
+		{"let g = fn(a: int) { yield a + true }", []string{"1:30: error: type mismatch: int + bool"}},
+		{"let g = fn() -> int { yield 1 }", []string{"1:17: error: generator g cannot declare a return type"}},
+		{"let c = chan(); let n: int = 1; select { x = recv(c) => n + x, _ => n + true }", []string{"1:71: error: type mismatch: int + bool"}},
+		{"let c = chan(); let x: int = 1; select { x = recv(c) => x + true }; x + 1", nil},
+		{"let n: int = 1; spawn fn() { n + true }", []string{"1:32: error: type mismatch: int + bool"}},
+		{"struct P { x }; fn (p P) f(a: int) -> int { a + true }", []string{"1:47: error: type mismatch: int + bool"}},
+		{"let f = fn(a: int, b: int = 1) -> int { a + b }; f(1); f(1, 2); f(b: 2, a: 1)", nil},
+		{"let f = fn(a: int, b: int = 1) -> int { a + b }; f()", []string{"1:51: error: wrong number of arguments for f(a: int, b: int?): want 1 to 2, got=0"}},
+		{"let f = fn(a: int, b: int = true) { a }", []string{"1:29: error: cannot use bool as default of b of type int"}},
+		{"let f = fn(a, ...r: int) { r }; f(1, 2, true)", []string{"1:41: error: argument 3 of f: cannot use bool as int"}},
+		{"let f = fn(a, ...r) { r }; f()", []string{"1:29: error: wrong number of arguments for f(a, ...r): want at least 1, got=0"}},
+		{"let x = 1; let [x] = [true]; x + 1", nil},
+		{"let [a, b] = [1, 2]; a + b", nil},
+		{`match (1) { 1 => "one", _ => "other" } + 1`, []string{"1:40: error: type mismatch: string + int"}},
+		{"match (1) { x => x + true }", nil},
+		{
+			"let apply = fn(f, x: int) -> int { f(x) }; let g = fn(x: bool) { x }; apply(g, 1); g(1)",
+			[]string{"1:86: error: argument 1 of g: cannot use int as bool"},
+		},
+	}
+
+	for _, tt := range tests {
+		p := parser.New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors for %q: %v", tt.input, p.Errors())
+		}
+
+		diagnostics := Check(program)
+		if len(diagnostics) != len(tt.expected) {
+			t.Errorf("wrong number of diagnostics for %q. expected=%v, got=%v",
+				tt.input, tt.expected, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.expected[i] {
+				t.Errorf("wrong diagnostic for %q. expected=%q, got=%q",
+					tt.input, tt.expected[i], d.String())
+			}
+		}
+	}
+}
+
+func TestCompatible(t *testing.T) {
+	intToInt := &Function{Params: []Type{Int}, Return: Int}
+	dynToInt := &Function{Params: []Type{Dynamic}, Return: Int}
+	boolToInt := &Function{Params: []Type{Bool}, Return: Int}
+	floatToInt := &Function{Params: []Type{Float}, Return: Int}
+	intToFloat := &Function{Params: []Type{Int}, Return: Float}
+
+	tests := []struct {
+		value    Type
+		target   Type
+		expected bool
+	}{
+		{Int, Int, true},
+		{Int, Float, true},
+		{Float, Int, false},
+		{Dynamic, Bool, true},
+		{Bool, Dynamic, true},
+		{dynToInt, intToInt, true},
+		{boolToInt, intToInt, false},
+		{intToInt, Int, false},
+		{floatToInt, intToInt, true},
+		{intToInt, floatToInt, false},
+		{intToInt, intToFloat, true},
+		{intToFloat, intToInt, false},
+	}
+	for _, tt := range tests {
+		if got := Compatible(tt.value, tt.target); got != tt.expected {
+			t.Errorf("Compatible(%s, %s) wrong. expected=%t, got=%t",
+				tt.value, tt.target, tt.expected, got)
+		}
+	}
+}